@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/logic"
+	"golang.org/x/net/context"
+)
+
+var testKeys = []Key{{Id: "k1", Secret: "0123456789abcdef"}, {Id: "k2", Secret: "fedcba9876543210"}}
+
+func newTestService(t *testing.T, active string) (*Service, *time.Time) {
+	t.Helper()
+	credentials := NewMemoryCredentials([]User{{Account: "alice", Uid: 7, Password: "secret"}})
+	s, err := NewService(Cfg{Keys: testKeys, ActiveKey: active, AccessTTL: time.Hour, RefreshTTL: 24 * time.Hour},
+		NewMemoryStore(), credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func login(t *testing.T, s *Service, deviceId string) *logic.TokenReply {
+	t.Helper()
+	reply, err := s.Login(context.Background(), &logic.LoginReq{Account: "alice", Password: "secret", DeviceId: deviceId, Platform: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func verify(s *Service, token, deviceId string) error {
+	_, err := s.Verify(context.Background(), &logic.VerifyReq{Token: token, DeviceId: deviceId})
+	return err
+}
+
+func TestSigner(t *testing.T) {
+	if _, err := NewSigner(nil, ""); err == nil {
+		t.Fatal("no key should fail")
+	}
+	if _, err := NewSigner([]Key{{Id: "k1", Secret: "short"}}, ""); err == nil {
+		t.Fatal("short secret should fail")
+	}
+	if _, err := NewSigner(testKeys, "k3"); err == nil {
+		t.Fatal("unknown active key should fail")
+	}
+	signer, _ := NewSigner(testKeys, "")
+	now := time.Now()
+	token, err := signer.Sign(&Claims{Type: TypeAccess, Uid: 7, Expire: now.Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, "k1.") {
+		t.Fatalf("token should be signed by k1: %s", token)
+	}
+	if claims, err := signer.Parse(token, now); err != nil || claims.Uid != 7 || claims.Id == "" {
+		t.Fatalf("unexpected claims %+v, %v", claims, err)
+	}
+	if _, err = signer.Parse(token, now.Add(time.Minute)); err != ecode.TokenExpired {
+		t.Fatalf("want TokenExpired, got %v", err)
+	}
+	//篡改内容或使用未知的密钥
+	parts := strings.Split(token, ".")
+	for _, bad := range []string{
+		parts[0] + "." + parts[1] + "x." + parts[2],
+		"k2." + parts[1] + "." + parts[2],
+		"k9." + parts[1] + "." + parts[2],
+		"garbage",
+	} {
+		if _, err = signer.Parse(bad, now); err != ecode.TokenInvalid {
+			t.Fatalf("%s: want TokenInvalid, got %v", bad, err)
+		}
+	}
+}
+
+//密钥从环境变量或文件读取，都没有时启动失败
+func TestKeySecretSource(t *testing.T) {
+	if _, err := NewSigner([]Key{{Id: "k1", SecretEnv: "ZWCHAT_TEST_UNSET_KEY"}}, ""); err == nil {
+		t.Fatal("empty secret should fail")
+	}
+	os.Setenv("ZWCHAT_TEST_AUTH_KEY", "0123456789abcdef")
+	defer os.Unsetenv("ZWCHAT_TEST_AUTH_KEY")
+	file, err := ioutil.TempFile("", "auth_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("fedcba9876543210\n")
+	file.Close()
+	keys := []Key{
+		{Id: "env", SecretEnv: "ZWCHAT_TEST_AUTH_KEY", SecretFile: "/nonexistent"},
+		{Id: "file", SecretEnv: "ZWCHAT_TEST_UNSET_KEY", SecretFile: file.Name()},
+	}
+	signer, err := NewSigner(keys, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(signer.keys["env"]) != "0123456789abcdef" || string(signer.keys["file"]) != "fedcba9876543210" {
+		t.Fatalf("unexpected keys %q", signer.keys)
+	}
+	if _, err = NewSigner([]Key{{Id: "k1", SecretFile: "/nonexistent"}}, ""); err == nil {
+		t.Fatal("missing secret file should fail")
+	}
+}
+
+//轮换后旧密钥签发的令牌仍然有效，删除旧密钥后失效
+func TestKeyRotation(t *testing.T) {
+	old, _ := newTestService(t, "k1")
+	tokens := login(t, old, "d1")
+	rotated, _ := newTestService(t, "k2")
+	if err := verify(rotated, tokens.AccessToken, "d1"); err != nil {
+		t.Fatal(err)
+	}
+	if token := login(t, rotated, "d1").AccessToken; !strings.HasPrefix(token, "k2.") {
+		t.Fatalf("token should be signed by k2: %s", token)
+	}
+	s, err := NewService(Cfg{Keys: testKeys[1:]}, NewMemoryStore(), NewMemoryCredentials(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = verify(s, tokens.AccessToken, "d1"); err != ecode.TokenInvalid {
+		t.Fatalf("want TokenInvalid, got %v", err)
+	}
+}
+
+func TestService(t *testing.T) {
+	s, now := newTestService(t, "")
+	ctx := context.Background()
+	if _, err := s.Login(ctx, &logic.LoginReq{Account: "alice", Password: "wrong"}); err != ecode.LoginFailed {
+		t.Fatalf("want LoginFailed, got %v", err)
+	}
+	if _, err := s.Login(ctx, &logic.LoginReq{Account: "bob", Password: "secret"}); err != ecode.LoginFailed {
+		t.Fatalf("want LoginFailed, got %v", err)
+	}
+	tokens := login(t, s, "d1")
+	if tokens.Uid != 7 || tokens.AccessExpire != now.Add(time.Hour).Unix() {
+		t.Fatalf("unexpected tokens %+v", tokens)
+	}
+	reply, err := s.Verify(ctx, &logic.VerifyReq{Token: tokens.AccessToken, DeviceId: "d1"})
+	if err != nil || reply.Uid != 7 || reply.Platform != 1 {
+		t.Fatalf("unexpected reply %+v, %v", reply, err)
+	}
+	//令牌绑定设备，刷新令牌不能用于登录
+	if err = verify(s, tokens.AccessToken, "d2"); err != ecode.TokenInvalid {
+		t.Fatalf("want TokenInvalid, got %v", err)
+	}
+	if err = verify(s, tokens.RefreshToken, "d1"); err != ecode.TokenInvalid {
+		t.Fatalf("want TokenInvalid, got %v", err)
+	}
+
+	//刷新后旧的刷新令牌失效
+	*now = now.Add(2 * time.Hour)
+	if err = verify(s, tokens.AccessToken, "d1"); err != ecode.TokenExpired {
+		t.Fatalf("want TokenExpired, got %v", err)
+	}
+	refreshed, err := s.Refresh(ctx, &logic.RefreshReq{RefreshToken: tokens.RefreshToken, DeviceId: "d1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = verify(s, refreshed.AccessToken, "d1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Refresh(ctx, &logic.RefreshReq{RefreshToken: tokens.RefreshToken, DeviceId: "d1"}); err != ecode.TokenRevoked {
+		t.Fatalf("want TokenRevoked, got %v", err)
+	}
+
+	if _, err = s.Revoke(ctx, &logic.RevokeReq{Token: refreshed.AccessToken}); err != nil {
+		t.Fatal(err)
+	}
+	if err = verify(s, refreshed.AccessToken, "d1"); err != ecode.TokenRevoked {
+		t.Fatalf("want TokenRevoked, got %v", err)
+	}
+	if _, err = s.Revoke(ctx, &logic.RevokeReq{Token: tokens.AccessToken}); err != nil {
+		t.Fatalf("revoke expired token: %v", err)
+	}
+
+	//LogoutAll之前签发的令牌全部失效，之后登录不受影响
+	other := login(t, s, "d2")
+	*now = now.Add(time.Millisecond)
+	if _, err = s.LogoutAll(ctx, &logic.LogoutAllReq{Uid: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err = verify(s, other.AccessToken, "d2"); err != ecode.TokenRevoked {
+		t.Fatalf("want TokenRevoked, got %v", err)
+	}
+	if _, err = s.Refresh(ctx, &logic.RefreshReq{RefreshToken: refreshed.RefreshToken, DeviceId: "d1"}); err != ecode.TokenRevoked {
+		t.Fatalf("want TokenRevoked, got %v", err)
+	}
+	*now = now.Add(time.Millisecond)
+	if err = verify(s, login(t, s, "d2").AccessToken, "d2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.LogoutAll(ctx, &logic.LogoutAllReq{}); err != ecode.RequestErr {
+		t.Fatalf("want RequestErr, got %v", err)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	s.Revoke(ctx, "old", time.Now().Add(-time.Second))
+	s.LogoutAll(ctx, 7, 1, -time.Second)
+	s.lastSweep = time.Now().Add(-sweepInterval)
+	s.Revoke(ctx, "new", time.Now().Add(time.Hour))
+	if revoked, _ := s.Revoked(ctx, "old"); revoked {
+		t.Fatal("expired record should be swept")
+	}
+	if revoked, _ := s.Revoked(ctx, "new"); !revoked {
+		t.Fatal("record should be kept")
+	}
+	if at, _ := s.NotBefore(ctx, 7); at != 0 {
+		t.Fatalf("expired notBefore should be swept, got %d", at)
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"golang.org/x/net/context"
+)
+
+//校验账号密码，失败时返回ecode.LoginFailed，不区分账号不存在和密码错误
+type Credentials interface {
+	Check(ctx context.Context, account, password string) (uid uint64, err error)
+}
+
+//配置文件中的静态账号，只用于开发和测试
+type User struct {
+	Account  string `yaml:"account"`
+	Uid      uint64 `yaml:"uid"`
+	Password string `yaml:"password"`
+}
+
+type memoryUser struct {
+	uid  uint64
+	salt []byte
+	hash []byte
+}
+
+//密码加盐后保存摘要
+type MemoryCredentials struct {
+	mutex sync.RWMutex
+	users map[string]*memoryUser
+}
+
+func NewMemoryCredentials(users []User) *MemoryCredentials {
+	c := &MemoryCredentials{users: make(map[string]*memoryUser, len(users))}
+	for _, user := range users {
+		c.Add(user.Account, user.Uid, user.Password)
+	}
+	return c
+}
+
+func hashPassword(salt []byte, password string) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(password))
+	return mac.Sum(nil)
+}
+
+//已存在时覆盖
+func (c *MemoryCredentials) Add(account string, uid uint64, password string) {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.users[account] = &memoryUser{uid: uid, salt: salt, hash: hashPassword(salt, password)}
+}
+
+func (c *MemoryCredentials) Check(ctx context.Context, account, password string) (uint64, error) {
+	c.mutex.RLock()
+	user := c.users[account]
+	c.mutex.RUnlock()
+	if user == nil || !hmac.Equal(user.hash, hashPassword(user.salt, password)) {
+		return 0, ecode.LoginFailed
+	}
+	return user.uid, nil
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	etcdv3 "github.com/coreos/etcd/clientv3"
+	"golang.org/x/net/context"
+)
+
+//记录写在各自的租约下，到期后由etcd删除。
+//key为/{prefix}/auth/revoked/{令牌id}和/{prefix}/auth/notbefore/{uid}
+type EtcdStore struct {
+	client *etcdv3.Client
+	prefix string
+}
+
+//Close时关闭client
+func NewEtcdStore(client *etcdv3.Client, prefix string) *EtcdStore {
+	return &EtcdStore{client: client, prefix: prefix}
+}
+
+func (s *EtcdStore) revokedKey(id string) string {
+	return fmt.Sprintf("/%s/auth/revoked/%s", s.prefix, id)
+}
+
+func (s *EtcdStore) notBeforeKey(uid uint64) string {
+	return fmt.Sprintf("/%s/auth/notbefore/%d", s.prefix, uid)
+}
+
+//租约至少1s
+func (s *EtcdStore) put(ctx context.Context, key, value string, ttl time.Duration) error {
+	seconds := int64(ttl / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	lease, err := s.client.Grant(ctx, seconds)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Put(ctx, key, value, etcdv3.WithLease(lease.ID))
+	return err
+}
+
+func (s *EtcdStore) Revoke(ctx context.Context, id string, expire time.Time) error {
+	return s.put(ctx, s.revokedKey(id), "", expire.Sub(time.Now())+time.Second)
+}
+
+func (s *EtcdStore) Revoked(ctx context.Context, id string) (bool, error) {
+	resp, err := s.client.Get(ctx, s.revokedKey(id), etcdv3.WithCountOnly())
+	if err != nil {
+		return false, err
+	}
+	return resp.Count > 0, nil
+}
+
+func (s *EtcdStore) LogoutAll(ctx context.Context, uid uint64, at int64, ttl time.Duration) error {
+	return s.put(ctx, s.notBeforeKey(uid), strconv.FormatInt(at, 10), ttl)
+}
+
+func (s *EtcdStore) NotBefore(ctx context.Context, uid uint64) (int64, error) {
+	resp, err := s.client.Get(ctx, s.notBeforeKey(uid))
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseInt(string(resp.Kvs[0].Value), 10, 64)
+}
+
+func (s *EtcdStore) Close() error {
+	return s.client.Close()
+}
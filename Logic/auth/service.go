@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/logic"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultAccessTTL  = 2 * time.Hour
+	defaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+type Cfg struct {
+	Keys       []Key         `yaml:"keys"`
+	ActiveKey  string        `yaml:"activeKey"`  //签名使用的密钥，为空时使用第一个
+	AccessTTL  time.Duration `yaml:"accessTTL"`  //访问令牌的有效期
+	RefreshTTL time.Duration `yaml:"refreshTTL"` //刷新令牌的有效期
+	Store      StoreCfg      `yaml:",inline"`
+	Users      []User        `yaml:"users"` //静态账号，只用于开发和测试
+}
+
+//实现logic.AuthServer，令牌绑定uid和设备，错误为ecode
+type Service struct {
+	signer      *Signer
+	store       Store
+	credentials Credentials
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+	now         func() time.Time
+}
+
+func NewService(cfg Cfg, store Store, credentials Credentials) (*Service, error) {
+	signer, err := NewSigner(cfg.Keys, cfg.ActiveKey)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{
+		signer:      signer,
+		store:       store,
+		credentials: credentials,
+		accessTTL:   cfg.AccessTTL,
+		refreshTTL:  cfg.RefreshTTL,
+		now:         time.Now,
+	}
+	if s.accessTTL <= 0 {
+		s.accessTTL = defaultAccessTTL
+	}
+	if s.refreshTTL <= 0 {
+		s.refreshTTL = defaultRefreshTTL
+	}
+	return s, nil
+}
+
+func (s *Service) Login(ctx context.Context, req *logic.LoginReq) (*logic.TokenReply, error) {
+	if req.Account == "" || req.Password == "" {
+		return nil, ecode.RequestErr
+	}
+	uid, err := s.credentials.Check(ctx, req.Account, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("auth login", zap.Uint64("uid", uid), zap.String("deviceId", req.DeviceId),
+		zap.Int32("platform", req.Platform))
+	return s.issue(uid, req.DeviceId, req.Platform)
+}
+
+//签发一对令牌
+func (s *Service) issue(uid uint64, deviceId string, platform int32) (*logic.TokenReply, error) {
+	now := s.now()
+	access := &Claims{
+		Type:     TypeAccess,
+		Uid:      uid,
+		DeviceId: deviceId,
+		Platform: platform,
+		IssuedAt: now.UnixNano(),
+		Expire:   now.Add(s.accessTTL).Unix(),
+	}
+	refresh := *access
+	refresh.Type, refresh.Expire = TypeRefresh, now.Add(s.refreshTTL).Unix()
+	reply := &logic.TokenReply{Uid: uid, AccessExpire: access.Expire, RefreshExpire: refresh.Expire}
+	var err error
+	if reply.AccessToken, err = s.signer.Sign(access); err != nil {
+		return nil, err
+	}
+	if reply.RefreshToken, err = s.signer.Sign(&refresh); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+//校验类型、设备和吊销状态，存储出错时返回ServerErr
+func (s *Service) check(ctx context.Context, token, typ, deviceId string) (*Claims, error) {
+	claims, err := s.signer.Parse(token, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != typ || claims.DeviceId != deviceId {
+		return nil, ecode.TokenInvalid
+	}
+	revoked, err := s.store.Revoked(ctx, claims.Id)
+	if err != nil {
+		logger.Error("auth revoked", zap.String("id", claims.Id), zap.Error(err))
+		return nil, ecode.ServerErr
+	}
+	if revoked {
+		return nil, ecode.TokenRevoked
+	}
+	notBefore, err := s.store.NotBefore(ctx, claims.Uid)
+	if err != nil {
+		logger.Error("auth notBefore", zap.Uint64("uid", claims.Uid), zap.Error(err))
+		return nil, ecode.ServerErr
+	}
+	if claims.IssuedAt < notBefore {
+		return nil, ecode.TokenRevoked
+	}
+	return claims, nil
+}
+
+func (s *Service) Verify(ctx context.Context, req *logic.VerifyReq) (*logic.VerifyReply, error) {
+	claims, err := s.check(ctx, req.Token, TypeAccess, req.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+	return &logic.VerifyReply{
+		Uid:      claims.Uid,
+		DeviceId: claims.DeviceId,
+		Platform: claims.Platform,
+		Expire:   claims.Expire,
+	}, nil
+}
+
+//旧的刷新令牌吊销后才签发新令牌，同一个刷新令牌不能使用两次
+func (s *Service) Refresh(ctx context.Context, req *logic.RefreshReq) (*logic.TokenReply, error) {
+	claims, err := s.check(ctx, req.RefreshToken, TypeRefresh, req.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.store.Revoke(ctx, claims.Id, time.Unix(claims.Expire, 0)); err != nil {
+		logger.Error("auth revoke", zap.String("id", claims.Id), zap.Error(err))
+		return nil, ecode.ServerErr
+	}
+	return s.issue(claims.Uid, claims.DeviceId, claims.Platform)
+}
+
+//已过期的令牌不需要吊销
+func (s *Service) Revoke(ctx context.Context, req *logic.RevokeReq) (*logic.RevokeReply, error) {
+	claims, err := s.signer.Parse(req.Token, s.now())
+	if err == ecode.TokenExpired {
+		return &logic.RevokeReply{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = s.store.Revoke(ctx, claims.Id, time.Unix(claims.Expire, 0)); err != nil {
+		logger.Error("auth revoke", zap.String("id", claims.Id), zap.Error(err))
+		return nil, ecode.ServerErr
+	}
+	return &logic.RevokeReply{}, nil
+}
+
+//之前签发的令牌全部失效，之后登录签发的令牌不受影响
+func (s *Service) LogoutAll(ctx context.Context, req *logic.LogoutAllReq) (*logic.LogoutAllReply, error) {
+	if req.Uid == 0 {
+		return nil, ecode.RequestErr
+	}
+	if err := s.store.LogoutAll(ctx, req.Uid, s.now().UnixNano(), s.refreshTTL); err != nil {
+		logger.Error("auth logoutAll", zap.Uint64("uid", req.Uid), zap.Error(err))
+		return nil, ecode.ServerErr
+	}
+	logger.Info("auth logoutAll", zap.Uint64("uid", req.Uid))
+	return &logic.LogoutAllReply{}, nil
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	etcdv3 "github.com/coreos/etcd/clientv3"
+	"golang.org/x/net/context"
+)
+
+const (
+	StoreMemory = "memory"
+	StoreEtcd   = "etcd"
+
+	sweepInterval = time.Minute
+)
+
+//吊销状态的存储，多个Logic实例需要共用同一个存储。实现需要可以并发调用
+type Store interface {
+	//吊销一个令牌，expire之后令牌已过期，不再需要记录
+	Revoke(ctx context.Context, id string, expire time.Time) error
+	Revoked(ctx context.Context, id string) (bool, error)
+	//签发时间早于at(ns)的令牌全部失效，ttl后所有旧令牌都已过期，不再需要记录
+	LogoutAll(ctx context.Context, uid uint64, at int64, ttl time.Duration) error
+	//没有记录时返回0
+	NotBefore(ctx context.Context, uid uint64) (int64, error)
+	Close() error
+}
+
+type StoreCfg struct {
+	Store       string        `yaml:"store"`       //memory或etcd
+	Target      string        `yaml:"target"`      //etcd地址，多个用逗号分隔
+	Prefix      string        `yaml:"prefix"`      //etcd的key前缀
+	DialTimeout time.Duration `yaml:"dialTimeout"` //连接etcd的超时
+}
+
+//memory只在本进程内有效，用于单实例部署和测试
+func NewStore(cfg StoreCfg) (Store, error) {
+	switch cfg.Store {
+	case StoreMemory:
+		return NewMemoryStore(), nil
+	case StoreEtcd:
+		client, err := etcdv3.New(etcdv3.Config{
+			Endpoints:   strings.Split(cfg.Target, ","),
+			DialTimeout: cfg.DialTimeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewEtcdStore(client, cfg.Prefix), nil
+	}
+	return nil, fmt.Errorf("[auth] unknown store %q", cfg.Store)
+}
+
+type notBefore struct {
+	at     int64
+	expire time.Time
+}
+
+//过期的记录在写入时顺带清理
+type MemoryStore struct {
+	mutex     sync.Mutex
+	revoked   map[string]time.Time
+	notBefore map[uint64]notBefore
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		revoked:   make(map[string]time.Time),
+		notBefore: make(map[uint64]notBefore),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Revoke(ctx context.Context, id string, expire time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sweep(time.Now())
+	s.revoked[id] = expire
+	return nil
+}
+
+func (s *MemoryStore) Revoked(ctx context.Context, id string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.revoked[id]
+	return ok, nil
+}
+
+func (s *MemoryStore) LogoutAll(ctx context.Context, uid uint64, at int64, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	s.sweep(now)
+	s.notBefore[uid] = notBefore{at: at, expire: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) NotBefore(ctx context.Context, uid uint64) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.notBefore[uid].at, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+//调用方持有mutex
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for id, expire := range s.revoked {
+		if now.After(expire) {
+			delete(s.revoked, id)
+		}
+	}
+	for uid, item := range s.notBefore {
+		if now.After(item.expire) {
+			delete(s.notBefore, uid)
+		}
+	}
+}
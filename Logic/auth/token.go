@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+)
+
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+
+	minSecretLen = 16
+)
+
+//令牌的内容，签名后不能修改
+type Claims struct {
+	Type     string `json:"typ"`
+	Uid      uint64 `json:"uid"`
+	DeviceId string `json:"dev"`
+	Platform int32  `json:"plat"`
+	IssuedAt int64  `json:"iat"` //签发时间(ns)，用于判断是否在LogoutAll之前签发
+	Expire   int64  `json:"exp"` //过期时间(unix秒)
+	Id       string `json:"jti"` //唯一id，吊销时使用
+}
+
+//签名密钥，id写在令牌中，校验时据此找到密钥。
+//secret为空时依次从环境变量secretEnv和文件secretFile读取，避免密钥写在配置文件中
+type Key struct {
+	Id         string `yaml:"id"`
+	Secret     string `yaml:"secret"`
+	SecretEnv  string `yaml:"secretEnv"`
+	SecretFile string `yaml:"secretFile"` //去掉首尾空白
+}
+
+func (key Key) secret() (string, error) {
+	if key.Secret != "" {
+		return key.Secret, nil
+	}
+	if key.SecretEnv != "" {
+		if secret := os.Getenv(key.SecretEnv); secret != "" {
+			return secret, nil
+		}
+	}
+	if key.SecretFile != "" {
+		data, err := ioutil.ReadFile(key.SecretFile)
+		if err != nil {
+			return "", fmt.Errorf("[auth] key %s: %v", key.Id, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", nil
+}
+
+//用HMAC-SHA256签名，令牌格式为{密钥id}.{base64(claims)}.{base64(签名)}。
+//轮换密钥时先加入新密钥并设为active，旧密钥在刷新令牌的有效期过后再删除
+type Signer struct {
+	keys   map[string][]byte
+	active string
+}
+
+//active为空时使用第一个密钥签名
+func NewSigner(keys []Key, active string) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("[auth] no signing key")
+	}
+	s := &Signer{keys: make(map[string][]byte, len(keys)), active: active}
+	for _, key := range keys {
+		if key.Id == "" || strings.Contains(key.Id, ".") {
+			return nil, fmt.Errorf("[auth] invalid key id %q", key.Id)
+		}
+		secret, err := key.secret()
+		if err != nil {
+			return nil, err
+		}
+		if len(secret) < minSecretLen {
+			return nil, fmt.Errorf("[auth] key %s: secret shorter than %d bytes", key.Id, minSecretLen)
+		}
+		if _, ok := s.keys[key.Id]; ok {
+			return nil, fmt.Errorf("[auth] duplicate key id %s", key.Id)
+		}
+		s.keys[key.Id] = []byte(secret)
+	}
+	if s.active == "" {
+		s.active = keys[0].Id
+	}
+	if _, ok := s.keys[s.active]; !ok {
+		return nil, fmt.Errorf("[auth] active key %s not found", s.active)
+	}
+	return s, nil
+}
+
+func newTokenId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func sign(secret []byte, data string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+//claims.Id为空时生成，错误为ecode.CalcTokenFailed
+func (s *Signer) Sign(claims *Claims) (string, error) {
+	if claims.Id == "" {
+		id, err := newTokenId()
+		if err != nil {
+			return "", ecode.CalcTokenFailed
+		}
+		claims.Id = id
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", ecode.CalcTokenFailed
+	}
+	data := s.active + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return data + "." + sign(s.keys[s.active], data), nil
+}
+
+//校验签名和过期时间，不检查是否被吊销。过期时同时返回claims和ecode.TokenExpired
+func (s *Signer) Parse(token string, now time.Time) (*Claims, error) {
+	if token == "" {
+		return nil, ecode.NoToken
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ecode.TokenInvalid
+	}
+	secret, ok := s.keys[parts[0]]
+	if !ok {
+		return nil, ecode.TokenInvalid
+	}
+	expected := sign(secret, parts[0]+"."+parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, ecode.TokenInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ecode.TokenInvalid
+	}
+	claims := &Claims{}
+	if err = json.Unmarshal(payload, claims); err != nil || claims.Id == "" || claims.Uid == 0 {
+		return nil, ecode.TokenInvalid
+	}
+	if now.Unix() >= claims.Expire {
+		return claims, ecode.TokenExpired
+	}
+	return claims, nil
+}
@@ -7,7 +7,8 @@ import (
 	"io/ioutil"
 	"path/filepath"
 	"os"
-	"fmt"
+	"go.uber.org/zap"
+	"github.com/imkuqin-zw/ZWChat/Logic/auth"
 )
 
 var (
@@ -16,21 +17,23 @@ var (
 )
 
 type Config struct {
-	Server *commconf.Server
-	Path   *commconf.Path
-	Log    *commconf.Log
-	ServiceDiscovery *commconf.ServiceDiscoveryServer
-	RpcClient *RpcClient
-	RpcServer *commconf.Server
-	Etcd *commconf.Etcd
+	Path             *commconf.Path                   `yaml:"path"`
+	Log              *zap.Config                      `yaml:"log"`
+	ServiceDiscovery *commconf.ServiceDiscoveryServer `yaml:"serviceDiscovery"`
+	RpcClient        *RpcClient                       `yaml:"rpcClient"`
+	RpcServer        *commconf.Server                 `yaml:"rpcServer"` //上行命令和认证服务
+	Etcd             *commconf.Etcd                   `yaml:"etcd"`
+	Auth             *auth.Cfg                        `yaml:"auth"`
+	Concurrency      int                              `yaml:"concurrency"` //每条上行流同时处理的请求数
 }
 
+//register不为空时通过注册服务校验账号密码，否则使用auth.users中的静态账号
 type RpcClient struct {
-	LoginClient *commconf.ServiceDiscoveryClient
+	Register *commconf.ServiceDiscoveryClient `yaml:"register"`
 }
 
 func init()  {
-	flag.StringVar(&confPath, "conf", "./access.yaml", "config path")
+	flag.StringVar(&confPath, "conf", "./login.yaml", "config path")
 }
 
 func Init() (err error) {
@@ -48,8 +51,5 @@ func Init() (err error) {
 	if err != nil {
 		return
 	}
-	fmt.Println(Conf.Etcd)
-	fmt.Println(Conf.RpcClient)
-	fmt.Println(Conf.ServiceDiscovery)
 	return
-}
\ No newline at end of file
+}
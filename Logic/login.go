@@ -1,5 +1,108 @@
-package Logic
+package main
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/imkuqin-zw/ZWChat/Logic/auth"
+	"github.com/imkuqin-zw/ZWChat/Logic/config"
+	"github.com/imkuqin-zw/ZWChat/Logic/rpc"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+	//注册etcd后端
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery/etcd"
+	"go.uber.org/zap"
+)
 
 func main() {
+	flag.Parse()
+	authCfg := config.Conf.Auth
+	if authCfg == nil {
+		logger.Fatal("auth not configured")
+		return
+	}
+	if etcdCfg := config.Conf.Etcd; etcdCfg != nil {
+		if authCfg.Store.Prefix == "" {
+			authCfg.Store.Prefix = etcdCfg.Prefix
+		}
+		if authCfg.Store.DialTimeout == 0 {
+			authCfg.Store.DialTimeout = etcdCfg.DialTimeout
+		}
+	}
+	store, err := auth.NewStore(authCfg.Store)
+	if err != nil {
+		logger.Fatal("auth store", zap.Error(err))
+		return
+	}
+	defer store.Close()
+	rpcClient := &rpc.RPCClient{}
+	var credentials auth.Credentials = auth.NewMemoryCredentials(authCfg.Users)
+	if config.Conf.RpcClient != nil && config.Conf.RpcClient.Register != nil {
+		cfg := config.Conf.RpcClient.Register
+		registerDiscovery, err := service_discovery.New(service_discovery.Cfg{
+			Backend: cfg.Backend, Target: cfg.Target, Reload: cfg.Reload,
+		})
+		if err != nil {
+			logger.Fatal("register discovery", zap.String("backend", cfg.Backend), zap.Error(err))
+			return
+		}
+		defer registerDiscovery.Close()
+		if rpcClient.Register, err = rpc.NewRegisterRPCCli(cfg.ServerName, registerDiscovery); err != nil {
+			logger.Fatal("NewRegisterRPCCli", zap.Error(err))
+			return
+		}
+		defer rpcClient.Register.Close()
+		credentials = rpcClient.Register
+	}
+	authService, err := auth.NewService(*authCfg, store, credentials)
+	if err != nil {
+		logger.Fatal("auth service", zap.Error(err))
+		return
+	}
+	rpcServer := rpc.NewRPCServer(authService, config.Conf.Concurrency, rpcClient)
+	if err = rpcServer.Serve(rpcNetwork(config.Conf.RpcServer.Proto), config.Conf.RpcServer.Addr); err != nil {
+		return
+	}
+	defer rpcServer.Stop()
+	logger.Info("rpc init success", zap.String("addr", config.Conf.RpcServer.Addr))
+	//接入节点通过服务发现找到Logic
+	if sd := config.Conf.ServiceDiscovery; sd != nil {
+		discovery, err := service_discovery.New(service_discovery.Cfg{
+			Backend: sd.Backend, Target: sd.Target, TTL: sd.TTL, Reload: sd.Reload,
+		})
+		if err != nil {
+			logger.Fatal("service discovery", zap.String("backend", sd.Backend), zap.Error(err))
+			return
+		}
+		defer discovery.Close()
+		if err = discovery.Register(sd.ServerName, service_discovery.Instance{
+			Addr: sd.RpcAddr, Version: sd.Version, Zone: sd.Zone, Weight: sd.Weight,
+		}); err != nil {
+			logger.Error("service register", zap.Error(err))
+		}
+		defer discovery.Deregister(sd.ServerName, sd.RpcAddr)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+}
+
+func rpcNetwork(proto string) string {
+	if proto == "" {
+		return "tcp"
+	}
+	return proto
+}
 
+func init() {
+	if err := config.Init(); err != nil {
+		panic(err)
+	}
+	if config.Conf.Etcd != nil {
+		etcd.DialTimeout = config.Conf.Etcd.DialTimeout
+		etcd.Prefix = config.Conf.Etcd.Prefix
+	}
+	logger.InitLogger(config.Conf.Log)
 }
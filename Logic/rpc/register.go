@@ -1,11 +1,71 @@
 package rpc
 
-import "google.golang.org/grpc"
+import (
+	"errors"
 
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/register"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery/balancer"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+)
+
+const registerScheme = "register"
+
+//Logic到注册服务的客户端，通过服务发现找到实例，请求在实例间轮询。错误为ecode
 type RegisterRPCCli struct {
-	conn *grpc.ClientConn
+	conn   *grpc.ClientConn
+	client register.RegisterClient
 }
 
-func NewRegisterRPCCli()  (registerRPCCli *RegisterRPCCli, err error) {
+func NewRegisterRPCCli(serverName string, discovery service_discovery.Discovery) (registerRPCCli *RegisterRPCCli, err error) {
+	resolver.Register(service_discovery.NewBuilder(registerScheme, discovery))
+	conn, err := grpc.Dial(registerScheme+":///"+serverName, grpc.WithInsecure(),
+		grpc.WithBalancerBuilder(balancer.Get(balancer.RoundRobin)))
+	if err != nil {
+		return
+	}
+	registerRPCCli = &RegisterRPCCli{conn: conn, client: register.NewRegisterClient(conn)}
 	return
-}
\ No newline at end of file
+}
+
+func (cli *RegisterRPCCli) Register(ctx context.Context, req *register.RegisterReq) (uint64, error) {
+	reply, err := cli.client.Register(ctx, req)
+	if err != nil {
+		return 0, rpcError(err)
+	}
+	return reply.Uid, nil
+}
+
+//实现auth.Credentials，account为用户名、手机号或邮箱
+func (cli *RegisterRPCCli) Check(ctx context.Context, account, password string) (uint64, error) {
+	reply, err := cli.client.Authenticate(ctx, &register.AuthenticateReq{Account: account, Password: password})
+	if err != nil {
+		return 0, rpcError(err)
+	}
+	return reply.Uid, nil
+}
+
+func (cli *RegisterRPCCli) GetProfile(ctx context.Context, uid uint64) (*register.Profile, error) {
+	profile, err := cli.client.GetProfile(ctx, &register.ProfileReq{Uid: uid})
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return profile, nil
+}
+
+func (cli *RegisterRPCCli) Close() error {
+	return cli.conn.Close()
+}
+
+//服务返回的ecode保留在status的描述中，连接类的错误返回ServerErr
+func rpcError(err error) error {
+	if s, ok := status.FromError(err); ok && s.Code() == codes.Unknown {
+		return ecode.From(errors.New(s.Message()))
+	}
+	return ecode.ServerErr
+}
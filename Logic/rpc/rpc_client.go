@@ -1,6 +1,5 @@
 package rpc
 
 type RPCClient struct {
-	Register *RegisterRPCCli
+	Register *RegisterRPCCli //没有配置注册服务时为nil
 }
-
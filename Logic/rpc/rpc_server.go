@@ -1,5 +1,69 @@
 package rpc
 
+import (
+	"net"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/logic"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+//Logic对接入节点提供的gRPC服务：上行命令和认证
 type RPCServer struct {
+	server    *grpc.Server
 	rpcClient *RPCClient
-} 
+}
+
+func NewRPCServer(auth logic.AuthServer, concurrency int, rpcClient *RPCClient) *RPCServer {
+	server := grpc.NewServer(KeepaliveEnforcement)
+	upstream := NewUpstreamServer(concurrency)
+	upstream.Handle(external.Cmd_LOGIN, login)
+	upstream.Handle(external.Cmd_LOGOUT, logout)
+	upstream.HandleOffline(offline)
+	logic.RegisterLogicServer(server, upstream)
+	logic.RegisterAuthServer(server, auth)
+	return &RPCServer{server: server, rpcClient: rpcClient}
+}
+
+func (s *RPCServer) Serve(network, addr string) error {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		logger.Error("rpc listen", zap.String("addr", addr), zap.Error(err))
+		return err
+	}
+	go func() {
+		if err := s.server.Serve(l); err != nil {
+			logger.Debug("rpc serve", zap.String("addr", addr), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (s *RPCServer) Stop() {
+	s.server.GracefulStop()
+}
+
+//接入节点已经通过Auth.Verify校验了令牌，meta.Uid为令牌中的uid
+func login(ctx context.Context, meta *logic.Meta, req proto.Message) (proto.Message, error) {
+	logger.Info("session login", zap.Uint64("uid", meta.Uid), zap.String("accessId", meta.AccessId),
+		zap.Uint64("session", meta.SessionId), zap.String("deviceId", meta.DeviceId))
+	return &external.LoginAck{Uid: meta.Uid, ServerTime: time.Now().UnixNano() / int64(time.Millisecond)}, nil
+}
+
+func logout(ctx context.Context, meta *logic.Meta, req proto.Message) (proto.Message, error) {
+	logger.Info("session logout", zap.Uint64("uid", meta.Uid), zap.String("accessId", meta.AccessId),
+		zap.Uint64("session", meta.SessionId))
+	return &external.LogoutAck{}, nil
+}
+
+//连接断开、超时或被顶替，没有经过LOGOUT
+func offline(ctx context.Context, meta *logic.Meta) error {
+	logger.Info("session offline", zap.Uint64("uid", meta.Uid), zap.String("accessId", meta.AccessId),
+		zap.Uint64("session", meta.SessionId))
+	return nil
+}
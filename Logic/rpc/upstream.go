@@ -0,0 +1,144 @@
+package rpc
+
+import (
+	"io"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/logic"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+const defaultConcurrency = 256
+
+//接入节点每30s发送一次keepalive ping，需要放宽服务端的默认限制
+var KeepaliveEnforcement = grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+	MinTime:             10 * time.Second,
+	PermitWithoutStream: true,
+})
+
+//处理接入节点转发的一条命令，req的类型由external的命令表决定。
+//返回的错误转换为ecode回复给客户端
+type UpstreamHandler func(ctx context.Context, meta *logic.Meta, req proto.Message) (proto.Message, error)
+
+//处理接入节点上报的下线，客户端主动发送的LOGOUT由UpstreamHandler处理
+type OfflineHandler func(ctx context.Context, meta *logic.Meta) error
+
+//实现logic.LogicServer，按命令号分发接入节点转发的请求
+type UpstreamServer struct {
+	handlers    map[uint32]UpstreamHandler
+	offline     OfflineHandler //为nil时忽略下线事件
+	concurrency int
+}
+
+//concurrency为单条流同时处理的请求数，达到后暂停读取，由grpc流控把压力传回接入节点
+func NewUpstreamServer(concurrency int) *UpstreamServer {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	return &UpstreamServer{handlers: make(map[uint32]UpstreamHandler), concurrency: concurrency}
+}
+
+//注册需要在启动前完成
+func (s *UpstreamServer) Handle(cmd external.Cmd, handler UpstreamHandler) {
+	command := external.Lookup(uint32(cmd))
+	if command == nil || command.Push {
+		panic("upstream: not a client command " + cmd.String())
+	}
+	s.handlers[uint32(cmd)] = handler
+}
+
+//注册需要在启动前完成
+func (s *UpstreamServer) HandleOffline(handler OfflineHandler) {
+	s.offline = handler
+}
+
+//返回的错误为ecode
+func (s *UpstreamServer) Offline(ctx context.Context, req *logic.OfflineReq) (*logic.OfflineReply, error) {
+	if req.Meta == nil || req.Meta.Uid == 0 {
+		return nil, ecode.RequestErr
+	}
+	if s.offline != nil {
+		if err := s.offline(ctx, req.Meta); err != nil {
+			return nil, err
+		}
+	}
+	return &logic.OfflineReply{}, nil
+}
+
+func (s *UpstreamServer) Upstream(stream logic.Logic_UpstreamServer) error {
+	var (
+		sendMutex sync.Mutex
+		wg        sync.WaitGroup
+	)
+	sem := make(chan struct{}, s.concurrency)
+	defer wg.Wait()
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			resp := s.handle(stream.Context(), req)
+			sendMutex.Lock()
+			err := stream.Send(resp)
+			sendMutex.Unlock()
+			if err != nil {
+				logger.Debug("upstream send", zap.Uint64("callId", req.CallId), zap.Error(err))
+			}
+		}()
+	}
+}
+
+func (s *UpstreamServer) handle(ctx context.Context, req *logic.UpstreamReq) (resp *logic.UpstreamResp) {
+	handler := s.handlers[req.Cmd]
+	if handler == nil {
+		return errorResp(req, ecode.UnknownCmd)
+	}
+	msg := external.Lookup(req.Cmd).Req()
+	if err := proto.Unmarshal(req.Payload, msg); err != nil {
+		return errorResp(req, ecode.RequestErr)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("upstream panic", zap.Uint32("cmd", req.Cmd), zap.Any("panic", r),
+				zap.String("stack", string(debug.Stack())))
+			resp = errorResp(req, ecode.ServerErr)
+		}
+	}()
+	result, err := handler(ctx, req.Meta, msg)
+	if err != nil {
+		return errorResp(req, err)
+	}
+	resp = &logic.UpstreamResp{CallId: req.CallId, Cmd: req.Cmd}
+	if result != nil {
+		if resp.Payload, err = proto.Marshal(result); err != nil {
+			logger.Error("upstream marshal", zap.Uint32("cmd", req.Cmd), zap.Error(err))
+			return errorResp(req, ecode.ServerErr)
+		}
+	}
+	return resp
+}
+
+func errorResp(req *logic.UpstreamReq, err error) *logic.UpstreamResp {
+	code := ecode.From(err)
+	payload, _ := proto.Marshal(&external.Error{Cmd: req.Cmd, ErrCoed: code.Uint32(), ErrMsg: code.String()})
+	return &logic.UpstreamResp{CallId: req.CallId, Cmd: uint32(external.Cmd_ERROR), Payload: payload}
+}
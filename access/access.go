@@ -1,34 +1,159 @@
 package main
 
 import (
+	"github.com/imkuqin-zw/ZWChat/access/admin"
 	"github.com/imkuqin-zw/ZWChat/access/server"
 
 	"github.com/imkuqin-zw/ZWChat/access/config"
 	"flag"
 	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
 	"github.com/imkuqin-zw/ZWChat/access/rpc"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+	//注册etcd后端
 	"github.com/imkuqin-zw/ZWChat/lib/service_discovery/etcd"
 	"go.uber.org/zap"
 	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/lib/route"
+	"github.com/imkuqin-zw/ZWChat/lib/dynconf"
 )
 
 func main()  {
 	var err error
 	flag.Parse()
 	accessServer := server.New()
-	accessServer.Server, err = net_lib.Serve(config.Conf.Server.Proto, config.Conf.Server.Addr,
-		config.Conf.SessionCfg, 1)
+	if len(config.Conf.Server.Listeners) > 0 {
+		accessServer.Server, err = net_lib.ServeListeners(config.Conf.Server.Listeners, config.Conf.SessionCfg, 1)
+	} else {
+		accessServer.Server, err = net_lib.Serve(config.Conf.Server.Proto, config.Conf.Server.Addr,
+			config.Conf.SessionCfg, 1)
+	}
+	if err != nil {
+		return
+	}
+	if err = accessServer.Server.SetTrustedProxies(config.Conf.Server.TrustedProxies); err != nil {
+		logger.Fatal("SetTrustedProxies", zap.Error(err))
+		return
+	}
+	if config.Conf.Limit != nil {
+		if err = accessServer.Server.SetLimit(*config.Conf.Limit); err != nil {
+			logger.Fatal("SetLimit", zap.Error(err))
+			return
+		}
+	}
+	var adminServer *admin.Admin
+	if config.Conf.Admin != nil && config.Conf.Admin.Addr != "" {
+		adminServer = admin.New(accessServer.Server, accessServer.Metrics)
+		adminServer.Serve(config.Conf.Admin.Addr)
+	}
+	//服务注册和路由表共用一个租约，在两者关闭后撤销
+	lease, err := sharedLease()
 	if err != nil {
+		logger.Fatal("etcd lease", zap.Error(err))
 		return
 	}
-	rpcClient, err := rpc.NewRPCClient()
+	if lease != nil {
+		defer lease.Client().Close()
+		defer lease.Close()
+	}
+	//注册Push服务和发现Logic共用一个后端
+	var discovery service_discovery.Backend
+	if sd := config.Conf.ServiceDiscovery; sd != nil {
+		discovery, err = service_discovery.New(service_discovery.Cfg{
+			Backend: sd.Backend, Target: sd.Target, TTL: sd.TTL, Reload: sd.Reload, Lease: lease,
+		})
+		if err != nil {
+			logger.Fatal("service discovery", zap.String("backend", sd.Backend), zap.Error(err))
+			return
+		}
+		defer discovery.Close()
+	}
+	var logicCfg *rpc.LogicCfg
+	if config.Conf.RpcClient != nil {
+		logicCfg = config.Conf.RpcClient.Logic
+	}
+	rpcClient, err := rpc.NewRPCClient(logicCfg, discovery)
 	if err != nil {
 		return
 	}
-	logger.Info("server init success", zap.String("addr", config.Conf.Server.Addr))
+	var (
+		rpcServer *rpc.RPCServer
+		reporter  *loadReporter
+	)
+	if config.Conf.RpcServer != nil && config.Conf.RpcServer.Addr != "" {
+		rpcServer = rpc.NewRPCServer(accessServer.Server.Manager())
+		if err = rpcServer.Serve(rpcNetwork(config.Conf.RpcServer.Proto), config.Conf.RpcServer.Addr); err != nil {
+			return
+		}
+		//Logic通过服务发现找到接入节点的Push服务
+		if sd := config.Conf.ServiceDiscovery; sd != nil {
+			if err = discovery.Register(sd.ServerName, service_discovery.Instance{
+				Addr: sd.RpcAddr, Version: sd.Version, Zone: sd.Zone, Weight: sd.Weight, Endpoints: sd.Endpoints,
+			}); err != nil {
+				logger.Error("service register", zap.Error(err))
+			}
+			reporter = newLoadReporter(discovery, sd.ServerName, sd.RpcAddr, sd.Interval, accessServer.Server.Manager())
+			go reporter.run()
+			if adminServer != nil {
+				adminServer.SetDrainer(reporter.SetDraining)
+			}
+		}
+	}
+	for _, l := range accessServer.Server.Listeners() {
+		logger.Info("server init success", zap.String("addr", l.Addr().String()))
+	}
+	accessServer.Reactor = config.Conf.Server.Reactor
+	if config.Conf.RateLimit != nil {
+		accessServer.SetRateLimit(*config.Conf.RateLimit)
+	}
+	accessServer.NodeId = config.Conf.NodeId
+	if cfg := config.Conf.Route; cfg != nil {
+		if etcdCfg := config.Conf.Etcd; etcdCfg != nil {
+			if cfg.Prefix == "" {
+				cfg.Prefix = etcdCfg.Prefix
+			}
+			if cfg.DialTimeout == 0 {
+				cfg.DialTimeout = etcdCfg.DialTimeout
+			}
+		}
+		cfg.Lease = lease
+		if accessServer.Routes, err = route.New(*cfg); err != nil {
+			logger.Fatal("route store", zap.Error(err))
+			return
+		}
+		defer accessServer.Routes.Close()
+	}
+	//etcd中的配置覆盖access.yaml，修改后立即生效
+	if cfg := config.Conf.Dynamic; cfg != nil {
+		if etcdCfg := config.Conf.Etcd; etcdCfg != nil {
+			if cfg.Prefix == "" {
+				cfg.Prefix = etcdCfg.Prefix
+			}
+			if cfg.DialTimeout == 0 {
+				cfg.DialTimeout = etcdCfg.DialTimeout
+			}
+		}
+		watcher, err := dynconf.NewWatcher(*cfg, newDynamic(accessServer))
+		if err != nil {
+			logger.Fatal("dynamic config", zap.Error(err))
+			return
+		}
+		defer watcher.Close()
+	}
+	go handleUpgrade(accessServer, adminServer, rpcServer, reporter)
+	//由旧进程平滑升级启动时，通知旧进程开始关闭
+	if err = net_lib.UpgradeReady(); err != nil {
+		logger.Error("UpgradeReady", zap.Error(err))
+	}
 	accessServer.Loop(rpcClient)
 }
 
+func rpcNetwork(proto string) string {
+	if proto == "" {
+		return "tcp"
+	}
+	return proto
+}
+
 func init()  {
 	if err := config.Init(); err != nil {
 		panic(err)
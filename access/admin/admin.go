@@ -0,0 +1,159 @@
+package admin
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/access/router"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"go.uber.org/zap"
+)
+
+//接入服务的管理接口:
+//
+//	GET    /limit/stats                   连接数和被拒绝的连接数
+//	GET    /bans                          封禁列表
+//	POST   /bans   target=ip|cidr&ttl=10m 封禁，ttl为空时永久封禁
+//	DELETE /bans?target=ip|cidr           解除封禁
+//	GET    /router/stats                  各命令的请求数、错误数和耗时
+//	POST   /drain                         标记为排空，dispatch不再分配新的客户端
+//	DELETE /drain                         取消排空
+type Admin struct {
+	server   *net_lib.Server
+	metrics  *router.Metrics
+	listener net.Listener
+	mutex    sync.Mutex
+	drainer  func(draining bool) //没有注册到服务发现时为nil
+}
+
+func New(server *net_lib.Server, metrics *router.Metrics) *Admin {
+	return &Admin{server: server, metrics: metrics}
+}
+
+func (admin *Admin) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/limit/stats", admin.limitStats)
+	mux.HandleFunc("/bans", admin.bans)
+	mux.HandleFunc("/router/stats", admin.routerStats)
+	mux.HandleFunc("/drain", admin.drain)
+	return mux
+}
+
+//平滑升级时新进程继承旧进程的监听
+func (admin *Admin) Serve(addr string) error {
+	l, err := net_lib.Listen("tcp", addr)
+	if err != nil {
+		logger.Error("admin listen", zap.String("addr", addr), zap.Error(err))
+		return err
+	}
+	admin.listener = l
+	go func() {
+		if err := http.Serve(l, admin.Handler()); err != nil {
+			logger.Debug("admin serve", zap.String("addr", addr), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+//Serve之前返回nil
+func (admin *Admin) Listener() net.Listener {
+	return admin.listener
+}
+
+func (admin *Admin) limitStats(w http.ResponseWriter, r *http.Request) {
+	limiter := admin.limiter(w)
+	if limiter == nil {
+		return
+	}
+	writeJson(w, limiter.Stats())
+}
+
+func (admin *Admin) routerStats(w http.ResponseWriter, r *http.Request) {
+	if admin.metrics == nil {
+		http.Error(w, "router metrics not configured", http.StatusNotFound)
+		return
+	}
+	writeJson(w, admin.metrics.Stats())
+}
+
+func (admin *Admin) bans(w http.ResponseWriter, r *http.Request) {
+	limiter := admin.limiter(w)
+	if limiter == nil {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, limiter.Bans())
+	case http.MethodPost:
+		var ttl time.Duration
+		if value := r.FormValue("ttl"); value != "" {
+			var err error
+			if ttl, err = time.ParseDuration(value); err != nil || ttl < 0 {
+				http.Error(w, "invalid ttl", http.StatusBadRequest)
+				return
+			}
+		}
+		target := r.FormValue("target")
+		if err := limiter.Ban(target, ttl); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Info("admin ban", zap.String("target", target), zap.Duration("ttl", ttl))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		target := r.FormValue("target")
+		if !limiter.Unban(target) {
+			http.Error(w, "ban not found", http.StatusNotFound)
+			return
+		}
+		logger.Info("admin unban", zap.String("target", target))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (admin *Admin) SetDrainer(f func(draining bool)) {
+	admin.mutex.Lock()
+	admin.drainer = f
+	admin.mutex.Unlock()
+}
+
+func (admin *Admin) drain(w http.ResponseWriter, r *http.Request) {
+	admin.mutex.Lock()
+	drainer := admin.drainer
+	admin.mutex.Unlock()
+	if drainer == nil {
+		http.Error(w, "service discovery not configured", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		drainer(true)
+	case http.MethodDelete:
+		drainer(false)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (admin *Admin) limiter(w http.ResponseWriter) *net_lib.Limiter {
+	limiter := admin.server.Limiter()
+	if limiter == nil {
+		http.Error(w, "limit not configured", http.StatusNotFound)
+	}
+	return limiter
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("admin writeJson", zap.Error(err))
+	}
+}
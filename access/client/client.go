@@ -2,24 +2,25 @@ package client
 
 import (
 	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"github.com/imkuqin-zw/ZWChat/access/router"
 	"github.com/imkuqin-zw/ZWChat/access/rpc"
 )
 
 type Client struct {
 	Session *net_lib.Session
 	rpcClient *rpc.RPCClient
+	router *router.Router
 }
 
-func New(session *net_lib.Session, rpcClient *rpc.RPCClient) *Client {
+func New(session *net_lib.Session, rpcClient *rpc.RPCClient, router *router.Router) *Client {
 	return &Client{
 		Session: session,
 		rpcClient: rpcClient,
+		router: router,
 	}
 }
 
-func (client *Client) Parse(cmd uint32, reqData []byte) (err error) {
-	switch cmd {
-
-	}
-	return
-}
\ No newline at end of file
+//按命令号交给router处理，未注册的命令和处理失败时回复external.Error
+func (client *Client) Parse(env *net_lib.Envelope) (err error) {
+	return client.router.Dispatch(client.Session, env)
+}
@@ -7,8 +7,13 @@ import (
 	"io/ioutil"
 	"path/filepath"
 	"os"
+	"time"
 	"go.uber.org/zap"
 	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"github.com/imkuqin-zw/ZWChat/access/router"
+	"github.com/imkuqin-zw/ZWChat/access/rpc"
+	"github.com/imkuqin-zw/ZWChat/lib/route"
+	"github.com/imkuqin-zw/ZWChat/lib/dynconf"
 )
 
 var (
@@ -17,7 +22,7 @@ var (
 )
 
 type Config struct {
-	Server           *commconf.Server                 `yaml:"server"`
+	Server           *Server                          `yaml:"server"`
 	Path             *commconf.Path                   `yaml:"path"`
 	ServiceDiscovery *commconf.ServiceDiscoveryServer `yaml:"serviceDiscovery"`
 	RpcClient        *RpcClient                       `yaml:"rpcClient"`
@@ -25,10 +30,30 @@ type Config struct {
 	Etcd             *commconf.Etcd                   `yaml:"etcd"`
 	Log              *zap.Config                      `yaml:"log"`
 	SessionCfg       *net_lib.SessionCfg              `yaml:"sessionCfg"`
+	Limit            *net_lib.LimitCfg                `yaml:"limit"`
+	Admin            *commconf.Admin                  `yaml:"admin"`
+	Upgrade          *Upgrade                         `yaml:"upgrade"`
+	NodeId           string                           `yaml:"nodeId"` //接入节点id，为空时使用rpcAddr或主机名
+	RateLimit        *router.RateLimitCfg             `yaml:"rateLimit"`
+	Route            *route.Cfg                       `yaml:"route"`
+	Dynamic          *dynconf.Cfg                     `yaml:"dynamic"` //为空时不从etcd读取动态配置
+}
+
+//收到SIGUSR2时启动新进程接管监听，旧进程向连接发送GOAWAY后退出
+type Upgrade struct {
+	ReadyTimeout time.Duration `yaml:"readyTimeout"` //等待新进程开始接受连接的时间
+	DrainTimeout time.Duration `yaml:"drainTimeout"` //等待客户端断开的时间，超时后强制关闭
+}
+
+//listeners为空时使用proto和addr作为唯一的监听
+type Server struct {
+	commconf.Server `yaml:",inline"`
+	Listeners       []*net_lib.ListenerCfg `yaml:"listeners"`
+	Reactor         *net_lib.ReactorCfg    `yaml:"reactor"` //不为空时使用epoll处理连接(仅linux)
 }
 
 type RpcClient struct {
-	LoginClient *commconf.ServiceDiscoveryClient `yaml:"loginClient"`
+	Logic *rpc.LogicCfg `yaml:"logic"` //为空时不转发到Logic
 }
 
 func init() {
@@ -45,6 +70,14 @@ func Init() (err error) {
 	if err = yaml.Unmarshal(configBody, Conf); err != nil {
 		return
 	}
+	if Conf.NodeId == "" && Conf.ServiceDiscovery != nil {
+		Conf.NodeId = Conf.ServiceDiscovery.RpcAddr
+	}
+	if Conf.NodeId == "" {
+		if Conf.NodeId, err = os.Hostname(); err != nil {
+			return
+		}
+	}
 	Conf.Path = &commconf.Path{}
 	Conf.Path.Root, err = filepath.Abs(filepath.Dir(os.Args[0]))
 	if err != nil {
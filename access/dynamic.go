@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/imkuqin-zw/ZWChat/access/config"
+	"github.com/imkuqin-zw/ZWChat/access/router"
+	"github.com/imkuqin-zw/ZWChat/access/server"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/lib/dynconf"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"go.uber.org/zap"
+)
+
+//可以在运行时修改的配置项，value为空时恢复为access.yaml中的配置:
+//
+//	sessionCfg  json，字段同access.yaml的sessionCfg，只对新建立的连接生效
+//	logLevel    debug、info、warn、error
+//	rateLimit   json，字段同access.yaml的rateLimit
+func newDynamic(accessServer *server.Server) *dynconf.Dynamic {
+	var audit io.Writer
+	if path := config.Conf.Dynamic.Audit; path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			logger.Error("open audit log", zap.String("path", path), zap.Error(err))
+		} else {
+			audit = f
+		}
+	}
+	dynamic := dynconf.New(audit)
+
+	var sessionCfg net_lib.SessionCfg
+	if config.Conf.SessionCfg != nil {
+		sessionCfg = *config.Conf.SessionCfg
+	}
+	dynamic.Register("sessionCfg", func(value []byte) error {
+		cfg := sessionCfg
+		if value != nil {
+			if err := json.Unmarshal(value, &cfg); err != nil {
+				return err
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		accessServer.Server.SetSessionCfg(cfg)
+		return nil
+	})
+
+	level := logger.Level()
+	dynamic.Register("logLevel", func(value []byte) error {
+		if value == nil {
+			return logger.SetLevel(level)
+		}
+		return logger.SetLevel(strings.TrimSpace(string(value)))
+	})
+
+	var rateLimit router.RateLimitCfg
+	if config.Conf.RateLimit != nil {
+		rateLimit = *config.Conf.RateLimit
+	}
+	dynamic.Register("rateLimit", func(value []byte) error {
+		cfg := rateLimit
+		if value != nil {
+			if err := json.Unmarshal(value, &cfg); err != nil {
+				return err
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		accessServer.SetRateLimit(cfg)
+		return nil
+	})
+	return dynamic
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	etcdv3 "github.com/coreos/etcd/clientv3"
+	"github.com/imkuqin-zw/ZWChat/access/config"
+	"github.com/imkuqin-zw/ZWChat/lib/etcd_lib"
+	"github.com/imkuqin-zw/ZWChat/lib/route"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+)
+
+const defaultLeaseTTL = 10 * time.Second
+
+//服务注册和路由表使用同一个etcd时共用一个client和租约，进程崩溃后两者在同一个ttl内过期。
+//不使用同一个etcd时返回nil，各自创建。调用方先关闭注册和路由表，再关闭租约和client
+func sharedLease() (*etcd_lib.LeaseKeeper, error) {
+	sd, routeCfg := config.Conf.ServiceDiscovery, config.Conf.Route
+	if sd == nil || routeCfg == nil || routeCfg.Store != route.StoreEtcd || routeCfg.Target != sd.Target {
+		return nil, nil
+	}
+	if sd.Backend != "" && sd.Backend != service_discovery.BackendEtcd {
+		return nil, nil
+	}
+	client, err := etcdv3.New(etcdv3.Config{
+		Endpoints:   strings.Split(sd.Target, ","),
+		DialTimeout: config.Conf.Etcd.DialTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return etcd_lib.NewLeaseKeeper(client, leaseTTL(sd.TTL, routeCfg.TTL), "access"), nil
+}
+
+//取两者中较小的，都没有配置时使用默认值
+func leaseTTL(ttls ...time.Duration) time.Duration {
+	var ttl time.Duration
+	for _, item := range ttls {
+		if item > 0 && (ttl == 0 || item < ttl) {
+			ttl = item
+		}
+	}
+	if ttl == 0 {
+		ttl = defaultLeaseTTL
+	}
+	return ttl
+}
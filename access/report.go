@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/lib/cpu"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+	"go.uber.org/zap"
+)
+
+const defaultReportInterval = 5 * time.Second
+
+//定期把连接数、cpu和排空状态上报到服务发现，dispatch据此为客户端选择接入节点
+type loadReporter struct {
+	registry service_discovery.Registry
+	name     string
+	addr     string
+	interval time.Duration
+	manager  *net_lib.Manager
+	cpu      *cpu.Sampler
+	draining int32
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newLoadReporter(registry service_discovery.Registry, name, addr string, interval time.Duration,
+	manager *net_lib.Manager) *loadReporter {
+	if interval <= 0 {
+		interval = defaultReportInterval
+	}
+	return &loadReporter{
+		registry: registry,
+		name:     name,
+		addr:     addr,
+		interval: interval,
+		manager:  manager,
+		cpu:      cpu.NewSampler(),
+		stop:     make(chan struct{}),
+	}
+}
+
+func (r *loadReporter) run() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.report()
+		}
+	}
+}
+
+func (r *loadReporter) report() {
+	load := service_discovery.Load{
+		Conns:    int64(r.manager.Count()),
+		CPU:      r.cpu.Usage(),
+		Draining: atomic.LoadInt32(&r.draining) == 1,
+	}
+	if err := r.registry.SetLoad(r.name, r.addr, load); err != nil {
+		logger.Error("report load", zap.Error(err))
+	}
+}
+
+//立即上报，dispatch不再把新的客户端分配到排空中的节点，已有的连接不受影响
+func (r *loadReporter) SetDraining(draining bool) {
+	var value int32
+	if draining {
+		value = 1
+	}
+	atomic.StoreInt32(&r.draining, value)
+	logger.Info("set draining", zap.Bool("draining", draining))
+	r.report()
+}
+
+//平滑升级后由新进程上报
+func (r *loadReporter) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+}
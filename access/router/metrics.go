@@ -0,0 +1,115 @@
+package router
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+)
+
+//单个命令的统计
+type CmdStats struct {
+	Cmd       uint32
+	Name      string
+	Requests  uint64
+	Errors    uint64 //回复了错误码的请求，包括请求错误和限流
+	ServerErr uint64 //其中处理失败或panic的请求
+	TotalCost time.Duration
+	MaxCost   time.Duration
+}
+
+type cmdCounter struct {
+	name      string
+	requests  uint64
+	errors    uint64
+	serverErr uint64
+	totalCost int64
+	maxCost   int64
+}
+
+//按命令统计请求数、错误数和耗时，通过管理接口查看
+type Metrics struct {
+	mutex sync.RWMutex
+	cmds  map[uint32]*cmdCounter
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{cmds: make(map[uint32]*cmdCounter)}
+}
+
+func (metrics *Metrics) counter(ctx *Context) *cmdCounter {
+	metrics.mutex.RLock()
+	counter := metrics.cmds[ctx.Env.Cmd]
+	metrics.mutex.RUnlock()
+	if counter != nil {
+		return counter
+	}
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	if counter = metrics.cmds[ctx.Env.Cmd]; counter == nil {
+		counter = &cmdCounter{name: ctx.Route.Name}
+		metrics.cmds[ctx.Env.Cmd] = counter
+	}
+	return counter
+}
+
+//统计的中间件，放在Recovery之内才能统计到panic的请求
+func (metrics *Metrics) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) (resp proto.Message, err error) {
+			counter := metrics.counter(ctx)
+			start := time.Now()
+			//panic时不恢复，交给外层的Recovery，这里按ServerErr统计
+			finished := false
+			defer func() {
+				if !finished {
+					err = ecode.ServerErr
+				}
+				metrics.record(counter, time.Since(start), err)
+			}()
+			resp, err = next(ctx)
+			finished = true
+			return resp, err
+		}
+	}
+}
+
+func (metrics *Metrics) record(counter *cmdCounter, cost time.Duration, err error) {
+	atomic.AddUint64(&counter.requests, 1)
+	atomic.AddInt64(&counter.totalCost, int64(cost))
+	for {
+		max := atomic.LoadInt64(&counter.maxCost)
+		if int64(cost) <= max || atomic.CompareAndSwapInt64(&counter.maxCost, max, int64(cost)) {
+			break
+		}
+	}
+	if err != nil {
+		atomic.AddUint64(&counter.errors, 1)
+		if ecode.From(err) == ecode.ServerErr {
+			atomic.AddUint64(&counter.serverErr, 1)
+		}
+	}
+}
+
+//按命令号排序的统计
+func (metrics *Metrics) Stats() []CmdStats {
+	metrics.mutex.RLock()
+	stats := make([]CmdStats, 0, len(metrics.cmds))
+	for cmd, counter := range metrics.cmds {
+		stats = append(stats, CmdStats{
+			Cmd:       cmd,
+			Name:      counter.name,
+			Requests:  atomic.LoadUint64(&counter.requests),
+			Errors:    atomic.LoadUint64(&counter.errors),
+			ServerErr: atomic.LoadUint64(&counter.serverErr),
+			TotalCost: time.Duration(atomic.LoadInt64(&counter.totalCost)),
+			MaxCost:   time.Duration(atomic.LoadInt64(&counter.maxCost)),
+		})
+	}
+	metrics.mutex.RUnlock()
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Cmd < stats[j].Cmd })
+	return stats
+}
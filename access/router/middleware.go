@@ -0,0 +1,170 @@
+package router
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+)
+
+//处理函数panic时记录堆栈并回复ServerErr，应放在最外层
+func Recovery() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) (resp proto.Message, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("router panic", zap.Uint32("cmd", ctx.Env.Cmd), zap.Uint64("uid", ctx.UserId),
+						zap.Any("panic", r), zap.String("stack", string(debug.Stack())))
+					resp, err = nil, ecode.ServerErr
+				}
+			}()
+			return next(ctx)
+		}
+	}
+}
+
+//记录每条命令的耗时和结果
+func Logging() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) (proto.Message, error) {
+			start := time.Now()
+			resp, err := next(ctx)
+			fields := []zap.Field{zap.Uint32("cmd", ctx.Env.Cmd), zap.String("name", ctx.Route.Name),
+				zap.Uint64("uid", ctx.UserId), zap.Uint64("session", ctx.Session.Id()),
+				zap.Duration("cost", time.Since(start))}
+			if err != nil && ecode.From(err) == ecode.ServerErr {
+				logger.Error("router handle", append(fields, zap.Error(err))...)
+			} else {
+				logger.Debug("router handle", append(fields, zap.Error(err))...)
+			}
+			return resp, err
+		}
+	}
+}
+
+//注册时带有Auth选项的命令在未登录时回复NotLogin
+func AuthRequired() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) (proto.Message, error) {
+			if ctx.Route.Auth && ctx.UserId == 0 {
+				return nil, ecode.NotLogin
+			}
+			return next(ctx)
+		}
+	}
+}
+
+const rateSweepInterval = time.Minute
+
+//单个连接的请求速率限制
+type RateLimitCfg struct {
+	Rate  float64 `yaml:"rate"`  //每秒的请求数，为0时不限制
+	Burst int     `yaml:"burst"` //允许的突发请求数，默认等于rate
+}
+
+//按连接限制请求速率，rate为每秒的请求数，burst为允许的突发请求数，超过时回复TooManyRequests
+func RateLimit(rate float64, burst int) Middleware {
+	return NewRateLimiter(RateLimitCfg{Rate: rate, Burst: burst}).Middleware()
+}
+
+func (cfg RateLimitCfg) Validate() error {
+	if cfg.Rate < 0 || cfg.Burst < 0 {
+		return fmt.Errorf("invalid rate limit: rate %v, burst %d", cfg.Rate, cfg.Burst)
+	}
+	return nil
+}
+
+func (cfg RateLimitCfg) burst() float64 {
+	if cfg.Burst > 0 {
+		return float64(cfg.Burst)
+	}
+	if cfg.Rate < 1 {
+		return 1
+	}
+	return float64(int(cfg.Rate))
+}
+
+//可以在运行时修改速率的限流中间件，rate为0时不限制
+type RateLimiter struct {
+	limiter *rateLimiter
+}
+
+func NewRateLimiter(cfg RateLimitCfg) *RateLimiter {
+	return &RateLimiter{limiter: &rateLimiter{
+		rate:    cfg.Rate,
+		burst:   cfg.burst(),
+		buckets: make(map[uint64]*bucket),
+	}}
+}
+
+//已有连接的令牌在下次请求时按新的速率计算
+func (l *RateLimiter) Set(cfg RateLimitCfg) {
+	l.limiter.mutex.Lock()
+	defer l.limiter.mutex.Unlock()
+	l.limiter.rate, l.limiter.burst = cfg.Rate, cfg.burst()
+}
+
+func (l *RateLimiter) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) (proto.Message, error) {
+			if !l.limiter.allow(ctx.Session.Id(), time.Now()) {
+				return nil, ecode.TooManyRequests
+			}
+			return next(ctx)
+		}
+	}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	rate      float64
+	burst     float64
+	mutex     sync.Mutex
+	buckets   map[uint64]*bucket
+	lastSweep time.Time
+}
+
+func (limiter *rateLimiter) allow(sessionId uint64, now time.Time) bool {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if limiter.rate <= 0 {
+		return true
+	}
+	if now.Sub(limiter.lastSweep) > rateSweepInterval {
+		limiter.sweep(now)
+	}
+	b := limiter.buckets[sessionId]
+	if b == nil {
+		b = &bucket{tokens: limiter.burst, last: now}
+		limiter.buckets[sessionId] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * limiter.rate
+	if b.tokens > limiter.burst {
+		b.tokens = limiter.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+//删除令牌已经恢复满的连接，已关闭的连接在这里被清理，调用时需持有mutex
+func (limiter *rateLimiter) sweep(now time.Time) {
+	limiter.lastSweep = now
+	for id, b := range limiter.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*limiter.rate >= limiter.burst {
+			delete(limiter.buckets, id)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package router
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"go.uber.org/zap"
+)
+
+//错误响应的命令号，消息体为external.Error
+const CmdError = uint32(external.Cmd_ERROR)
+
+//处理一条命令，返回的消息作为响应发送给客户端，为nil时只在需要ack时回复空消息。
+//返回的错误转换为ecode，不是ecode的错误按ServerErr回复
+type HandlerFunc func(ctx *Context) (proto.Message, error)
+
+//包装处理函数，可以在调用next前后执行，不调用next时直接返回
+type Middleware func(next HandlerFunc) HandlerFunc
+
+//创建请求的消息结构，为nil时不解析消息体
+type NewRequest func() proto.Message
+
+type Route struct {
+	Cmd  uint32
+	Name string //用于日志和统计
+	Auth bool   //需要登录后才能调用
+	new  NewRequest
+	call HandlerFunc
+}
+
+type Option func(route *Route)
+
+//需要登录，配合AuthRequired中间件使用
+func Auth() Option {
+	return func(route *Route) { route.Auth = true }
+}
+
+func Name(name string) Option {
+	return func(route *Route) { route.Name = name }
+}
+
+type Context struct {
+	Session *net_lib.Session
+	UserId  uint64 //未登录时为0
+	Env     *net_lib.Envelope
+	Req     proto.Message //解析后的请求，类型由注册时的NewRequest决定
+	Route   *Route
+}
+
+//按命令号分发客户端的请求，注册完成后可以并发使用
+type Router struct {
+	middlewares []Middleware
+	routes      map[uint32]*Route
+}
+
+func New() *Router {
+	return &Router{routes: make(map[uint32]*Route)}
+}
+
+//添加中间件，先添加的在外层。只对之后注册的命令生效
+func (router *Router) Use(middlewares ...Middleware) {
+	router.middlewares = append(router.middlewares, middlewares...)
+}
+
+//注册命令，重复注册时覆盖之前的处理函数
+func (router *Router) Handle(cmd uint32, newReq NewRequest, handler HandlerFunc, opts ...Option) {
+	route := &Route{Cmd: cmd, new: newReq}
+	for _, opt := range opts {
+		opt(route)
+	}
+	//解析请求在最内层，解析失败也会经过日志和统计
+	call := func(ctx *Context) (proto.Message, error) {
+		if route.new != nil {
+			ctx.Req = route.new()
+			if err := ctx.Env.Unmarshal(ctx.Req); err != nil {
+				logger.Debug("router unmarshal", zap.Uint32("cmd", cmd), zap.Error(err))
+				return nil, ecode.RequestErr
+			}
+		}
+		return handler(ctx)
+	}
+	for i := len(router.middlewares) - 1; i >= 0; i-- {
+		call = router.middlewares[i](call)
+	}
+	route.call = call
+	router.routes[cmd] = route
+}
+
+//按external的命令表注册客户端命令，请求类型由命令表决定，默认以命令名作为Name
+func (router *Router) HandleCmd(cmd external.Cmd, handler HandlerFunc, opts ...Option) {
+	command := external.Lookup(uint32(cmd))
+	if command == nil || command.Push {
+		panic("router: not a client command " + cmd.String())
+	}
+	opts = append([]Option{Name(strings.ToLower(cmd.String()))}, opts...)
+	router.Handle(uint32(cmd), command.Req, handler, opts...)
+}
+
+func (router *Router) Route(cmd uint32) *Route {
+	return router.routes[cmd]
+}
+
+//处理一条请求并回复，出错时回复external.Error
+func (router *Router) Dispatch(session *net_lib.Session, env *net_lib.Envelope) error {
+	route := router.routes[env.Cmd]
+	if route == nil {
+		return ReplyError(session, env, ecode.UnknownCmd)
+	}
+	ctx := &Context{Session: session, UserId: session.GetUserId(), Env: env, Route: route}
+	resp, err := route.call(ctx)
+	if err != nil {
+		return ReplyError(session, env, err)
+	}
+	if resp == nil && env.Flags&net_lib.EnvelopeNeedAck == 0 {
+		return nil
+	}
+	reply, err := env.Reply(env.Cmd, resp)
+	if err != nil {
+		logger.Error("router reply", zap.Uint32("cmd", env.Cmd), zap.Error(err))
+		return ReplyError(session, env, ecode.ServerErr)
+	}
+	return session.Send(reply)
+}
+
+//回复错误，错误码通过ecode.From转换
+func ReplyError(session *net_lib.Session, env *net_lib.Envelope, err error) error {
+	code := ecode.From(err)
+	reply, err := env.Reply(CmdError, &external.Error{
+		Cmd:     env.Cmd,
+		ErrCoed: code.Uint32(),
+		ErrMsg:  code.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return session.Send(reply)
+}
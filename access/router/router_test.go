@@ -0,0 +1,238 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/lib/net_client"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+)
+
+//测试用的命令号，不在external的命令表中
+const (
+	cmdEcho uint32 = iota + 1000
+	cmdLogin
+	cmdProfile
+	cmdFail
+	cmdPanic
+)
+
+func newMsg() proto.Message { return &external.Msg{} }
+
+func testRouter(metrics *Metrics, middlewares ...Middleware) *Router {
+	r := New()
+	r.Use(Recovery(), metrics.Middleware(), Logging(), AuthRequired())
+	r.Use(middlewares...)
+	r.Handle(cmdEcho, newMsg, func(ctx *Context) (proto.Message, error) {
+		return ctx.Req, nil
+	})
+	r.Handle(cmdLogin, newMsg, func(ctx *Context) (proto.Message, error) {
+		ctx.Session.Bind(ctx.Req.(*external.Msg).From)
+		return nil, nil
+	}, Name("login"))
+	r.Handle(cmdProfile, nil, func(ctx *Context) (proto.Message, error) {
+		return &external.Msg{To: ctx.UserId}, nil
+	}, Auth())
+	r.Handle(cmdFail, nil, func(ctx *Context) (proto.Message, error) {
+		return nil, errors.New("db down")
+	})
+	r.Handle(cmdPanic, nil, func(ctx *Context) (proto.Message, error) {
+		panic("boom")
+	})
+	return r
+}
+
+//每个连接按顺序分发，与接入服务的sessionLoop相同
+func serve(t *testing.T, r *Router) *net_client.Client {
+	server, err := net_lib.Serve("tcp", "127.0.0.1:0", &net_lib.SessionCfg{MaxMsgSize: 64 << 10}, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	go func() {
+		for {
+			session, err := server.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				if session.InitCodec() != nil {
+					return
+				}
+				for {
+					env, err := session.Receive()
+					if err != nil {
+						return
+					}
+					r.Dispatch(session, env)
+				}
+			}()
+		}
+	}()
+	c, err := net_client.Dial(net_client.Options{Addr: server.Listener().Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func request(t *testing.T, c *net_client.Client, cmd uint32, msg proto.Message) *net_lib.Envelope {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	resp, err := c.Request(ctx, cmd, msg)
+	if err != nil {
+		t.Fatalf("cmd %d: %v", cmd, err)
+	}
+	return resp
+}
+
+func checkError(t *testing.T, resp *net_lib.Envelope, cmd uint32, code error) {
+	t.Helper()
+	if resp.Cmd != CmdError {
+		t.Fatalf("cmd %d: want error reply, got cmd %d", cmd, resp.Cmd)
+	}
+	msg := &external.Error{}
+	if err := resp.Unmarshal(msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Cmd != cmd || msg.ErrCoed != ecode.From(code).Uint32() || msg.ErrMsg != ecode.From(code).String() {
+		t.Fatalf("cmd %d: want error %v, got %+v", cmd, code, msg)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	metrics := NewMetrics()
+	c := serve(t, testRouter(metrics))
+
+	resp := request(t, c, cmdEcho, &external.Msg{MsgId: 1, Content: []byte("hello")})
+	msg := &external.Msg{}
+	if err := resp.Unmarshal(msg); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Cmd != cmdEcho || string(msg.Content) != "hello" {
+		t.Fatalf("unexpected echo cmd %d %+v", resp.Cmd, msg)
+	}
+
+	checkError(t, request(t, c, 99, nil), 99, ecode.UnknownCmd)
+	checkError(t, request(t, c, cmdFail, nil), cmdFail, ecode.ServerErr)
+	checkError(t, request(t, c, cmdPanic, nil), cmdPanic, ecode.ServerErr)
+
+	//登录前后调用需要登录的命令
+	checkError(t, request(t, c, cmdProfile, nil), cmdProfile, ecode.NotLogin)
+	c.Send(cmdLogin, &external.Msg{From: 42})
+	resp = request(t, c, cmdProfile, nil)
+	if err := resp.Unmarshal(msg); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Cmd != cmdProfile || msg.To != 42 {
+		t.Fatalf("unexpected profile cmd %d %+v", resp.Cmd, msg)
+	}
+
+	stats := make(map[uint32]CmdStats)
+	for _, item := range metrics.Stats() {
+		stats[item.Cmd] = item
+	}
+	if s := stats[cmdProfile]; s.Requests != 2 || s.Errors != 1 || s.ServerErr != 0 {
+		t.Fatalf("unexpected profile stats %+v", s)
+	}
+	if s := stats[cmdPanic]; s.Requests != 1 || s.ServerErr != 1 {
+		t.Fatalf("unexpected panic stats %+v", s)
+	}
+	if s := stats[cmdLogin]; s.Name != "login" || s.Requests != 1 {
+		t.Fatalf("unexpected login stats %+v", s)
+	}
+	if _, ok := stats[99]; ok {
+		t.Fatal("unknown command should not be counted")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	c := serve(t, testRouter(NewMetrics(), RateLimit(0.01, 2)))
+	for i := 0; i < 2; i++ {
+		if resp := request(t, c, cmdEcho, &external.Msg{}); resp.Cmd != cmdEcho {
+			t.Fatalf("request %d should pass, got cmd %d", i, resp.Cmd)
+		}
+	}
+	checkError(t, request(t, c, cmdEcho, &external.Msg{}), cmdEcho, ecode.TooManyRequests)
+}
+
+func TestRateLimiterSet(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitCfg{})
+	c := serve(t, testRouter(NewMetrics(), limiter.Middleware()))
+	for i := 0; i < 3; i++ {
+		if resp := request(t, c, cmdEcho, &external.Msg{}); resp.Cmd != cmdEcho {
+			t.Fatalf("request %d should pass, got cmd %d", i, resp.Cmd)
+		}
+	}
+	limiter.Set(RateLimitCfg{Rate: 0.01, Burst: 1})
+	request(t, c, cmdEcho, &external.Msg{})
+	checkError(t, request(t, c, cmdEcho, &external.Msg{}), cmdEcho, ecode.TooManyRequests)
+	limiter.Set(RateLimitCfg{})
+	if resp := request(t, c, cmdEcho, &external.Msg{}); resp.Cmd != cmdEcho {
+		t.Fatalf("limit should be disabled, got cmd %d", resp.Cmd)
+	}
+	if err := (RateLimitCfg{Rate: -1}).Validate(); err == nil {
+		t.Fatal("negative rate should fail")
+	}
+}
+
+func TestRateLimitSweep(t *testing.T) {
+	limiter := &rateLimiter{rate: 10, burst: 2, buckets: make(map[uint64]*bucket)}
+	now := time.Now()
+	limiter.lastSweep = now
+	limiter.allow(1, now)
+	limiter.allow(2, now)
+	limiter.allow(2, now)
+	//连接1的令牌已恢复满，连接2还在限流中
+	later := now.Add(rateSweepInterval + time.Millisecond)
+	limiter.buckets[2].last = later
+	limiter.allow(3, later)
+	if _, ok := limiter.buckets[1]; ok {
+		t.Fatal("idle bucket should be swept")
+	}
+	if _, ok := limiter.buckets[2]; !ok {
+		t.Fatal("active bucket should be kept")
+	}
+}
+
+//通过SDK调用按命令表注册的命令
+func TestHandleCmd(t *testing.T) {
+	r := New()
+	r.Use(AuthRequired())
+	r.HandleCmd(external.Cmd_HEARTBEAT, func(ctx *Context) (proto.Message, error) {
+		return &external.HeartbeatAck{ServerTime: ctx.Req.(*external.Heartbeat).ClientTime}, nil
+	})
+	r.HandleCmd(external.Cmd_SYNC, func(ctx *Context) (proto.Message, error) {
+		return &external.SyncAck{}, nil
+	}, Auth())
+	if route := r.Route(uint32(external.Cmd_HEARTBEAT)); route == nil || route.Name != "heartbeat" {
+		t.Fatalf("unexpected route %+v", route)
+	}
+	c := serve(t, r)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ack, err := c.Heartbeat(ctx)
+	if err != nil || ack.ServerTime == 0 {
+		t.Fatalf("heartbeat %+v %v", ack, err)
+	}
+	for cmd, call := range map[external.Cmd]func() error{
+		external.Cmd_LOGIN: func() error { _, err := c.Login(ctx, 1, "token"); return err },
+		external.Cmd_SYNC:  func() error { _, err := c.Sync(ctx, 0, 0); return err },
+	} {
+		want := ecode.UnknownCmd
+		if cmd == external.Cmd_SYNC {
+			want = ecode.NotLogin
+		}
+		serverErr, ok := call().(*net_client.ServerError)
+		if !ok || serverErr.Cmd != uint32(cmd) || serverErr.Code != want.Uint32() {
+			t.Fatalf("cmd %v: want server error %d, got %v", cmd, want.Uint32(), serverErr)
+		}
+	}
+}
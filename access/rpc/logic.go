@@ -1,26 +1,404 @@
 package rpc
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/logic"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
-	//"github.com/imkuqin-zw/ZWChat/etcd"
-	//"github.com/golang/glog"
-	//"github.com/imkuqin-zw/ZWChat/access/config"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultMaxInflight = 1024
+	defaultCallTimeout = 5 * time.Second
+	reconnectMin       = 100 * time.Millisecond
+	reconnectMax       = 5 * time.Second
 )
 
+//addrs不为空时使用静态地址，否则通过服务发现找到serverName的实例
+type LogicCfg struct {
+	ServerName  string        `yaml:"serverName"`
+	Addrs       []string      `yaml:"addrs"`
+	MaxInflight int           `yaml:"maxInflight"` //每个Logic实例等待响应的最大请求数，超过时调用方阻塞
+	CallTimeout time.Duration `yaml:"callTimeout"` //包括排队等待的时间
+}
+
+//接入节点到Logic的上行通道，每个Logic实例保持一条双向流。
+//同一个用户的请求总是发往同一个实例，实例不可用时顺延到下一个
 type LogicRPCCli struct {
-	conn *grpc.ClientConn
-}
-
-func NewLogicRPCCli() (logicRPCCli *LogicRPCCli, err error) {
-	//r := etcd.NewResolver(config.Conf.RpcClient.LoginClient.ServerName)
-	//b := grpc.RoundRobin(r)
-	//conn, err := grpc.Dial(config.Conf.RpcClient.LoginClient.Target, grpc.WithInsecure(), grpc.WithBalancer(b))
-	//if err != nil {
-	//	glog.Error(err)
-	//	panic(err)
-	//}
-	//logicRPCCli = &LogicRPCCli{
-	//	conn: conn,
-	//}
-	return
-}
\ No newline at end of file
+	cfg       LogicCfg
+	mutex     sync.RWMutex
+	upstreams map[string]*upstream
+	ring      []*upstream //按地址排序
+	closed    bool
+	cancel    context.CancelFunc //停止服务发现，使用静态地址时为nil
+	done      chan struct{}
+}
+
+//使用静态地址时discovery可以为nil
+func NewLogicRPCCli(cfg LogicCfg, discovery service_discovery.Discovery) (*LogicRPCCli, error) {
+	if cfg.MaxInflight <= 0 {
+		cfg.MaxInflight = defaultMaxInflight
+	}
+	if cfg.CallTimeout <= 0 {
+		cfg.CallTimeout = defaultCallTimeout
+	}
+	cli := &LogicRPCCli{cfg: cfg, upstreams: make(map[string]*upstream)}
+	if len(cfg.Addrs) > 0 {
+		for _, addr := range cfg.Addrs {
+			if err := cli.add(addr); err != nil {
+				cli.Close()
+				return nil, err
+			}
+		}
+		return cli, nil
+	}
+	if discovery == nil {
+		return nil, fmt.Errorf("logic: no addrs or service discovery")
+	}
+	//每个实例需要单独的流，不经过grpc的负载均衡，直接使用发现的实例
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := discovery.Watch(ctx, cfg.ServerName)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	cli.cancel, cli.done = cancel, make(chan struct{})
+	go cli.watch(ch)
+	return cli, nil
+}
+
+func (cli *LogicRPCCli) watch(ch <-chan []*service_discovery.Instance) {
+	defer close(cli.done)
+	for instances := range ch {
+		cli.update(instances)
+	}
+}
+
+//instances为当前全部实例
+func (cli *LogicRPCCli) update(instances []*service_discovery.Instance) {
+	set := make(map[string]bool, len(instances))
+	for _, ins := range instances {
+		set[ins.Addr] = true
+		if err := cli.add(ins.Addr); err != nil {
+			logger.Error("logic add", zap.String("addr", ins.Addr), zap.Error(err))
+		}
+	}
+	cli.mutex.RLock()
+	removed := make([]string, 0)
+	for addr := range cli.upstreams {
+		if !set[addr] {
+			removed = append(removed, addr)
+		}
+	}
+	cli.mutex.RUnlock()
+	for _, addr := range removed {
+		cli.remove(addr)
+	}
+}
+
+func (cli *LogicRPCCli) add(addr string) error {
+	cli.mutex.Lock()
+	defer cli.mutex.Unlock()
+	if cli.closed || cli.upstreams[addr] != nil {
+		return nil
+	}
+	//流控由grpc完成，keepalive用于尽早发现失联的实例，避免请求一直等待
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithKeepaliveParams(keepalive.ClientParameters{
+		Time:    30 * time.Second,
+		Timeout: 10 * time.Second,
+	}))
+	if err != nil {
+		return err
+	}
+	up := newUpstream(addr, conn, cli.cfg.MaxInflight)
+	cli.upstreams[addr] = up
+	cli.resetRing()
+	go up.run()
+	logger.Info("logic add", zap.String("addr", addr))
+	return nil
+}
+
+func (cli *LogicRPCCli) remove(addr string) {
+	cli.mutex.Lock()
+	up := cli.upstreams[addr]
+	if up != nil {
+		delete(cli.upstreams, addr)
+		cli.resetRing()
+	}
+	cli.mutex.Unlock()
+	if up != nil {
+		up.close()
+		logger.Info("logic remove", zap.String("addr", addr))
+	}
+}
+
+func (cli *LogicRPCCli) resetRing() {
+	ring := make([]*upstream, 0, len(cli.upstreams))
+	for _, up := range cli.upstreams {
+		ring = append(ring, up)
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].addr < ring[j].addr })
+	cli.ring = ring
+}
+
+func (cli *LogicRPCCli) pick(key uint64) *upstream {
+	cli.mutex.RLock()
+	defer cli.mutex.RUnlock()
+	n := uint64(len(cli.ring))
+	for i := uint64(0); i < n; i++ {
+		if up := cli.ring[(key+i)%n]; up.ready() {
+			return up
+		}
+	}
+	return nil
+}
+
+//转发一条命令并等待响应，payload为protobuf编码的请求。
+//ctx没有超时时间时使用CallTimeout，错误为ecode
+func (cli *LogicRPCCli) Call(ctx context.Context, meta *logic.Meta, cmd uint32, payload []byte) (*logic.UpstreamResp, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cli.cfg.CallTimeout)
+		defer cancel()
+	}
+	up := cli.pick(metaKey(meta))
+	if up == nil {
+		return nil, ecode.NoLogicServer
+	}
+	return up.call(ctx, &logic.UpstreamReq{Meta: meta, Cmd: cmd, Payload: payload})
+}
+
+//同一个用户的请求发往同一个实例，未登录时按连接选择
+func metaKey(meta *logic.Meta) uint64 {
+	if meta.Uid != 0 {
+		return meta.Uid
+	}
+	return meta.SessionId
+}
+
+//上报已登录连接的下线，发往该用户上行流所在的实例，错误为ecode
+func (cli *LogicRPCCli) Offline(ctx context.Context, meta *logic.Meta) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cli.cfg.CallTimeout)
+		defer cancel()
+	}
+	up := cli.pick(metaKey(meta))
+	if up == nil {
+		return ecode.NoLogicServer
+	}
+	if _, err := logic.NewLogicClient(up.conn).Offline(ctx, &logic.OfflineReq{Meta: meta}); err != nil {
+		return rpcError(err)
+	}
+	return nil
+}
+
+//通过Logic的Auth服务校验登录令牌，使用与上行流相同的连接和实例选择，错误为ecode
+func (cli *LogicRPCCli) Verify(ctx context.Context, meta *logic.Meta, token string) (*logic.VerifyReply, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cli.cfg.CallTimeout)
+		defer cancel()
+	}
+	up := cli.pick(meta.SessionId)
+	if up == nil {
+		return nil, ecode.NoLogicServer
+	}
+	reply, err := logic.NewAuthClient(up.conn).Verify(ctx, &logic.VerifyReq{Token: token, DeviceId: meta.DeviceId})
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return reply, nil
+}
+
+//Logic返回的ecode保留在status的描述中，其他错误按grpc的状态码转换
+func rpcError(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return ecode.ServerErr
+	}
+	switch s.Code() {
+	case codes.Unknown:
+		return ecode.From(errors.New(s.Message()))
+	case codes.DeadlineExceeded:
+		return ecode.LogicTimeout
+	case codes.Unavailable:
+		return ecode.NoLogicServer
+	}
+	return ecode.ServerErr
+}
+
+func (cli *LogicRPCCli) Close() {
+	//先停止服务发现，之后不会再有实例加入
+	if cli.cancel != nil {
+		cli.cancel()
+		<-cli.done
+	}
+	cli.mutex.Lock()
+	upstreams := cli.upstreams
+	cli.upstreams = make(map[string]*upstream)
+	cli.ring = nil
+	cli.closed = true
+	cli.mutex.Unlock()
+	for _, up := range upstreams {
+		up.close()
+	}
+}
+
+//与一个Logic实例之间的流，断开后自动重连，重连期间的调用直接失败
+type upstream struct {
+	addr      string
+	conn      *grpc.ClientConn
+	ctx       context.Context
+	cancel    context.CancelFunc
+	inflight  chan struct{} //满时调用方阻塞，Logic处理慢时压力传回客户端连接
+	sendMutex sync.Mutex    //流不能并发Send
+	mutex     sync.Mutex
+	stream    logic.Logic_UpstreamClient //断开时为nil
+	broken    chan struct{}              //当前流断开时关闭
+	callId    uint64
+	pending   map[uint64]chan *logic.UpstreamResp
+}
+
+func newUpstream(addr string, conn *grpc.ClientConn, maxInflight int) *upstream {
+	up := &upstream{
+		addr:     addr,
+		conn:     conn,
+		inflight: make(chan struct{}, maxInflight),
+		pending:  make(map[uint64]chan *logic.UpstreamResp),
+	}
+	up.ctx, up.cancel = context.WithCancel(context.Background())
+	return up
+}
+
+func (up *upstream) run() {
+	client := logic.NewLogicClient(up.conn)
+	backoff := reconnectMin
+	for {
+		start := time.Now()
+		stream, err := client.Upstream(up.ctx)
+		if err == nil {
+			broken := up.setStream(stream)
+			err = up.recvLoop(stream)
+			up.resetStream(broken)
+		}
+		if up.ctx.Err() != nil {
+			return
+		}
+		//流维持了较长时间说明实例正常，立即重连
+		if time.Since(start) > reconnectMax {
+			backoff = reconnectMin
+		}
+		logger.Warn("logic upstream broken", zap.String("addr", up.addr), zap.Duration("retry", backoff), zap.Error(err))
+		select {
+		case <-up.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > reconnectMax {
+			backoff = reconnectMax
+		}
+	}
+}
+
+func (up *upstream) setStream(stream logic.Logic_UpstreamClient) chan struct{} {
+	up.mutex.Lock()
+	defer up.mutex.Unlock()
+	up.stream = stream
+	up.broken = make(chan struct{})
+	return up.broken
+}
+
+//等待中的调用返回NoLogicServer
+func (up *upstream) resetStream(broken chan struct{}) {
+	up.mutex.Lock()
+	defer up.mutex.Unlock()
+	up.stream = nil
+	up.pending = make(map[uint64]chan *logic.UpstreamResp)
+	close(broken)
+}
+
+func (up *upstream) recvLoop(stream logic.Logic_UpstreamClient) error {
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		up.mutex.Lock()
+		ch := up.pending[resp.CallId]
+		delete(up.pending, resp.CallId)
+		up.mutex.Unlock()
+		//超时的调用已经移除，响应直接丢弃
+		if ch != nil {
+			ch <- resp
+		}
+	}
+}
+
+func (up *upstream) ready() bool {
+	up.mutex.Lock()
+	defer up.mutex.Unlock()
+	return up.stream != nil
+}
+
+func (up *upstream) forget(callId uint64) {
+	up.mutex.Lock()
+	delete(up.pending, callId)
+	up.mutex.Unlock()
+}
+
+func (up *upstream) call(ctx context.Context, req *logic.UpstreamReq) (*logic.UpstreamResp, error) {
+	select {
+	case up.inflight <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ecode.LogicBusy
+	}
+	defer func() { <-up.inflight }()
+	up.mutex.Lock()
+	stream, broken := up.stream, up.broken
+	if stream == nil {
+		up.mutex.Unlock()
+		return nil, ecode.NoLogicServer
+	}
+	up.callId++
+	req.CallId = up.callId
+	ch := make(chan *logic.UpstreamResp, 1)
+	up.pending[req.CallId] = ch
+	up.mutex.Unlock()
+
+	//grpc流控窗口用完时Send阻塞，直到Logic读取
+	up.sendMutex.Lock()
+	err := stream.Send(req)
+	up.sendMutex.Unlock()
+	if err != nil {
+		up.forget(req.CallId)
+		logger.Debug("logic upstream send", zap.String("addr", up.addr), zap.Error(err))
+		return nil, ecode.NoLogicServer
+	}
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-broken:
+		return nil, ecode.NoLogicServer
+	case <-ctx.Done():
+		up.forget(req.CallId)
+		return nil, ecode.LogicTimeout
+	}
+}
+
+func (up *upstream) close() {
+	up.cancel()
+	up.conn.Close()
+}
@@ -0,0 +1,283 @@
+package rpc
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/imkuqin-zw/ZWChat/Logic/auth"
+	logicrpc "github.com/imkuqin-zw/ZWChat/Logic/rpc"
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/logic"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func serveLogic(t *testing.T, addr string, upstream *logicrpc.UpstreamServer) (*grpc.Server, string) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(logicrpc.KeepaliveEnforcement)
+	logic.RegisterLogicServer(server, upstream)
+	go server.Serve(lis)
+	return server, lis.Addr().String()
+}
+
+func newCli(t *testing.T, addr string, maxInflight int) *LogicRPCCli {
+	cli, err := NewLogicRPCCli(LogicCfg{Addrs: []string{addr}, MaxInflight: maxInflight, CallTimeout: 2 * time.Second}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cli.Close)
+	waitReady(t, cli, true)
+	return cli
+}
+
+func waitReady(t *testing.T, cli *LogicRPCCli, ready bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for (cli.pick(0) != nil) != ready {
+		if time.Now().After(deadline) {
+			t.Fatalf("upstream ready should be %v", ready)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func send(ctx context.Context, cli *LogicRPCCli, uid uint64, clientMsgId string) (*external.SendMsgAck, error) {
+	payload, _ := proto.Marshal(&external.SendP2PMsg{ClientMsgId: clientMsgId})
+	resp, err := cli.Call(ctx, &logic.Meta{Uid: uid, SessionId: 1, AccessId: "access-1"},
+		uint32(external.Cmd_SEND_P2P_MSG), payload)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Cmd != uint32(external.Cmd_SEND_P2P_MSG) {
+		return nil, fmt.Errorf("unexpected resp cmd %d", resp.Cmd)
+	}
+	ack := &external.SendMsgAck{}
+	return ack, proto.Unmarshal(resp.Payload, ack)
+}
+
+func echoServer(release chan struct{}) *logicrpc.UpstreamServer {
+	upstream := logicrpc.NewUpstreamServer(0)
+	upstream.Handle(external.Cmd_SEND_P2P_MSG, func(ctx context.Context, meta *logic.Meta, req proto.Message) (proto.Message, error) {
+		if release != nil {
+			<-release
+		}
+		time.Sleep(time.Duration(meta.Uid%5) * time.Millisecond)
+		return &external.SendMsgAck{ClientMsgId: req.(*external.SendP2PMsg).ClientMsgId, MsgId: meta.Uid}, nil
+	})
+	return upstream
+}
+
+//并发请求乱序返回，响应通过callId回到各自的调用方
+func TestLogicCall(t *testing.T) {
+	server, addr := serveLogic(t, "127.0.0.1:0", echoServer(nil))
+	defer server.Stop()
+	cli := newCli(t, addr, 0)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(uid uint64) {
+			defer wg.Done()
+			clientMsgId := fmt.Sprint("msg-", uid)
+			ack, err := send(context.Background(), cli, uid, clientMsgId)
+			if err == nil && (ack.ClientMsgId != clientMsgId || ack.MsgId != uid) {
+				err = fmt.Errorf("uid %d got %+v", uid, ack)
+			}
+			if err != nil {
+				errs <- err
+			}
+		}(uint64(i + 1))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	//Logic没有注册的命令回复external.Error
+	resp, err := cli.Call(context.Background(), &logic.Meta{Uid: 1}, uint32(external.Cmd_SYNC), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &external.Error{}
+	if err = proto.Unmarshal(resp.Payload, e); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Cmd != uint32(external.Cmd_ERROR) || e.Cmd != uint32(external.Cmd_SYNC) || e.ErrCoed != ecode.UnknownCmd.Uint32() {
+		t.Fatalf("unexpected error resp cmd %d %+v", resp.Cmd, e)
+	}
+}
+
+//等待响应的请求达到上限后，新的请求在超时前得不到发送机会
+func TestLogicBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	server, addr := serveLogic(t, "127.0.0.1:0", echoServer(release))
+	defer server.Stop()
+	cli := newCli(t, addr, 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(uid uint64) {
+			defer wg.Done()
+			if _, err := send(context.Background(), cli, uid, "blocked"); err != nil {
+				t.Error(err)
+			}
+		}(uint64(i))
+	}
+	up := cli.pick(0)
+	for len(up.inflight) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := send(ctx, cli, 3, "rejected"); err != ecode.LogicBusy {
+		t.Fatalf("want LogicBusy, got %v", err)
+	}
+	close(release)
+	wg.Wait()
+	if _, err := send(context.Background(), cli, 4, "after"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//流断开时等待中的请求立即失败，Logic恢复后自动重连
+func TestLogicReconnect(t *testing.T) {
+	release := make(chan struct{})
+	server, addr := serveLogic(t, "127.0.0.1:0", echoServer(release))
+	cli := newCli(t, addr, 0)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := send(context.Background(), cli, 1, "pending")
+		done <- err
+	}()
+	up := cli.pick(0)
+	for len(up.inflight) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	server.Stop()
+	select {
+	case err := <-done:
+		if err != ecode.NoLogicServer {
+			t.Fatalf("want NoLogicServer, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending call should fail when the stream breaks")
+	}
+	close(release)
+	waitReady(t, cli, false)
+	if _, err := send(context.Background(), cli, 2, "down"); err != ecode.NoLogicServer {
+		t.Fatalf("want NoLogicServer, got %v", err)
+	}
+
+	server, _ = serveLogic(t, addr, echoServer(nil))
+	defer server.Stop()
+	waitReady(t, cli, true)
+	if _, err := send(context.Background(), cli, 3, "up"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//通过服务发现找到Logic实例，实例下线后不再使用
+func TestLogicDiscovery(t *testing.T) {
+	server, addr := serveLogic(t, "127.0.0.1:0", echoServer(nil))
+	defer server.Stop()
+	discovery := service_discovery.NewMemory()
+	cli, err := NewLogicRPCCli(LogicCfg{ServerName: "logic"}, discovery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	if _, err = send(context.Background(), cli, 1, "none"); err != ecode.NoLogicServer {
+		t.Fatalf("want NoLogicServer, got %v", err)
+	}
+	discovery.Register("logic", service_discovery.Instance{Addr: addr})
+	waitReady(t, cli, true)
+	if _, err = send(context.Background(), cli, 1, "found"); err != nil {
+		t.Fatal(err)
+	}
+	discovery.Deregister("logic", addr)
+	waitReady(t, cli, false)
+
+	if _, err = NewLogicRPCCli(LogicCfg{ServerName: "logic"}, nil); err == nil {
+		t.Fatal("no addrs and no discovery should fail")
+	}
+}
+
+//Logic返回的ecode原样传回接入节点
+func TestLogicVerify(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials := auth.NewMemoryCredentials([]auth.User{{Account: "alice", Uid: 7, Password: "secret"}})
+	service, err := auth.NewService(auth.Cfg{Keys: []auth.Key{{Id: "k1", Secret: "0123456789abcdef"}}},
+		auth.NewMemoryStore(), credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(logicrpc.KeepaliveEnforcement)
+	logic.RegisterLogicServer(server, logicrpc.NewUpstreamServer(0))
+	logic.RegisterAuthServer(server, service)
+	go server.Serve(lis)
+	defer server.Stop()
+	cli := newCli(t, lis.Addr().String(), 0)
+
+	tokens, err := service.Login(context.Background(), &logic.LoginReq{Account: "alice", Password: "secret", DeviceId: "d1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := cli.Verify(context.Background(), &logic.Meta{SessionId: 1, DeviceId: "d1"}, tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Uid != 7 || reply.DeviceId != "d1" {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+	if _, err = cli.Verify(context.Background(), &logic.Meta{SessionId: 1, DeviceId: "d2"}, tokens.AccessToken); err != ecode.TokenInvalid {
+		t.Fatalf("want TokenInvalid, got %v", err)
+	}
+	if _, err = cli.Verify(context.Background(), &logic.Meta{SessionId: 1}, ""); err != ecode.NoToken {
+		t.Fatalf("want NoToken, got %v", err)
+	}
+}
+
+//下线事件发往用户上行流所在的实例，uid为0时Logic返回RequestErr
+func TestLogicOffline(t *testing.T) {
+	got := make(chan *logic.Meta, 1)
+	upstream := logicrpc.NewUpstreamServer(0)
+	upstream.HandleOffline(func(ctx context.Context, meta *logic.Meta) error {
+		got <- meta
+		return nil
+	})
+	server, addr := serveLogic(t, "127.0.0.1:0", upstream)
+	defer server.Stop()
+	cli := newCli(t, addr, 0)
+
+	meta := &logic.Meta{Uid: 7, SessionId: 3, AccessId: "access-1", DeviceId: "d1"}
+	if err := cli.Offline(context.Background(), meta); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-got:
+		if !proto.Equal(m, meta) {
+			t.Fatalf("unexpected meta %+v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("offline not received")
+	}
+	if err := cli.Offline(context.Background(), &logic.Meta{SessionId: 3}); err != ecode.RequestErr {
+		t.Fatalf("want RequestErr, got %v", err)
+	}
+}
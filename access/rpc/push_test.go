@@ -0,0 +1,209 @@
+package rpc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/access"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/lib/net_client"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+//登录只绑定用户，推送通过Push服务调用
+func servePush(t *testing.T) (*net_lib.Server, access.PushClient) {
+	server, err := net_lib.Serve("tcp", "127.0.0.1:0", &net_lib.SessionCfg{MaxMsgSize: 64 << 10}, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	go func() {
+		for {
+			session, err := server.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				if session.InitCodec() != nil {
+					return
+				}
+				for {
+					env, err := session.Receive()
+					if err != nil {
+						return
+					}
+					req := &external.Login{}
+					if env.Cmd != uint32(external.Cmd_LOGIN) || env.Unmarshal(req) != nil {
+						continue
+					}
+					session.Bind(req.Uid)
+					if reply, err := env.Reply(env.Cmd, &external.LoginAck{Uid: req.Uid}); err == nil {
+						session.Send(reply)
+					}
+				}
+			}()
+		}
+	}()
+
+	rpcServer := NewRPCServer(server.Manager())
+	if err = rpcServer.Serve("tcp", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rpcServer.Stop)
+	conn, err := grpc.Dial(rpcServer.Listener().Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return server, access.NewPushClient(conn)
+}
+
+func login(t *testing.T, server *net_lib.Server, uid uint64) *net_client.Client {
+	c, err := net_client.Dial(net_client.Options{Addr: server.Listener().Addr().String(), MaxRetries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err = c.Login(ctx, uid, "token"); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func receive(t *testing.T, c *net_client.Client, cmd external.Cmd) proto.Message {
+	t.Helper()
+	select {
+	case p := <-c.Receive():
+		msg, err := net_client.Decode(p)
+		if err != nil || p.Cmd != uint32(cmd) {
+			t.Fatalf("want push %v, got cmd %d %v", cmd, p.Cmd, err)
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatalf("push %v not received", cmd)
+	}
+	return nil
+}
+
+func expectNothing(t *testing.T, c *net_client.Client) {
+	t.Helper()
+	select {
+	case p := <-c.Receive():
+		t.Fatalf("unexpected push cmd %d", p.Cmd)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func pushMsg(content string) *access.PushMsg {
+	payload, _ := proto.Marshal(&external.Msg{Content: []byte(content)})
+	return &access.PushMsg{Cmd: uint32(external.Cmd_PUSH_MSG), Payload: payload}
+}
+
+func TestPush(t *testing.T) {
+	server, push := servePush(t)
+	a, b := login(t, server, 1), login(t, server, 2)
+	ctx := context.Background()
+
+	reply, err := push.PushToUser(ctx, &access.PushToUserReq{Uids: []uint64{1, 2, 3}, Msg: pushMsg("user")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Deliveries) != 3 {
+		t.Fatalf("unexpected deliveries %v", reply.Deliveries)
+	}
+	sessions := make(map[uint64]uint64)
+	for _, d := range reply.Deliveries {
+		want := access.Status_OK
+		if d.Uid == 3 {
+			want = access.Status_OFFLINE
+		}
+		if d.Status != want {
+			t.Fatalf("uid %d: want %v, got %v", d.Uid, want, d.Status)
+		}
+		sessions[d.Uid] = d.SessionId
+	}
+	for _, c := range []*net_client.Client{a, b} {
+		if msg := receive(t, c, external.Cmd_PUSH_MSG).(*external.Msg); string(msg.Content) != "user" {
+			t.Fatalf("unexpected push %+v", msg)
+		}
+	}
+
+	reply, err = push.PushToSessions(ctx, &access.PushToSessionsReq{
+		Targets: []*access.Target{{Uid: 2, SessionId: sessions[2]}, {Uid: 2, SessionId: sessions[1]}},
+		Msg:     pushMsg("session"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Deliveries[0].Status != access.Status_OK || reply.Deliveries[1].Status != access.Status_OFFLINE {
+		t.Fatalf("unexpected deliveries %v", reply.Deliveries)
+	}
+	receive(t, b, external.Cmd_PUSH_MSG)
+	expectNothing(t, a)
+
+	//房间推送排除发送者
+	if _, err = push.JoinRoom(ctx, &access.RoomReq{RoomId: "room", Targets: []*access.Target{
+		{Uid: 1, SessionId: sessions[1]}, {Uid: 2, SessionId: sessions[2]},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	count, err := push.PushToRoom(ctx, &access.PushToRoomReq{RoomId: "room", Msg: pushMsg("room"), ExcludeUids: []uint64{1}})
+	if err != nil || count.Delivered != 1 || count.Failed != 0 {
+		t.Fatalf("unexpected room count %v %v", count, err)
+	}
+	receive(t, b, external.Cmd_PUSH_MSG)
+	expectNothing(t, a)
+
+	if count, err = push.Broadcast(ctx, &access.BroadcastReq{Msg: pushMsg("all")}); err != nil || count.Delivered != 2 {
+		t.Fatalf("unexpected broadcast count %v %v", count, err)
+	}
+	receive(t, a, external.Cmd_PUSH_MSG)
+	receive(t, b, external.Cmd_PUSH_MSG)
+
+	//只允许推送命令
+	if _, err = push.PushToUser(ctx, &access.PushToUserReq{Uids: []uint64{1}, Msg: &access.PushMsg{Cmd: uint32(external.Cmd_LOGIN)}}); err == nil {
+		t.Fatal("client command should not be pushed")
+	}
+
+	reply, err = push.KickUser(ctx, &access.KickUserReq{Uid: 1, Reason: "login elsewhere", Platform: int32(external.Platform_IOS)})
+	if err != nil || len(reply.Deliveries) != 1 || reply.Deliveries[0].Status != access.Status_OK {
+		t.Fatalf("unexpected kick reply %v %v", reply, err)
+	}
+	if msg := receive(t, a, external.Cmd_KICKOUT).(*external.Kickout); msg.Reason != "login elsewhere" || msg.Platform != external.Platform_IOS {
+		t.Fatalf("unexpected kickout %+v", msg)
+	}
+	//关闭的连接退出房间
+	if n := server.Manager().RoomCount("room"); n != 1 {
+		t.Fatalf("room should have 1 session, got %d", n)
+	}
+	if reply, err = push.KickUser(ctx, &access.KickUserReq{Uid: 1}); err != nil || reply.Deliveries[0].Status != access.Status_OFFLINE {
+		t.Fatalf("kicked user should be offline, got %v %v", reply, err)
+	}
+}
+
+//json格式的连接由接入节点重新编码
+func TestPushEnvelopeJSON(t *testing.T) {
+	push, err := newPushEnvelope(pushMsg("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := push.envelope(net_lib.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Format != net_lib.FormatJSON || !strings.Contains(string(env.Payload), `"content"`) {
+		t.Fatalf("unexpected json payload %s", env.Payload)
+	}
+	if again, _ := push.envelope(net_lib.FormatJSON); again != env {
+		t.Fatal("envelope should be encoded once per format")
+	}
+	if env, _ = push.envelope(net_lib.FormatProto); string(env.Payload) != string(pushMsg("hello").Payload) {
+		t.Fatal("proto payload should be passed through")
+	}
+}
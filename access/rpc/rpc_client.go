@@ -2,21 +2,22 @@ package rpc
 
 import (
 	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
 	"go.uber.org/zap"
 )
 
 type RPCClient struct {
-	Logic *LogicRPCCli
+	Logic *LogicRPCCli //没有配置Logic时为nil
 }
 
-func NewRPCClient() (c *RPCClient, err error) {
-	logic, err := NewLogicRPCCli()
-	if err != nil {
-		logger.Fatal("NewLogicRPCCli", zap.Error(err))
+func NewRPCClient(logicCfg *LogicCfg, discovery service_discovery.Discovery) (c *RPCClient, err error) {
+	c = &RPCClient{}
+	if logicCfg == nil {
 		return
 	}
-	c = &RPCClient{
-		Logic: logic,
+	if c.Logic, err = NewLogicRPCCli(*logicCfg, discovery); err != nil {
+		logger.Fatal("NewLogicRPCCli", zap.Error(err))
+		return
 	}
 	return
-}
\ No newline at end of file
+}
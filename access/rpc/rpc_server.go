@@ -1 +1,268 @@
 package rpc
+
+import (
+	"net"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/access"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+//接入节点对Logic提供的gRPC服务。监听通过net_lib.Listen创建，平滑升级时由新进程继承
+type RPCServer struct {
+	server   *grpc.Server
+	listener net.Listener
+}
+
+func NewRPCServer(manager *net_lib.Manager) *RPCServer {
+	server := grpc.NewServer()
+	access.RegisterPushServer(server, NewPushServer(manager))
+	return &RPCServer{server: server}
+}
+
+func (s *RPCServer) Serve(network, addr string) error {
+	l, err := net_lib.Listen(network, addr)
+	if err != nil {
+		logger.Error("rpc listen", zap.String("addr", addr), zap.Error(err))
+		return err
+	}
+	s.listener = l
+	go func() {
+		if err := s.server.Serve(l); err != nil {
+			logger.Debug("rpc serve", zap.String("addr", addr), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+//Serve之前返回nil
+func (s *RPCServer) Listener() net.Listener {
+	return s.listener
+}
+
+func (s *RPCServer) Stop() {
+	s.server.GracefulStop()
+}
+
+//Logic通过Push服务向本节点的连接推送消息，结果区分在线和离线
+type PushServer struct {
+	manager *net_lib.Manager
+}
+
+func NewPushServer(manager *net_lib.Manager) *PushServer {
+	return &PushServer{manager: manager}
+}
+
+func (s *PushServer) PushToUser(ctx context.Context, req *access.PushToUserReq) (*access.PushReply, error) {
+	push, err := newPushEnvelope(req.Msg)
+	if err != nil {
+		return nil, err
+	}
+	reply := &access.PushReply{}
+	for _, uid := range req.Uids {
+		sessions := s.manager.UserSessions(uid)
+		if len(sessions) == 0 {
+			reply.Deliveries = append(reply.Deliveries, &access.Delivery{Uid: uid, Status: access.Status_OFFLINE})
+			continue
+		}
+		for _, session := range sessions {
+			reply.Deliveries = append(reply.Deliveries, delivery(uid, session.Id(), session, push.deliver(session)))
+		}
+	}
+	return reply, nil
+}
+
+func (s *PushServer) PushToSessions(ctx context.Context, req *access.PushToSessionsReq) (*access.PushReply, error) {
+	push, err := newPushEnvelope(req.Msg)
+	if err != nil {
+		return nil, err
+	}
+	reply := &access.PushReply{Deliveries: make([]*access.Delivery, 0, len(req.Targets))}
+	for _, target := range req.Targets {
+		session := s.manager.GetSession(target.Uid, target.SessionId)
+		reply.Deliveries = append(reply.Deliveries, delivery(target.Uid, target.SessionId, session, push.deliver(session)))
+	}
+	return reply, nil
+}
+
+func (s *PushServer) PushToRoom(ctx context.Context, req *access.PushToRoomReq) (*access.CountReply, error) {
+	if req.RoomId == "" {
+		return nil, ecode.RequestErr
+	}
+	push, err := newPushEnvelope(req.Msg)
+	if err != nil {
+		return nil, err
+	}
+	exclude := make(map[uint64]bool, len(req.ExcludeUids))
+	for _, uid := range req.ExcludeUids {
+		exclude[uid] = true
+	}
+	reply := &access.CountReply{}
+	for _, session := range s.manager.RoomSessions(req.RoomId) {
+		if uid := session.GetUserId(); uid != 0 && exclude[uid] {
+			continue
+		}
+		count(reply, push.deliver(session))
+	}
+	return reply, nil
+}
+
+func (s *PushServer) Broadcast(ctx context.Context, req *access.BroadcastReq) (*access.CountReply, error) {
+	push, err := newPushEnvelope(req.Msg)
+	if err != nil {
+		return nil, err
+	}
+	reply := &access.CountReply{}
+	s.manager.Range(func(session *net_lib.Session) bool {
+		if session.GetUserId() != 0 {
+			count(reply, push.deliver(session))
+		}
+		return true
+	})
+	return reply, nil
+}
+
+//同步写出KICKOUT后关闭连接，避免踢下线的原因还在发送队列中就被丢弃
+func (s *PushServer) KickUser(ctx context.Context, req *access.KickUserReq) (*access.PushReply, error) {
+	payload, err := proto.Marshal(&external.Kickout{Reason: req.Reason, Platform: external.Platform(req.Platform)})
+	if err != nil {
+		return nil, err
+	}
+	push, err := newPushEnvelope(&access.PushMsg{Cmd: uint32(external.Cmd_KICKOUT), Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[uint64]bool, len(req.SessionIds))
+	for _, sessionId := range req.SessionIds {
+		targets[sessionId] = false
+	}
+	reply := &access.PushReply{}
+	for _, session := range s.manager.UserSessions(req.Uid) {
+		if len(targets) > 0 {
+			if _, ok := targets[session.Id()]; !ok {
+				continue
+			}
+			targets[session.Id()] = true
+		}
+		status := access.Status_OK
+		if env, err := push.envelope(session.Format()); err != nil || session.SendAndClose(env, net_lib.CloseKicked) != nil {
+			status = access.Status_FAILED
+		}
+		reply.Deliveries = append(reply.Deliveries, delivery(req.Uid, session.Id(), session, status))
+	}
+	for sessionId, found := range targets {
+		if !found {
+			reply.Deliveries = append(reply.Deliveries, delivery(req.Uid, sessionId, nil, access.Status_OFFLINE))
+		}
+	}
+	if len(reply.Deliveries) == 0 {
+		reply.Deliveries = append(reply.Deliveries, delivery(req.Uid, 0, nil, access.Status_OFFLINE))
+	}
+	logger.Info("kick user", zap.Uint64("uid", req.Uid), zap.String("reason", req.Reason),
+		zap.Int("sessions", len(reply.Deliveries)))
+	return reply, nil
+}
+
+func (s *PushServer) JoinRoom(ctx context.Context, req *access.RoomReq) (*access.PushReply, error) {
+	return s.room(req, s.manager.JoinRoom)
+}
+
+func (s *PushServer) LeaveRoom(ctx context.Context, req *access.RoomReq) (*access.PushReply, error) {
+	return s.room(req, s.manager.LeaveRoom)
+}
+
+func (s *PushServer) room(req *access.RoomReq, f func(session *net_lib.Session, roomId string)) (*access.PushReply, error) {
+	if req.RoomId == "" {
+		return nil, ecode.RequestErr
+	}
+	reply := &access.PushReply{Deliveries: make([]*access.Delivery, 0, len(req.Targets))}
+	for _, target := range req.Targets {
+		session := s.manager.GetSession(target.Uid, target.SessionId)
+		status := access.Status_OFFLINE
+		if session != nil && !session.IsClosed() {
+			f(session, req.RoomId)
+			status = access.Status_OK
+		}
+		reply.Deliveries = append(reply.Deliveries, delivery(target.Uid, target.SessionId, session, status))
+	}
+	return reply, nil
+}
+
+//连接已关闭的不计入
+func count(reply *access.CountReply, status access.Status) {
+	switch status {
+	case access.Status_OK:
+		reply.Delivered++
+	case access.Status_FAILED:
+		reply.Failed++
+	}
+}
+
+func delivery(uid, sessionId uint64, session *net_lib.Session, status access.Status) *access.Delivery {
+	d := &access.Delivery{Uid: uid, SessionId: sessionId, Status: status}
+	if session != nil {
+		d.ConnType = int32(session.GetConnType())
+	}
+	return d
+}
+
+//同一条推送发给多个连接时，每种格式只编码一次
+type pushEnvelope struct {
+	msg  *access.PushMsg
+	envs [net_lib.FormatJSON + 1]*net_lib.Envelope
+}
+
+//只允许推送external命令表中的推送命令
+func newPushEnvelope(msg *access.PushMsg) (*pushEnvelope, error) {
+	if msg == nil {
+		return nil, ecode.RequestErr
+	}
+	if command := external.Lookup(msg.Cmd); command == nil || !command.Push {
+		return nil, ecode.RequestErr
+	}
+	return &pushEnvelope{msg: msg}, nil
+}
+
+//protobuf格式直接使用Logic编码好的消息体
+func (push *pushEnvelope) envelope(format net_lib.WireFormat) (*net_lib.Envelope, error) {
+	if env := push.envs[format]; env != nil {
+		return env, nil
+	}
+	env := &net_lib.Envelope{Cmd: push.msg.Cmd, Payload: push.msg.Payload}
+	if format == net_lib.FormatJSON {
+		msg := external.Lookup(push.msg.Cmd).Resp()
+		if err := proto.Unmarshal(push.msg.Payload, msg); err != nil {
+			return nil, err
+		}
+		var err error
+		if env, err = net_lib.NewFormatEnvelope(format, push.msg.Cmd, msg); err != nil {
+			return nil, err
+		}
+	}
+	push.envs[format] = env
+	return env, nil
+}
+
+func (push *pushEnvelope) deliver(session *net_lib.Session) access.Status {
+	if session == nil || session.IsClosed() {
+		return access.Status_OFFLINE
+	}
+	if !session.Handshaked() {
+		return access.Status_FAILED
+	}
+	env, err := push.envelope(session.Format())
+	if err != nil {
+		logger.Error("push envelope", zap.Uint32("cmd", push.msg.Cmd), zap.Error(err))
+		return access.Status_FAILED
+	}
+	if err = session.Send(env); err != nil {
+		return access.Status_FAILED
+	}
+	return access.Status_OK
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/access/rpc"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/logic"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"github.com/imkuqin-zw/ZWChat/lib/route"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+//回调中每次写路由表或上报下线的超时
+const hookTimeout = 3 * time.Second
+
+//接入层统一在这里处理连接的上下线事件，绑定和解绑用户时更新路由表。
+//没有经过LOGOUT的下线由这里上报给Logic
+type hooks struct {
+	net_lib.NopHooks
+	routes  route.Store      //为nil时不写路由表
+	logic   *rpc.LogicRPCCli //为nil时不上报下线
+	pool    *workerPool      //routes和logic都为nil时为nil
+	nodeId  string
+	mutex   sync.Mutex
+	pending map[hookKey]*hookState
+}
+
+type hookKey struct {
+	uid       uint64
+	sessionId uint64
+}
+
+//连接上还没有执行的路由变化和下线上报，只保留最新的状态
+type hookState struct {
+	route   *route.Route //为nil时不写路由表
+	online  bool         //为true时写入route，否则删除
+	offline *logic.Meta  //不为nil时上报Logic
+}
+
+func (h *hooks) OnHandshake(session *net_lib.Session) {
+	logger.Debug("session handshake", zap.Uint64("session", session.Id()),
+		zap.Int8("connType", session.GetConnType()), zap.String("ip", session.RemoteIp))
+}
+
+func (h *hooks) OnBind(session *net_lib.Session, userId uint64) {
+	logger.Info("user online", zap.Uint64("uid", userId), zap.Uint64("session", session.Id()),
+		zap.Int8("connType", session.GetConnType()))
+	if h.routes == nil {
+		return
+	}
+	r := h.route(session, userId)
+	r.LoginTime = nowMillis()
+	h.update(session, userId, func(state *hookState) {
+		state.route, state.online = r, true
+	})
+}
+
+func (h *hooks) OnUnbind(session *net_lib.Session, userId uint64) {
+	logger.Info("user offline", zap.Uint64("uid", userId), zap.Uint64("session", session.Id()),
+		zap.Int8("connType", session.GetConnType()))
+	var meta *logic.Meta
+	//连接断开、超时、被顶替或被踢时通知Logic
+	if logout, _ := session.Attr(attrLogout).(bool); h.logic != nil && !logout {
+		meta = sessionMeta(session, h.nodeId, userId)
+	}
+	if h.routes == nil && meta == nil {
+		return
+	}
+	r := h.route(session, userId)
+	h.update(session, userId, func(state *hookState) {
+		if h.routes != nil {
+			state.route, state.online = r, false
+		}
+		if meta != nil {
+			state.offline = meta
+		}
+	})
+}
+
+//合并到连接待执行的状态中，没有待执行的任务时交给pool执行，etcd或Logic慢时不占用回调协程。
+//同一个用户的操作按顺序执行，队列满时等待，状态变化不能丢失
+func (h *hooks) update(session *net_lib.Session, userId uint64, f func(state *hookState)) {
+	key := hookKey{uid: userId, sessionId: session.Id()}
+	h.mutex.Lock()
+	state, ok := h.pending[key]
+	if !ok {
+		if h.pending == nil {
+			h.pending = make(map[hookKey]*hookState)
+		}
+		state = &hookState{}
+		h.pending[key] = state
+	}
+	f(state)
+	h.mutex.Unlock()
+	if !ok {
+		h.pool.post(userId, func() { h.flush(key) })
+	}
+}
+
+//执行时取出最新的状态，执行期间的变化由下一个任务处理。失败只记录日志
+func (h *hooks) flush(key hookKey) {
+	h.mutex.Lock()
+	state := h.pending[key]
+	delete(h.pending, key)
+	h.mutex.Unlock()
+	if state.route != nil {
+		r := state.route
+		if state.online {
+			h.exec("route put", key, func(ctx context.Context) error { return h.routes.Put(ctx, r) })
+		} else {
+			h.exec("route delete", key, func(ctx context.Context) error { return h.routes.Delete(ctx, r) })
+		}
+	}
+	if state.offline != nil {
+		h.exec("logic offline", key, func(ctx context.Context) error { return h.logic.Offline(ctx, state.offline) })
+	}
+}
+
+func (h *hooks) exec(op string, key hookKey, f func(ctx context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	if err := f(ctx); err != nil {
+		logger.Error(op, zap.Uint64("uid", key.uid), zap.Uint64("session", key.sessionId), zap.Error(err))
+	}
+}
+
+func (h *hooks) route(session *net_lib.Session, userId uint64) *route.Route {
+	r := &route.Route{Uid: userId, NodeId: h.nodeId, SessionId: session.Id(), ConnType: session.GetConnType()}
+	if device, ok := session.Attr(attrDevice).(*external.Handshake); ok {
+		r.Platform = int32(device.Platform)
+	}
+	return r
+}
+
+func (h *hooks) OnClose(session *net_lib.Session, reason net_lib.CloseReason, err error) {
+	logger.Debug("session close", zap.Uint64("session", session.Id()),
+		zap.String("reason", reason.String()), zap.Error(err))
+}
+
+func (h *hooks) OnError(session *net_lib.Session, err error) {
+	logger.Error("session error", zap.Uint64("session", session.Id()), zap.Error(err))
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"sync"
+)
+
+const (
+	upstreamWorkers = 256
+	upstreamQueue   = 64
+	hookWorkers     = 8
+	hookQueue       = 1024
+)
+
+//按key分到固定的协程执行，同一个key的任务按投递顺序执行，每个协程的队列有长度限制。
+//epoll模式下需要等待Logic的请求，以及回调中写路由表、上报下线等需要等待下游的操作在这里执行
+type workerPool struct {
+	queues    []chan func()
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+func newWorkerPool(workers, queue int) *workerPool {
+	pool := &workerPool{queues: make([]chan func(), workers), closeChan: make(chan struct{})}
+	for i := range pool.queues {
+		pool.queues[i] = make(chan func(), queue)
+		go pool.work(pool.queues[i])
+	}
+	return pool
+}
+
+func (pool *workerPool) work(queue chan func()) {
+	for {
+		select {
+		case f := <-queue:
+			f()
+		case <-pool.closeChan:
+			return
+		}
+	}
+}
+
+func (pool *workerPool) queue(key uint64) chan func() {
+	return pool.queues[key%uint64(len(pool.queues))]
+}
+
+//队列满时等待，压力传回调用方
+func (pool *workerPool) post(key uint64, f func()) {
+	select {
+	case pool.queue(key) <- f:
+	case <-pool.closeChan:
+	}
+}
+
+//服务停止后调用，未执行的任务直接丢弃
+func (pool *workerPool) close() {
+	pool.closeOnce.Do(func() {
+		close(pool.closeChan)
+	})
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"github.com/imkuqin-zw/ZWChat/lib/route"
+	"golang.org/x/net/context"
+)
+
+func newSessions(t *testing.T, n int) []*net_lib.Session {
+	manager := net_lib.NewManager()
+	sessions := make([]*net_lib.Session, n)
+	for i := range sessions {
+		c1, c2 := net.Pipe()
+		t.Cleanup(func() { c2.Close() })
+		sessions[i] = manager.NewSession(c1, net_lib.ProtoTcp, 0, net_lib.SessionCfg{})
+	}
+	t.Cleanup(manager.Dispose)
+	return sessions
+}
+
+func recv(t *testing.T, ch chan int, want int) {
+	t.Helper()
+	select {
+	case v := <-ch:
+		if v != want {
+			t.Fatalf("want %d, got %d", want, v)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("%d not executed", want)
+	}
+}
+
+//同一个key的任务按顺序执行，一个key等待时其他协程上的key不受影响
+func TestWorkerPool(t *testing.T) {
+	pool := newWorkerPool(2, 4)
+	defer pool.close()
+	release := make(chan struct{})
+	done := make(chan int, 8)
+	pool.post(0, func() {
+		<-release
+		done <- 1
+	})
+	pool.post(0, func() { done <- 2 })
+	pool.post(1, func() { done <- 3 })
+	recv(t, done, 3)
+	close(release)
+	recv(t, done, 1)
+	recv(t, done, 2)
+}
+
+//队列满时post等待到关闭
+func TestWorkerPoolFull(t *testing.T) {
+	pool := newWorkerPool(1, 1)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	pool.post(0, func() {
+		close(started)
+		<-release
+	})
+	<-started
+	pool.post(0, func() {})
+	posted := make(chan struct{})
+	go func() {
+		pool.post(0, func() {})
+		close(posted)
+	}()
+	select {
+	case <-posted:
+		t.Fatal("want post blocked on full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+	pool.close()
+	select {
+	case <-posted:
+	case <-time.After(time.Second):
+		t.Fatal("post blocked after close")
+	}
+}
+
+//Put在release关闭前阻塞，开始时通知putting
+type slowStore struct {
+	route.Store
+	release chan struct{}
+	putting chan struct{}
+	mutex   sync.Mutex
+	ops     []string
+}
+
+func newSlowStore() *slowStore {
+	return &slowStore{release: make(chan struct{}), putting: make(chan struct{}, 16)}
+}
+
+func (s *slowStore) Put(ctx context.Context, r *route.Route) error {
+	s.putting <- struct{}{}
+	<-s.release
+	return s.record("put")
+}
+
+func (s *slowStore) Delete(ctx context.Context, r *route.Route) error {
+	return s.record("delete")
+}
+
+func (s *slowStore) record(op string) error {
+	s.mutex.Lock()
+	s.ops = append(s.ops, op)
+	s.mutex.Unlock()
+	return nil
+}
+
+func (s *slowStore) recorded() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.ops...)
+}
+
+func (s *slowStore) wait(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(s.recorded()) < n && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return s.recorded()
+}
+
+//写路由表慢时回调不等待，同一个用户的写入按顺序执行
+func TestHooksRouteAsync(t *testing.T) {
+	session := newSessions(t, 1)[0]
+	store := newSlowStore()
+	h := &hooks{routes: store, nodeId: "access-1", pool: newWorkerPool(hookWorkers, hookQueue)}
+	defer h.pool.close()
+	returned := make(chan struct{})
+	go func() {
+		h.OnBind(session, 7)
+		<-store.putting
+		h.OnUnbind(session, 7)
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("hook blocked on route store")
+	}
+	close(store.release)
+	if ops := store.wait(t, 2); len(ops) != 2 || ops[0] != "put" || ops[1] != "delete" {
+		t.Fatalf("unexpected ops %v", ops)
+	}
+}
+
+//队列满时回调等待而不是丢弃，还没有执行的变化合并为连接最新的状态
+func TestHooksQueueFull(t *testing.T) {
+	sessions := newSessions(t, 3)
+	store := newSlowStore()
+	h := &hooks{routes: store, nodeId: "access-1", pool: newWorkerPool(1, 1)}
+	defer h.pool.close()
+	h.OnBind(sessions[0], 7)
+	<-store.putting
+	//sessions[1]的写入在队列中，解绑合并到同一个任务
+	h.OnBind(sessions[1], 7)
+	h.OnUnbind(sessions[1], 7)
+	posted := make(chan struct{})
+	go func() {
+		h.OnBind(sessions[2], 7)
+		close(posted)
+	}()
+	select {
+	case <-posted:
+		t.Fatal("want hook blocked on full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(store.release)
+	select {
+	case <-posted:
+	case <-time.After(time.Second):
+		t.Fatal("hook still blocked")
+	}
+	if ops := store.wait(t, 3); len(ops) != 3 || ops[0] != "put" || ops[1] != "delete" || ops[2] != "put" {
+		t.Fatalf("unexpected ops %v", ops)
+	}
+}
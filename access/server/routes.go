@@ -0,0 +1,182 @@
+package server
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/imkuqin-zw/ZWChat/access/router"
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/logic"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+const (
+	attrDevice = "device" //握手时上报的设备信息，类型为*external.Handshake
+	attrLogout = "logout" //客户端发送了LOGOUT，解绑时不再上报下线，类型为bool
+)
+
+//中间件依次为: panic恢复、统计、日志、登录检查、限流
+func (s *Server) initRouter() {
+	r := router.New()
+	r.Use(router.Recovery(), s.Metrics.Middleware(), router.Logging(), router.AuthRequired(),
+		s.rateLimiter.Middleware())
+	r.HandleCmd(external.Cmd_HANDSHAKE, handshake)
+	r.HandleCmd(external.Cmd_HEARTBEAT, heartbeat)
+	if s.rpcClient == nil || s.rpcClient.Logic == nil {
+		//没有配置Logic时消息发送只回复确认，供压测和客户端调试使用
+		r.HandleCmd(external.Cmd_SEND_P2P_MSG, sendMsgAck)
+		r.HandleCmd(external.Cmd_SEND_GROUP_MSG, sendMsgAck)
+		s.Router = r
+		return
+	}
+	r.HandleCmd(external.Cmd_LOGIN, s.login)
+	r.HandleCmd(external.Cmd_LOGOUT, s.logout, router.Auth())
+	for _, cmd := range []external.Cmd{
+		external.Cmd_SEND_P2P_MSG,
+		external.Cmd_SEND_GROUP_MSG,
+		external.Cmd_MSG_ACK,
+		external.Cmd_SYNC,
+		external.Cmd_PRESENCE_SUB,
+	} {
+		r.HandleCmd(cmd, s.forward, router.Auth())
+	}
+	s.Router = r
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+//心跳间隔为读超时的一半，没有读超时时由客户端决定
+func handshake(ctx *router.Context) (proto.Message, error) {
+	req := ctx.Req.(*external.Handshake)
+	if req.Version != external.Version_V1 {
+		return nil, ecode.VersionNotSupported
+	}
+	ctx.Session.SetAttr(attrDevice, req)
+	return &external.HandshakeAck{
+		Version:    external.Version_V1,
+		ServerTime: nowMillis(),
+		Heartbeat:  uint32(ctx.Session.Config().ReadDeadLine / 2),
+	}, nil
+}
+
+func heartbeat(ctx *router.Context) (proto.Message, error) {
+	return &external.HeartbeatAck{ServerTime: nowMillis()}, nil
+}
+
+func sendMsgAck(ctx *router.Context) (proto.Message, error) {
+	var clientMsgId string
+	switch req := ctx.Req.(type) {
+	case *external.SendP2PMsg:
+		clientMsgId = req.ClientMsgId
+	case *external.SendGroupMsg:
+		clientMsgId = req.ClientMsgId
+	}
+	return &external.SendMsgAck{ClientMsgId: clientMsgId, SendTime: nowMillis()}, nil
+}
+
+//转发给Logic时携带的连接信息
+func (s *Server) meta(ctx *router.Context) *logic.Meta {
+	return sessionMeta(ctx.Session, s.NodeId, ctx.UserId)
+}
+
+func sessionMeta(session *net_lib.Session, nodeId string, userId uint64) *logic.Meta {
+	meta := &logic.Meta{
+		Uid:       userId,
+		SessionId: session.Id(),
+		AccessId:  nodeId,
+		RemoteIp:  session.RemoteIp,
+		ConnType:  int32(session.GetConnType()),
+	}
+	if device, ok := session.Attr(attrDevice).(*external.Handshake); ok {
+		meta.DeviceId = device.DeviceId
+		meta.Platform = int32(device.Platform)
+		meta.AppVersion = device.AppVersion
+	}
+	return meta
+}
+
+//请求统一按protobuf转发给Logic，响应解析后由router按客户端的格式回复。
+//Logic处理慢时在这里阻塞，不再读取该连接的请求
+func (s *Server) forward(ctx *router.Context) (proto.Message, error) {
+	payload, err := proto.Marshal(ctx.Req)
+	if err != nil {
+		return nil, err
+	}
+	return s.call(s.meta(ctx), ctx.Env.Cmd, payload)
+}
+
+func (s *Server) call(meta *logic.Meta, cmd uint32, payload []byte) (proto.Message, error) {
+	resp, err := s.rpcClient.Logic.Call(context.Background(), meta, cmd, payload)
+	if err != nil {
+		return nil, err
+	}
+	command := external.Lookup(resp.Cmd)
+	if command == nil || command.Resp == nil {
+		return nil, nil
+	}
+	msg := command.Resp()
+	if err = proto.Unmarshal(resp.Payload, msg); err != nil {
+		logger.Error("logic resp unmarshal", zap.Uint32("cmd", resp.Cmd), zap.Error(err))
+		return nil, ecode.ServerErr
+	}
+	if e, ok := msg.(*external.Error); ok {
+		return nil, ecode.To(e.ErrCoed)
+	}
+	return msg, nil
+}
+
+//由Logic校验令牌，成功后绑定用户并通知Logic
+func (s *Server) login(ctx *router.Context) (proto.Message, error) {
+	req := ctx.Req.(*external.Login)
+	if req.Token == "" {
+		return nil, ecode.NoToken
+	}
+	meta := s.meta(ctx)
+	verified, err := s.rpcClient.Logic.Verify(context.Background(), meta, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if req.Uid != 0 && req.Uid != verified.Uid {
+		return nil, ecode.TokenInvalid
+	}
+	payload, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	meta.Uid = verified.Uid
+	resp, err := s.call(meta, ctx.Env.Cmd, payload)
+	if err != nil {
+		return nil, err
+	}
+	ack, ok := resp.(*external.LoginAck)
+	if !ok {
+		return nil, ecode.ServerErr
+	}
+	ack.Uid = verified.Uid
+	ctx.Session.SetAttr(attrLogout, false)
+	//校验和登录期间连接已断开，不会再有解绑回调，由这里通知Logic
+	if !ctx.Session.Bind(verified.Uid) {
+		if err = s.rpcClient.Logic.Offline(context.Background(), meta); err != nil {
+			logger.Error("logic offline", zap.Uint64("uid", verified.Uid), zap.Uint64("session", ctx.Session.Id()), zap.Error(err))
+		}
+		return nil, net_lib.SessionClosedErr
+	}
+	return ack, nil
+}
+
+//Logic已经通过LOGOUT知道下线，解绑时不再上报
+func (s *Server) logout(ctx *router.Context) (proto.Message, error) {
+	resp, err := s.forward(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Session.SetAttr(attrLogout, true)
+	ctx.Session.Unbind()
+	return resp, nil
+}
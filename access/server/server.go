@@ -2,68 +2,100 @@ package server
 
 import (
 	"github.com/imkuqin-zw/ZWChat/access/client"
+	"github.com/imkuqin-zw/ZWChat/access/router"
 	"github.com/imkuqin-zw/ZWChat/access/rpc"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
 	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"github.com/imkuqin-zw/ZWChat/lib/route"
 	"go.uber.org/zap"
-	"github.com/imkuqin-zw/ZWChat/common/logger"
-	"fmt"
+	"io"
 )
 
 type Server struct {
-	Server *net_lib.Server
+	Server      *net_lib.Server
+	Reactor     *net_lib.ReactorCfg //不为空时使用epoll模式
+	Router      *router.Router      //Loop时根据配置创建
+	Metrics     *router.Metrics
+	NodeId      string      //转发到Logic时携带，Logic据此找到连接所在的节点
+	Routes      route.Store //用户路由表，为空时不写入
+	rpcClient   *rpc.RPCClient
+	rateLimiter *router.RateLimiter
+	upstream    *workerPool //epoll模式下配置了Logic时不为nil
 }
 
 func New() (s *Server) {
-	s = &Server{}
+	s = &Server{Metrics: router.NewMetrics(), rateLimiter: router.NewRateLimiter(router.RateLimitCfg{})}
 	return
 }
 
+//单个连接的请求速率限制，rate为0时不限制，可以在运行时修改
+func (s *Server) SetRateLimit(cfg router.RateLimitCfg) {
+	s.rateLimiter.Set(cfg)
+}
+
 func (s *Server) Loop(rpcClient *rpc.RPCClient) {
+	s.rpcClient = rpcClient
+	h := &hooks{routes: s.Routes, nodeId: s.NodeId}
+	if rpcClient != nil {
+		h.logic = rpcClient.Logic
+	}
+	if h.routes != nil || h.logic != nil {
+		h.pool = newWorkerPool(hookWorkers, hookQueue)
+		defer h.pool.close()
+	}
+	s.Server.SetHooks(h)
+	s.initRouter()
+	if s.Reactor != nil {
+		if rpcClient != nil && rpcClient.Logic != nil {
+			s.upstream = newWorkerPool(upstreamWorkers, upstreamQueue)
+			defer s.upstream.close()
+		}
+		if err := s.Server.ServeReactor(*s.Reactor, s.handle); err != nil {
+			logger.Error("serve reactor", zap.Error(err))
+		}
+		return
+	}
 	for {
 		session, err := s.Server.Accept()
+		if err == io.EOF {
+			return
+		}
 		if err != nil {
 			logger.Error("session accept", zap.Error(err))
 			continue
 		}
-		c := client.New(session, rpcClient)
-		logger.Debug("session accept", zap.String("ip", session.RemoteIp),
-			zap.String("port", session.RemotePort))
+		c := client.New(session, rpcClient, s.Router)
 		go s.sessionLoop(c)
 	}
 }
 
 func (s *Server) sessionLoop(client *client.Client) {
+	//出错时连接已关闭，由hooks统一处理
 	if err := client.Session.InitCodec(); err != nil {
-		logger.Error("Server SessionLoop", zap.Error(err))
 		return
 	}
 	for {
-		if client.Session.IsWaiting() {
-			break
-		}
-		reqData, err := client.Session.Receive()
+		env, err := client.Session.Receive()
 		if err != nil {
-			logger.Error("session receive", zap.Error(err))
 			return
 		}
-		fmt.Println(string(reqData))
-		client.Session.Send(reqData)
-		//if reqData != nil {
-		//	baseCMD := &protobuf.Cmd{}
-		//	if err = proto.Unmarshal(reqData, baseCMD); err != nil {
-		//		if err = client.Session.Send(&protobuf.Error{
-		//			Cmd:     baseCMD.Cmd,
-		//			ErrCoed: ecode.ServerErr.Uint32(),
-		//			ErrMsg:  ecode.ServerErr.String(),
-		//		}); err != nil {
-		//			glog.Error(err)
-		//		}
-		//		continue
-		//	}
-		//	if err = client.Parmse(baseCMD.Cmd, reqData); err != nil {
-		//		glog.Error(err)
-		//		continue
-		//	}
-		//}
+		if err = client.Parse(env); err != nil {
+			logger.Debug("session reply", zap.Uint64("session", client.Session.Id()), zap.Error(err))
+		}
+	}
+}
+
+//在reactor的工作协程中执行。配置了Logic时请求可能等待Logic响应，交给upstream执行，不阻塞其他连接
+func (s *Server) handle(session *net_lib.Session, env *net_lib.Envelope) {
+	if s.upstream != nil {
+		s.upstream.post(session.Id(), func() { s.dispatch(session, env) })
+		return
+	}
+	s.dispatch(session, env)
+}
+
+func (s *Server) dispatch(session *net_lib.Session, env *net_lib.Envelope) {
+	if err := s.Router.Dispatch(session, env); err != nil {
+		logger.Debug("session reply", zap.Uint64("session", session.Id()), zap.Error(err))
 	}
 }
@@ -0,0 +1,66 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/access/admin"
+	"github.com/imkuqin-zw/ZWChat/access/config"
+	"github.com/imkuqin-zw/ZWChat/access/rpc"
+	"github.com/imkuqin-zw/ZWChat/access/server"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"go.uber.org/zap"
+)
+
+//收到SIGUSR2时启动新的二进制接管监听，成功后向所有连接发送GOAWAY，
+//等待客户端断开或超时后关闭服务，Loop返回后进程退出
+func handleUpgrade(accessServer *server.Server, adminServer *admin.Admin, rpcServer *rpc.RPCServer, reporter *loadReporter) {
+	readyTimeout, drainTimeout := 30*time.Second, 60*time.Second
+	if cfg := config.Conf.Upgrade; cfg != nil {
+		if cfg.ReadyTimeout > 0 {
+			readyTimeout = cfg.ReadyTimeout
+		}
+		if cfg.DrainTimeout > 0 {
+			drainTimeout = cfg.DrainTimeout
+		}
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGUSR2)
+	for range sig {
+		var extra []net.Listener
+		if adminServer != nil && adminServer.Listener() != nil {
+			extra = append(extra, adminServer.Listener())
+		}
+		if rpcServer != nil && rpcServer.Listener() != nil {
+			extra = append(extra, rpcServer.Listener())
+		}
+		process, err := accessServer.Server.Upgrade(readyTimeout, extra...)
+		if err != nil {
+			logger.Error("upgrade failed, keep serving", zap.Error(err))
+			continue
+		}
+		signal.Stop(sig)
+		logger.Info("upgrade success, draining", zap.Int("pid", process.Pid),
+			zap.Int("sessions", accessServer.Server.Manager().Count()))
+		if adminServer != nil && adminServer.Listener() != nil {
+			adminServer.Listener().Close()
+		}
+		//新进程使用同一个地址注册，负载改由新进程上报
+		if reporter != nil {
+			reporter.Stop()
+		}
+		//只关闭监听，已有的Logic连接继续向排空中的连接推送
+		if rpcServer != nil && rpcServer.Listener() != nil {
+			rpcServer.Listener().Close()
+		}
+		accessServer.Server.Drain(net_lib.GoAwayRestart, drainTimeout)
+		return
+	}
+}
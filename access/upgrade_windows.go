@@ -0,0 +1,11 @@
+package main
+
+import (
+	"github.com/imkuqin-zw/ZWChat/access/admin"
+	"github.com/imkuqin-zw/ZWChat/access/rpc"
+	"github.com/imkuqin-zw/ZWChat/access/server"
+)
+
+//windows不支持传递监听的文件描述符
+func handleUpgrade(accessServer *server.Server, adminServer *admin.Admin, rpcServer *rpc.RPCServer, reporter *loadReporter) {
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/imkuqin-zw/ZWChat/access/server"
+	"github.com/imkuqin-zw/ZWChat/lib/net_client"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+)
+
+//在本进程内启动接入服务，客户端改为连接它监听的地址
+func startInproc(opts *net_client.Options) (func(), error) {
+	var err error
+	accessServer := server.New()
+	cfg := &net_lib.SessionCfg{
+		ReadDeadLine:  100,
+		WriteDeadLine: 100,
+		MaxMsgSize:    uint32(*maxMsgSize),
+		Compress:      []string{"gzip", "deflate"},
+	}
+	accessServer.Server, err = net_lib.Serve("tcp", "127.0.0.1:0", cfg, 256)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.ShareKey) != 0 {
+		authKeyId, shareKey := opts.AuthKeyId, opts.ShareKey
+		accessServer.Server.Manager().SetShareKeyFunc(func(id []byte) []byte {
+			if bytes.Equal(id, authKeyId) {
+				return shareKey
+			}
+			return nil
+		})
+	}
+	if *reactor {
+		accessServer.Reactor = &net_lib.ReactorCfg{}
+	}
+	opts.Addr = accessServer.Server.Listener().Addr().String()
+	go accessServer.Loop(nil)
+	return accessServer.Server.Stop, nil
+}
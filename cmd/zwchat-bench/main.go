@@ -0,0 +1,345 @@
+//zwchat-bench 接入服务的压测工具
+//
+//模拟N个客户端通过tcp、ws或http连接接入服务，按指定的速率建立连接、登录和发送消息，
+//统计连接延迟、登录延迟、发送确认延迟、端到端投递延迟的分位数、错误数和吞吐量。
+//消息内容的前8字节为发送时间，接收方收到PUSH_MSG时计算投递延迟，
+//所以投递延迟只有在接入服务连接了Logic并且客户端已登录时才有样本。
+//
+//	zwchat-bench -addr 127.0.0.1:11000 -proto tcp -clients 1000 -conn-rate 200 -msg-rate 5 -tokens tokens.txt
+//	zwchat-bench -inproc -proto ws -clients 100 -encrypt
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	mrand "math/rand"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/lib/net_client"
+	"golang.org/x/net/context"
+)
+
+var (
+	addr       = flag.String("addr", "127.0.0.1:11000", "access server address")
+	proto      = flag.String("proto", "tcp", "protocol: tcp, ws or http")
+	path       = flag.String("path", "/", "ws/http request path")
+	clients    = flag.Int("clients", 100, "number of simulated clients")
+	connRate   = flag.Float64("conn-rate", 100, "new connections per second")
+	tokens     = flag.String("tokens", "", "file of \"uid token\" lines, clients log in with them after the handshake")
+	msgRate    = flag.Float64("msg-rate", 1, "messages per second per client, 0 only connects")
+	payload    = flag.Int("payload", 128, "message payload size in bytes, at least 8 to carry the send time")
+	groupRatio = flag.Float64("group-ratio", 0, "fraction of group messages in [0, 1]")
+	encrypt    = flag.Bool("encrypt", false, "encrypt messages with -share-key")
+	shareKey   = flag.String("share-key", "", "hex encoded 32 bytes share key, random when empty")
+	duration   = flag.Duration("duration", 30*time.Second, "test duration after all clients are connected")
+	report     = flag.Duration("report", 5*time.Second, "progress report interval")
+	maxMsgSize = flag.Uint("max-msg-size", 1<<20, "max message size accepted by the clients")
+	inproc     = flag.Bool("inproc", false, "run against an in-process access server")
+	reactor    = flag.Bool("reactor", false, "the in-process access server uses the epoll reactor (linux only)")
+	legacy     = flag.Bool("legacy-frame", false, "tcp clients send the legacy length-prefixed frame")
+	compress   = flag.String("compress", "", "comma separated compression algorithms offered by the clients")
+)
+
+//发送时间放在消息内容的前8字节
+const sendTimeLen = 8
+
+type account struct {
+	uid   uint64
+	token string
+}
+
+func main() {
+	flag.Parse()
+	if err := checkFlags(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	opts, err := clientOptions()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var accounts []account
+	if *tokens != "" {
+		if accounts, err = loadTokens(*tokens, *clients); err != nil {
+			fmt.Fprintln(os.Stderr, "load tokens:", err)
+			os.Exit(1)
+		}
+	}
+	if *inproc {
+		stop, err := startInproc(&opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "start in-process server:", err)
+			os.Exit(1)
+		}
+		defer stop()
+	}
+	fmt.Printf("bench %s://%s clients=%d conn-rate=%.0f/s msg-rate=%.2f/s payload=%dB group=%.0f%% encrypt=%v login=%v\n",
+		opts.Network, opts.Addr, *clients, *connRate, *msgRate, *payload, *groupRatio*100, *encrypt, accounts != nil)
+
+	b := &bench{opts: opts, stop: make(chan struct{}), accounts: accounts}
+	start := time.Now()
+	go b.progress(start)
+	b.connectAll()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-time.After(*duration):
+	case <-sig:
+	}
+	close(b.stop)
+	b.wait.Wait()
+	b.stats.summary(os.Stdout, time.Since(start))
+}
+
+//速率换算出的间隔必须为正数，否则time.NewTicker会panic
+func checkFlags() error {
+	if *clients <= 0 {
+		return errors.New("-clients must be positive")
+	}
+	if *connRate <= 0 || time.Duration(float64(time.Second) / *connRate) <= 0 {
+		return errors.New("-conn-rate must be in (0, 1e9]")
+	}
+	if *msgRate < 0 || (*msgRate > 0 && time.Duration(float64(time.Second) / *msgRate) <= 0) {
+		return errors.New("-msg-rate must be in [0, 1e9]")
+	}
+	if *payload < sendTimeLen {
+		return fmt.Errorf("-payload must be at least %d bytes", sendTimeLen)
+	}
+	if *groupRatio < 0 || *groupRatio > 1 {
+		return errors.New("-group-ratio must be in [0, 1]")
+	}
+	if *report <= 0 {
+		return errors.New("-report must be positive")
+	}
+	return nil
+}
+
+//每行一个"uid token"，行数不能少于客户端数
+func loadTokens(path string, n int) ([]account, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	accounts := make([]account, 0, n)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() && len(accounts) < n {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+		uid, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uid %q", fields[0])
+		}
+		accounts = append(accounts, account{uid: uid, token: fields[1]})
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(accounts) < n {
+		return nil, fmt.Errorf("%d tokens for %d clients", len(accounts), n)
+	}
+	return accounts, nil
+}
+
+func clientOptions() (net_client.Options, error) {
+	opts := net_client.Options{
+		Network:      *proto,
+		Addr:         *addr,
+		Path:         *path,
+		MaxMsgSize:   uint32(*maxMsgSize),
+		WriteTimeout: 10 * time.Second,
+		RecvChanSize: 256,
+		MaxRetries:   1,
+		LegacyFrame:  *legacy,
+	}
+	if *compress != "" {
+		opts.Compress = strings.Split(*compress, ",")
+	}
+	switch opts.Network {
+	case "tcp", "ws", "http":
+	default:
+		return opts, fmt.Errorf("unsupported proto %q", opts.Network)
+	}
+	if *encrypt {
+		opts.AuthKeyId = make([]byte, 8)
+		rand.Read(opts.AuthKeyId)
+		if *shareKey == "" {
+			opts.ShareKey = make([]byte, 32)
+			rand.Read(opts.ShareKey)
+		} else {
+			key, err := hex.DecodeString(*shareKey)
+			if err != nil || len(key) != 32 {
+				return opts, fmt.Errorf("share-key must be 32 hex encoded bytes")
+			}
+			opts.ShareKey = key
+		}
+	}
+	return opts, nil
+}
+
+type bench struct {
+	opts     net_client.Options
+	stats    stats
+	accounts []account //为nil时不登录
+	stop     chan struct{}
+	wait     sync.WaitGroup
+}
+
+//按conn-rate逐个建立连接
+func (b *bench) connectAll() {
+	interval := time.Duration(float64(time.Second) / *connRate)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; i < *clients; i++ {
+		select {
+		case <-ticker.C:
+		case <-b.stop:
+			return
+		}
+		b.wait.Add(1)
+		go b.runClient(i)
+	}
+}
+
+//第i个客户端的uid，登录时为token文件中的uid
+func (b *bench) uid(i int) uint64 {
+	if b.accounts != nil {
+		return b.accounts[i].uid
+	}
+	return uint64(i + 1)
+}
+
+func (b *bench) runClient(i int) {
+	defer b.wait.Done()
+	start := time.Now()
+	c, err := net_client.Dial(b.opts)
+	if err != nil {
+		atomic.AddInt64(&b.stats.connectErrs, 1)
+		return
+	}
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	_, err = c.Handshake(ctx, &external.Handshake{Platform: external.Platform_PC,
+		DeviceId: strconv.FormatUint(b.uid(i), 10)})
+	cancel()
+	if err != nil {
+		atomic.AddInt64(&b.stats.connectErrs, 1)
+		return
+	}
+	b.stats.connLatency.add(time.Since(start))
+	if b.accounts != nil {
+		start = time.Now()
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		_, err = c.Login(ctx, b.accounts[i].uid, b.accounts[i].token)
+		cancel()
+		if err != nil {
+			atomic.AddInt64(&b.stats.loginErrs, 1)
+			return
+		}
+		b.stats.loginLatency.add(time.Since(start))
+	}
+	atomic.AddInt64(&b.stats.connects, 1)
+	atomic.AddInt64(&b.stats.connected, 1)
+	defer atomic.AddInt64(&b.stats.connected, -1)
+
+	go b.receive(c)
+	if *msgRate <= 0 {
+		<-b.stop
+		return
+	}
+	interval := time.Duration(float64(time.Second) / *msgRate)
+	//错开各客户端的发送时间
+	time.Sleep(time.Duration(mrand.Int63n(int64(interval) + 1)))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.send(c)
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+//客户端消息id中携带发送时间，用于计算发送确认的延迟；
+//内容的前8字节也是发送时间，用于计算接收方收到推送的投递延迟
+func (b *bench) send(c *net_client.Client) {
+	var err error
+	now := time.Now().UnixNano()
+	clientMsgId := strconv.FormatInt(now, 10)
+	content := make([]byte, *payload)
+	binary.BigEndian.PutUint64(content, uint64(now))
+	if mrand.Float64() < *groupRatio {
+		_, err = c.Send(uint32(external.Cmd_SEND_GROUP_MSG),
+			&external.SendGroupMsg{ClientMsgId: clientMsgId, GroupId: 1, Content: content})
+	} else {
+		_, err = c.Send(uint32(external.Cmd_SEND_P2P_MSG),
+			&external.SendP2PMsg{ClientMsgId: clientMsgId, To: b.uid(mrand.Intn(*clients)), Content: content})
+	}
+	if err != nil {
+		atomic.AddInt64(&b.stats.sendErrs, 1)
+		return
+	}
+	atomic.AddInt64(&b.stats.sent, 1)
+}
+
+//服务端回复的错误也计入decode_err
+func (b *bench) receive(c *net_client.Client) {
+	for p := range c.Receive() {
+		msg, err := net_client.Decode(p)
+		if err != nil {
+			atomic.AddInt64(&b.stats.decodeErrs, 1)
+			continue
+		}
+		atomic.AddInt64(&b.stats.received, 1)
+		atomic.AddInt64(&b.stats.receivedSize, int64(len(p.Payload)))
+		switch msg := msg.(type) {
+		case *external.SendMsgAck:
+			if sendTime, err := strconv.ParseInt(msg.ClientMsgId, 10, 64); err == nil {
+				b.stats.ackLatency.add(time.Duration(time.Now().UnixNano() - sendTime))
+			}
+		case *external.Msg:
+			atomic.AddInt64(&b.stats.delivered, 1)
+			if len(msg.Content) >= sendTimeLen {
+				sendTime := int64(binary.BigEndian.Uint64(msg.Content))
+				b.stats.deliveryLatency.add(time.Duration(time.Now().UnixNano() - sendTime))
+			}
+			c.AckMsg(msg.Seq)
+		}
+	}
+}
+
+func (b *bench) progress(start time.Time) {
+	ticker := time.NewTicker(*report)
+	defer ticker.Stop()
+	var lastSent, lastRecv int64
+	for {
+		select {
+		case <-ticker.C:
+			lastSent, lastRecv = b.stats.tick(os.Stdout, time.Since(start), *report, lastSent, lastRecv)
+		case <-b.stop:
+			return
+		}
+	}
+}
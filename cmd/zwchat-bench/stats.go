@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//延迟采样
+type latency struct {
+	sync.Mutex
+	samples []time.Duration
+}
+
+func (l *latency) add(d time.Duration) {
+	l.Lock()
+	l.samples = append(l.samples, d)
+	l.Unlock()
+}
+
+func (l *latency) report(w io.Writer, name string) {
+	l.Lock()
+	samples := make([]time.Duration, len(l.samples))
+	copy(samples, l.samples)
+	l.Unlock()
+	if len(samples) == 0 {
+		fmt.Fprintf(w, "%-10s no samples\n", name)
+		return
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	var total time.Duration
+	for _, d := range samples {
+		total += d
+	}
+	fmt.Fprintf(w, "%-10s n=%d avg=%v p50=%v p90=%v p99=%v p999=%v max=%v\n", name, len(samples),
+		total/time.Duration(len(samples)), percentile(samples, 0.5), percentile(samples, 0.9),
+		percentile(samples, 0.99), percentile(samples, 0.999), samples[len(samples)-1])
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
+
+type stats struct {
+	connected       int64 //当前在线的连接数
+	connects        int64 //成功建立的连接数
+	connectErrs     int64
+	loginErrs       int64
+	sent            int64
+	sendErrs        int64
+	received        int64
+	receivedSize    int64
+	delivered       int64 //收到的PUSH_MSG
+	decodeErrs      int64
+	connLatency     latency
+	loginLatency    latency
+	ackLatency      latency //发送到收到SendMsgAck，只说明接入服务收到了消息
+	deliveryLatency latency //发送到接收方收到PUSH_MSG
+}
+
+func (s *stats) tick(w io.Writer, elapsed, interval time.Duration, lastSent, lastRecv int64) (int64, int64) {
+	sent, recv := atomic.LoadInt64(&s.sent), atomic.LoadInt64(&s.received)
+	fmt.Fprintf(w, "[%6.1fs] conns=%d sent=%d(%.0f/s) recv=%d(%.0f/s) errs=%d\n",
+		elapsed.Seconds(), atomic.LoadInt64(&s.connected), sent,
+		float64(sent-lastSent)/interval.Seconds(), recv, float64(recv-lastRecv)/interval.Seconds(),
+		atomic.LoadInt64(&s.connectErrs)+atomic.LoadInt64(&s.loginErrs)+atomic.LoadInt64(&s.sendErrs)+
+			atomic.LoadInt64(&s.decodeErrs))
+	return sent, recv
+}
+
+func (s *stats) summary(w io.Writer, elapsed time.Duration) {
+	fmt.Fprintf(w, "\n===== summary (%v) =====\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "connections: ok=%d err=%d login_err=%d\n", atomic.LoadInt64(&s.connects),
+		atomic.LoadInt64(&s.connectErrs), atomic.LoadInt64(&s.loginErrs))
+	fmt.Fprintf(w, "messages:    sent=%d recv=%d delivered=%d send_err=%d decode_err=%d\n", atomic.LoadInt64(&s.sent),
+		atomic.LoadInt64(&s.received), atomic.LoadInt64(&s.delivered), atomic.LoadInt64(&s.sendErrs),
+		atomic.LoadInt64(&s.decodeErrs))
+	fmt.Fprintf(w, "throughput:  send=%.0f msg/s recv=%.0f msg/s recv=%.2f MB/s\n",
+		float64(atomic.LoadInt64(&s.sent))/elapsed.Seconds(),
+		float64(atomic.LoadInt64(&s.received))/elapsed.Seconds(),
+		float64(atomic.LoadInt64(&s.receivedSize))/elapsed.Seconds()/1024/1024)
+	s.connLatency.report(w, "connect")
+	s.loginLatency.report(w, "login")
+	s.ackLatency.report(w, "ack")
+	s.deliveryLatency.report(w, "delivery")
+}
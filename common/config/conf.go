@@ -5,8 +5,14 @@ import (
 )
 
 type Server struct {
-	Proto string `yaml:"proto"`
-	Addr  string `yaml:"addr"`
+	Proto          string   `yaml:"proto"`
+	Addr           string   `yaml:"addr"`
+	TrustedProxies []string `yaml:"trustedProxies"`
+}
+
+//管理接口，addr为空时不启动
+type Admin struct {
+	Addr string `yaml:"addr"`
 }
 
 type Path struct {
@@ -18,15 +24,26 @@ type Etcd struct {
 	Prefix      string        `yaml:"prefix"`
 }
 
+//backend和target的含义同ServiceDiscoveryServer
 type ServiceDiscoveryClient struct {
-	Target     string `yaml:"target"`
-	ServerName string `yaml:"serverName"`
+	Backend    string        `yaml:"backend"`
+	Target     string        `yaml:"target"`
+	ServerName string        `yaml:"serverName"`
+	Reload     time.Duration `yaml:"reload"`
 }
 
+//backend为etcd(默认)、static或memory，static的target为实例文件的路径
 type ServiceDiscoveryServer struct {
+	Backend    string        `yaml:"backend"`
 	Target     string        `yaml:"target"`
 	ServerName string        `yaml:"serverName"`
 	RpcAddr    string        `yaml:"rpcAddr"`
-	Interval   time.Duration `yaml:"interval"`
+	Interval   time.Duration `yaml:"interval"` //上报负载的间隔
 	TTL        time.Duration `yaml:"ttl"`
+	Version    string        `yaml:"version"`
+	Zone       string        `yaml:"zone"`
+	Weight     int           `yaml:"weight"`
+	Reload     time.Duration `yaml:"reload"` //static检查文件变化的间隔
+	//对客户端公开的地址，key为协议(tcp、ws、http)，dispatch据此返回给客户端
+	Endpoints map[string]string `yaml:"endpoints"`
 }
@@ -13,11 +13,30 @@ const (
 	NoAccessServer ecode = 92001
 
 	// access
-	NoToken         ecode = 93001
-	CalcTokenFailed ecode = 93002
+	NoToken             ecode = 93001
+	CalcTokenFailed     ecode = 93002
+	NotLogin            ecode = 93003
+	UnknownCmd          ecode = 93004
+	TooManyRequests     ecode = 93005
+	VersionNotSupported ecode = 93006
+	NoLogicServer       ecode = 93007
+	LogicBusy           ecode = 93008
+	LogicTimeout        ecode = 93009
 
 	// register
 	UserIsAlreadyExist ecode = 94001
+	InvalidUsername    ecode = 94002
+	InvalidPhone       ecode = 94003
+	InvalidEmail       ecode = 94004
+	PasswordTooWeak    ecode = 94005
+	UserNotFound       ecode = 94006
+	NoIdentifier       ecode = 94007
+
+	// auth
+	LoginFailed  ecode = 95001
+	TokenInvalid ecode = 95002
+	TokenExpired ecode = 95003
+	TokenRevoked ecode = 95004
 
 	// network
 	NoData ecode = 91001
@@ -47,11 +47,30 @@ var (
 		NoAccessServer: "no accessServer",
 
 		// access
-		NoToken:         "no token",
-		CalcTokenFailed: "calc token failed",
+		NoToken:             "no token",
+		CalcTokenFailed:     "calc token failed",
+		NotLogin:            "not login",
+		UnknownCmd:          "unknown command",
+		TooManyRequests:     "too many requests",
+		VersionNotSupported: "protocol version not supported",
+		NoLogicServer:       "no logic server",
+		LogicBusy:           "logic server busy",
+		LogicTimeout:        "logic server timeout",
 
 		// register
 		UserIsAlreadyExist: "user is already exist",
+		InvalidUsername:    "invalid username",
+		InvalidPhone:       "invalid phone number",
+		InvalidEmail:       "invalid email",
+		PasswordTooWeak:    "password too weak",
+		UserNotFound:       "user not found",
+		NoIdentifier:       "username, phone or email required",
+
+		// auth
+		LoginFailed:  "account or password incorrect",
+		TokenInvalid: "token invalid",
+		TokenExpired: "token expired",
+		TokenRevoked: "token revoked",
 
 		// network
 		NoData: "no data found",
@@ -1,8 +1,14 @@
 package logger
 
-import "go.uber.org/zap"
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
 
-var logger *zap.Logger
+var (
+	logger = zap.NewNop()
+	level  = zap.NewAtomicLevel()
+)
 
 func InitLogger(cfg *zap.Config) {
 	var err error
@@ -16,6 +22,21 @@ func InitLogger(cfg *zap.Config) {
 	if err != nil {
 		panic(err)
 	}
+	level = cfg.Level
+}
+
+//运行时修改日志级别，text为debug、info、warn、error等
+func SetLevel(text string) error {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(text)); err != nil {
+		return err
+	}
+	level.SetLevel(l)
+	return nil
+}
+
+func Level() string {
+	return level.String()
 }
 
 func Info(msg string, field ...zap.Field) {
@@ -49,4 +70,3 @@ func Fatal(msg string, field ...zap.Field) {
 func Sync() {
 	logger.Sync()
 }
-
@@ -0,0 +1,44 @@
+package external
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+//修改.proto后在本目录重新生成，生成的代码不要手动修改
+//go:generate protoc --go_out=. auth.proto cmd.proto error.proto handshake.proto heartbeat.proto message.proto notify.proto presence.proto sync.proto
+
+//命令号对应的消息类型，接入层按此注册路由，客户端SDK按此解析响应和推送
+type Command struct {
+	Cmd  Cmd
+	Push bool                 //服务端主动推送
+	Req  func() proto.Message //客户端发送的消息，推送的命令为nil
+	Resp func() proto.Message //服务端回复或推送的消息，为nil时不回复
+}
+
+var commands = map[Cmd]*Command{}
+
+func register(cmd Cmd, push bool, req, resp func() proto.Message) {
+	commands[cmd] = &Command{Cmd: cmd, Push: push, Req: req, Resp: resp}
+}
+
+func init() {
+	register(Cmd_ERROR, true, nil, func() proto.Message { return &Error{} })
+	register(Cmd_HANDSHAKE, false, func() proto.Message { return &Handshake{} }, func() proto.Message { return &HandshakeAck{} })
+	register(Cmd_LOGIN, false, func() proto.Message { return &Login{} }, func() proto.Message { return &LoginAck{} })
+	register(Cmd_LOGOUT, false, func() proto.Message { return &Logout{} }, func() proto.Message { return &LogoutAck{} })
+	register(Cmd_HEARTBEAT, false, func() proto.Message { return &Heartbeat{} }, func() proto.Message { return &HeartbeatAck{} })
+	register(Cmd_SEND_P2P_MSG, false, func() proto.Message { return &SendP2PMsg{} }, func() proto.Message { return &SendMsgAck{} })
+	register(Cmd_SEND_GROUP_MSG, false, func() proto.Message { return &SendGroupMsg{} }, func() proto.Message { return &SendMsgAck{} })
+	register(Cmd_MSG_ACK, false, func() proto.Message { return &MsgAck{} }, nil)
+	register(Cmd_SYNC, false, func() proto.Message { return &SyncReq{} }, func() proto.Message { return &SyncAck{} })
+	register(Cmd_PRESENCE_SUB, false, func() proto.Message { return &PresenceSub{} }, func() proto.Message { return &PresenceSubAck{} })
+	register(Cmd_PUSH_MSG, true, nil, func() proto.Message { return &Msg{} })
+	register(Cmd_PUSH_PRESENCE, true, nil, func() proto.Message { return &Presence{} })
+	register(Cmd_PUSH_NOTIFY, true, nil, func() proto.Message { return &Notify{} })
+	register(Cmd_KICKOUT, true, nil, func() proto.Message { return &Kickout{} })
+}
+
+//未注册的命令号返回nil
+func Lookup(cmd uint32) *Command {
+	return commands[Cmd(cmd)]
+}
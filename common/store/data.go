@@ -0,0 +1,212 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+)
+
+//修改操作，memory直接应用，file先追加到日志再应用，打开时按顺序重放。
+//记录中已经带上分配好的id，重放的结果与写入时相同
+const (
+	opCreateUser     = "createUser"
+	opUpdateUser     = "updateUser"
+	opPutAuthKey     = "putAuthKey"
+	opDeleteAuthKey  = "deleteAuthKey"
+	opSaveMessage    = "saveMessage"
+	opAppendInbox    = "appendInbox"
+	opAckInbox       = "ackInbox"
+	opCreateGroup    = "createGroup"
+	opDeleteGroup    = "deleteGroup"
+	opAddMember      = "addMember"
+	opRemoveMember   = "removeMember"
+	opPutRelation    = "putRelation"
+	opDeleteRelation = "deleteRelation"
+)
+
+type record struct {
+	Op       string       `json:"op"`
+	User     *User        `json:"user,omitempty"`
+	AuthKey  *AuthKey     `json:"authKey,omitempty"`
+	Message  *Message     `json:"message,omitempty"`
+	Inbox    *InboxEntry  `json:"inbox,omitempty"`
+	Group    *Group       `json:"group,omitempty"`
+	Member   *GroupMember `json:"member,omitempty"`
+	Relation *Relation    `json:"relation,omitempty"`
+	Id       string       `json:"id,omitempty"`
+	Uid      uint64       `json:"uid,omitempty"`
+	Peer     uint64       `json:"peer,omitempty"`
+	GroupId  uint64       `json:"groupId,omitempty"`
+	Seq      uint64       `json:"seq,omitempty"`
+}
+
+type inbox struct {
+	last    uint64
+	acked   uint64
+	entries []*InboxEntry //seq递增
+}
+
+//内存中的全部数据，memory和file共用，调用方负责加锁。
+//保存的对象不会被修改，修改时整体替换
+type data struct {
+	users      map[uint64]*User
+	idents     map[string]*User
+	maxUid     uint64
+	authKeys   map[string]*AuthKey
+	messages   map[uint64]*Message
+	clientMsgs map[string]uint64 //发送者和ClientMsgId到消息id
+	maxMsgId   uint64
+	inboxes    map[uint64]*inbox
+	groups     map[uint64]*Group
+	members    map[uint64]map[uint64]*GroupMember
+	userGroups map[uint64]map[uint64]struct{}
+	maxGroupId uint64
+	relations  map[uint64]map[uint64]*Relation
+}
+
+func newData() *data {
+	return &data{
+		users:      make(map[uint64]*User),
+		idents:     make(map[string]*User),
+		maxUid:     firstUid - 1,
+		authKeys:   make(map[string]*AuthKey),
+		messages:   make(map[uint64]*Message),
+		clientMsgs: make(map[string]uint64),
+		maxMsgId:   firstMsgId - 1,
+		inboxes:    make(map[uint64]*inbox),
+		groups:     make(map[uint64]*Group),
+		members:    make(map[uint64]map[uint64]*GroupMember),
+		userGroups: make(map[uint64]map[uint64]struct{}),
+		maxGroupId: firstGroupId - 1,
+		relations:  make(map[uint64]map[uint64]*Relation),
+	}
+}
+
+func clientMsgKey(from uint64, clientMsgId string) string {
+	return fmt.Sprintf("%d:%s", from, clientMsgId)
+}
+
+//标识被uid以外的用户占用
+func (d *data) conflict(user *User, uid uint64) bool {
+	for _, id := range user.Identifiers() {
+		if owner := d.idents[id]; owner != nil && owner.Uid != uid {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *data) inbox(uid uint64) *inbox {
+	box := d.inboxes[uid]
+	if box == nil {
+		box = &inbox{}
+		d.inboxes[uid] = box
+	}
+	return box
+}
+
+func (d *data) addMember(member *GroupMember) {
+	if d.members[member.GroupId] == nil {
+		d.members[member.GroupId] = make(map[uint64]*GroupMember)
+	}
+	d.members[member.GroupId][member.Uid] = member
+	if d.userGroups[member.Uid] == nil {
+		d.userGroups[member.Uid] = make(map[uint64]struct{})
+	}
+	d.userGroups[member.Uid][member.GroupId] = struct{}{}
+}
+
+//重放时文件内容可能被改坏，缺少字段的记录返回错误而不是panic
+func (r *record) valid() bool {
+	switch r.Op {
+	case opCreateUser, opUpdateUser:
+		return r.User != nil
+	case opPutAuthKey:
+		return r.AuthKey != nil
+	case opSaveMessage:
+		return r.Message != nil
+	case opAppendInbox:
+		return r.Inbox != nil
+	case opCreateGroup:
+		return r.Group != nil
+	case opAddMember:
+		return r.Member != nil
+	case opPutRelation:
+		return r.Relation != nil
+	}
+	return true
+}
+
+func (d *data) apply(r *record) error {
+	if !r.valid() {
+		return fmt.Errorf("[store] invalid %s record", r.Op)
+	}
+	switch r.Op {
+	case opCreateUser, opUpdateUser:
+		if old := d.users[r.User.Uid]; old != nil {
+			for _, id := range old.Identifiers() {
+				delete(d.idents, id)
+			}
+		}
+		d.users[r.User.Uid] = r.User
+		for _, id := range r.User.Identifiers() {
+			d.idents[id] = r.User
+		}
+		if r.User.Uid > d.maxUid {
+			d.maxUid = r.User.Uid
+		}
+	case opPutAuthKey:
+		d.authKeys[r.AuthKey.Id] = r.AuthKey
+	case opDeleteAuthKey:
+		delete(d.authKeys, r.Id)
+	case opSaveMessage:
+		d.messages[r.Message.Id] = r.Message
+		if r.Message.ClientMsgId != "" {
+			d.clientMsgs[clientMsgKey(r.Message.From, r.Message.ClientMsgId)] = r.Message.Id
+		}
+		if r.Message.Id > d.maxMsgId {
+			d.maxMsgId = r.Message.Id
+		}
+	case opAppendInbox:
+		box := d.inbox(r.Inbox.Uid)
+		box.entries = append(box.entries, r.Inbox)
+		if r.Inbox.Seq > box.last {
+			box.last = r.Inbox.Seq
+		}
+	case opAckInbox:
+		box := d.inbox(r.Uid)
+		if r.Seq > box.acked {
+			box.acked = r.Seq
+		}
+		i := sort.Search(len(box.entries), func(i int) bool { return box.entries[i].Seq > box.acked })
+		box.entries = append([]*InboxEntry(nil), box.entries[i:]...)
+	case opCreateGroup:
+		d.groups[r.Group.Id] = r.Group
+		if r.Group.Id > d.maxGroupId {
+			d.maxGroupId = r.Group.Id
+		}
+		if r.Member != nil {
+			d.addMember(r.Member)
+		}
+	case opDeleteGroup:
+		for uid := range d.members[r.GroupId] {
+			delete(d.userGroups[uid], r.GroupId)
+		}
+		delete(d.members, r.GroupId)
+		delete(d.groups, r.GroupId)
+	case opAddMember:
+		d.addMember(r.Member)
+	case opRemoveMember:
+		delete(d.members[r.GroupId], r.Uid)
+		delete(d.userGroups[r.Uid], r.GroupId)
+	case opPutRelation:
+		if d.relations[r.Relation.Uid] == nil {
+			d.relations[r.Relation.Uid] = make(map[uint64]*Relation)
+		}
+		d.relations[r.Relation.Uid][r.Relation.Peer] = r.Relation
+	case opDeleteRelation:
+		delete(d.relations[r.Uid], r.Peer)
+	default:
+		return fmt.Errorf("[store] unknown op %q", r.Op)
+	}
+	return nil
+}
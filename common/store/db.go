@@ -0,0 +1,427 @@
+package store
+
+import (
+	"sort"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+//memory和file共用的实现，修改先检查再通过commit应用
+type db struct {
+	mutex  sync.RWMutex
+	data   *data
+	log    func(r *record) error //file写入日志，memory为nil
+	closed bool
+}
+
+func newDB() *db {
+	return &db{data: newData()}
+}
+
+//调用方持有写锁
+func (db *db) commit(r *record) error {
+	if db.log != nil {
+		if err := db.log(r); err != nil {
+			return err
+		}
+	}
+	return db.data.apply(r)
+}
+
+//加读锁，返回的unlock必须调用
+func (db *db) read() (func(), error) {
+	db.mutex.RLock()
+	if db.closed {
+		db.mutex.RUnlock()
+		return nil, ErrClosed
+	}
+	return db.mutex.RUnlock, nil
+}
+
+func (db *db) write() (func(), error) {
+	db.mutex.Lock()
+	if db.closed {
+		db.mutex.Unlock()
+		return nil, ErrClosed
+	}
+	return db.mutex.Unlock, nil
+}
+
+func (db *db) CreateUser(ctx context.Context, user *User) error {
+	unlock, err := db.write()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if db.data.conflict(user, 0) {
+		return ErrExists
+	}
+	copied := *user
+	copied.Uid = db.data.maxUid + 1
+	if err = db.commit(&record{Op: opCreateUser, User: &copied}); err != nil {
+		return err
+	}
+	user.Uid = copied.Uid
+	return nil
+}
+
+func (db *db) UpdateUser(ctx context.Context, user *User) error {
+	unlock, err := db.write()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if db.data.users[user.Uid] == nil {
+		return ErrNotFound
+	}
+	if db.data.conflict(user, user.Uid) {
+		return ErrExists
+	}
+	copied := *user
+	return db.commit(&record{Op: opUpdateUser, User: &copied})
+}
+
+func (db *db) GetUser(ctx context.Context, uid uint64) (*User, error) {
+	unlock, err := db.read()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	user := db.data.users[uid]
+	if user == nil {
+		return nil, ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (db *db) LookupUser(ctx context.Context, identifier string) (*User, error) {
+	unlock, err := db.read()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	user := db.data.idents[identifier]
+	if user == nil {
+		return nil, ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (db *db) PutAuthKey(ctx context.Context, key *AuthKey) error {
+	unlock, err := db.write()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return db.commit(&record{Op: opPutAuthKey, AuthKey: key.copy()})
+}
+
+func (db *db) GetAuthKey(ctx context.Context, id string) (*AuthKey, error) {
+	unlock, err := db.read()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	key := db.data.authKeys[id]
+	if key == nil {
+		return nil, ErrNotFound
+	}
+	return key.copy(), nil
+}
+
+func (db *db) ListAuthKeys(ctx context.Context) ([]*AuthKey, error) {
+	unlock, err := db.read()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	keys := make([]*AuthKey, 0, len(db.data.authKeys))
+	for _, key := range db.data.authKeys {
+		keys = append(keys, key.copy())
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreateTime != keys[j].CreateTime {
+			return keys[i].CreateTime < keys[j].CreateTime
+		}
+		return keys[i].Id < keys[j].Id
+	})
+	return keys, nil
+}
+
+func (db *db) DeleteAuthKey(ctx context.Context, id string) error {
+	unlock, err := db.write()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if db.data.authKeys[id] == nil {
+		return ErrNotFound
+	}
+	return db.commit(&record{Op: opDeleteAuthKey, Id: id})
+}
+
+func (db *db) SaveMessage(ctx context.Context, msg *Message) error {
+	unlock, err := db.write()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if msg.ClientMsgId != "" {
+		if id, ok := db.data.clientMsgs[clientMsgKey(msg.From, msg.ClientMsgId)]; ok {
+			msg.Id = id
+			return ErrExists
+		}
+	}
+	copied := msg.copy()
+	copied.Id = db.data.maxMsgId + 1
+	if err = db.commit(&record{Op: opSaveMessage, Message: copied}); err != nil {
+		return err
+	}
+	msg.Id = copied.Id
+	return nil
+}
+
+func (db *db) GetMessage(ctx context.Context, id uint64) (*Message, error) {
+	unlock, err := db.read()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	msg := db.data.messages[id]
+	if msg == nil {
+		return nil, ErrNotFound
+	}
+	return msg.copy(), nil
+}
+
+func (db *db) AppendInbox(ctx context.Context, uid, msgId uint64) (uint64, error) {
+	unlock, err := db.write()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	if db.data.messages[msgId] == nil {
+		return 0, ErrNotFound
+	}
+	var seq uint64 = 1
+	if box := db.data.inboxes[uid]; box != nil {
+		seq = box.last + 1
+	}
+	if err = db.commit(&record{Op: opAppendInbox, Inbox: &InboxEntry{Uid: uid, Seq: seq, MsgId: msgId}}); err != nil {
+		return 0, err
+	}
+	return seq, nil
+}
+
+func (db *db) ListInbox(ctx context.Context, uid, after uint64, limit int) ([]*InboxEntry, error) {
+	unlock, err := db.read()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	box := db.data.inboxes[uid]
+	if box == nil {
+		return []*InboxEntry{}, nil
+	}
+	i := sort.Search(len(box.entries), func(i int) bool { return box.entries[i].Seq > after })
+	n := len(box.entries) - i
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	entries := make([]*InboxEntry, 0, n)
+	for _, entry := range box.entries[i : i+n] {
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+	return entries, nil
+}
+
+func (db *db) AckInbox(ctx context.Context, uid, seq uint64) error {
+	unlock, err := db.write()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	box := db.data.inboxes[uid]
+	if box == nil {
+		return nil
+	}
+	//不能确认还没有分配的seq
+	if seq > box.last {
+		seq = box.last
+	}
+	if seq <= box.acked {
+		return nil
+	}
+	return db.commit(&record{Op: opAckInbox, Uid: uid, Seq: seq})
+}
+
+func (db *db) InboxState(ctx context.Context, uid uint64) (uint64, uint64, error) {
+	unlock, err := db.read()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unlock()
+	if box := db.data.inboxes[uid]; box != nil {
+		return box.last, box.acked, nil
+	}
+	return 0, 0, nil
+}
+
+func (db *db) CreateGroup(ctx context.Context, group *Group) error {
+	unlock, err := db.write()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	copied := *group
+	copied.Id = db.data.maxGroupId + 1
+	owner := &GroupMember{GroupId: copied.Id, Uid: copied.Owner, Role: RoleOwner, JoinTime: copied.CreateTime}
+	if err = db.commit(&record{Op: opCreateGroup, Group: &copied, Member: owner}); err != nil {
+		return err
+	}
+	group.Id = copied.Id
+	return nil
+}
+
+func (db *db) GetGroup(ctx context.Context, groupId uint64) (*Group, error) {
+	unlock, err := db.read()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	group := db.data.groups[groupId]
+	if group == nil {
+		return nil, ErrNotFound
+	}
+	copied := *group
+	return &copied, nil
+}
+
+func (db *db) DeleteGroup(ctx context.Context, groupId uint64) error {
+	unlock, err := db.write()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if db.data.groups[groupId] == nil {
+		return ErrNotFound
+	}
+	return db.commit(&record{Op: opDeleteGroup, GroupId: groupId})
+}
+
+func (db *db) AddMember(ctx context.Context, member *GroupMember) error {
+	unlock, err := db.write()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if db.data.groups[member.GroupId] == nil {
+		return ErrNotFound
+	}
+	if db.data.members[member.GroupId][member.Uid] != nil {
+		return ErrExists
+	}
+	copied := *member
+	return db.commit(&record{Op: opAddMember, Member: &copied})
+}
+
+func (db *db) RemoveMember(ctx context.Context, groupId, uid uint64) error {
+	unlock, err := db.write()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if db.data.members[groupId][uid] == nil {
+		return ErrNotFound
+	}
+	return db.commit(&record{Op: opRemoveMember, GroupId: groupId, Uid: uid})
+}
+
+func (db *db) Members(ctx context.Context, groupId uint64) ([]*GroupMember, error) {
+	unlock, err := db.read()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if db.data.groups[groupId] == nil {
+		return nil, ErrNotFound
+	}
+	members := make([]*GroupMember, 0, len(db.data.members[groupId]))
+	for _, member := range db.data.members[groupId] {
+		copied := *member
+		members = append(members, &copied)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Uid < members[j].Uid })
+	return members, nil
+}
+
+func (db *db) UserGroups(ctx context.Context, uid uint64) ([]uint64, error) {
+	unlock, err := db.read()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	groups := make([]uint64, 0, len(db.data.userGroups[uid]))
+	for groupId := range db.data.userGroups[uid] {
+		groups = append(groups, groupId)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i] < groups[j] })
+	return groups, nil
+}
+
+func (db *db) PutRelation(ctx context.Context, relation *Relation) error {
+	unlock, err := db.write()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	copied := *relation
+	return db.commit(&record{Op: opPutRelation, Relation: &copied})
+}
+
+func (db *db) GetRelation(ctx context.Context, uid, peer uint64) (*Relation, error) {
+	unlock, err := db.read()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	relation := db.data.relations[uid][peer]
+	if relation == nil {
+		return nil, ErrNotFound
+	}
+	copied := *relation
+	return &copied, nil
+}
+
+func (db *db) DeleteRelation(ctx context.Context, uid, peer uint64) error {
+	unlock, err := db.write()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if db.data.relations[uid][peer] == nil {
+		return ErrNotFound
+	}
+	return db.commit(&record{Op: opDeleteRelation, Uid: uid, Peer: peer})
+}
+
+func (db *db) ListRelations(ctx context.Context, uid uint64, typ int32) ([]*Relation, error) {
+	unlock, err := db.read()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	relations := make([]*Relation, 0, len(db.data.relations[uid]))
+	for _, relation := range db.data.relations[uid] {
+		if typ == 0 || relation.Type == typ {
+			copied := *relation
+			relations = append(relations, &copied)
+		}
+	}
+	sort.Slice(relations, func(i, j int) bool { return relations[i].Peer < relations[j].Peer })
+	return relations, nil
+}
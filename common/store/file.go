@@ -0,0 +1,109 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+)
+
+//每次修改一行json追加写入文件，写入后fsync，打开时按顺序重放全部记录。
+//进程在写入中途崩溃时最后一行不完整，打开时截掉。
+//文件只追加不压缩，只能由一个进程打开
+type File struct {
+	*db
+	file   *os.File
+	offset int64 //最后一条完整记录的结尾
+}
+
+func OpenFile(path string) (*File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{db: newDB(), file: file}
+	if err = f.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	f.log = f.append
+	return f, nil
+}
+
+func (f *File) load() error {
+	r := bufio.NewReader(f.file)
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				logger.Warn("store: truncate incomplete record", zap.String("path", f.file.Name()),
+					zap.Int64("offset", offset))
+				if err = f.file.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rec := &record{}
+		if err = json.Unmarshal(bytes.TrimSpace(line), rec); err != nil {
+			return fmt.Errorf("[store] offset %d: %v", offset, err)
+		}
+		if err = f.data.apply(rec); err != nil {
+			return fmt.Errorf("[store] offset %d: %v", offset, err)
+		}
+		offset += int64(len(line))
+	}
+	f.offset = offset
+	_, err := f.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+//调用方持有写锁
+func (f *File) append(r *record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err = f.file.Write(line); err != nil {
+		f.rollback()
+		return err
+	}
+	if err = f.file.Sync(); err != nil {
+		f.rollback()
+		return err
+	}
+	f.offset += int64(len(line))
+	return nil
+}
+
+//写入失败时去掉写了一半的记录，避免之后的记录接在后面
+func (f *File) rollback() {
+	if err := f.file.Truncate(f.offset); err != nil {
+		logger.Error("store: rollback", zap.String("path", f.file.Name()), zap.Error(err))
+	}
+	f.file.Seek(f.offset, io.SeekStart)
+}
+
+func (f *File) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	return f.file.Close()
+}
+
+func (f *File) Name() string {
+	return f.file.Name()
+}
@@ -0,0 +1,17 @@
+package store
+
+//只在本进程内有效，用于测试和单进程部署
+type Memory struct {
+	*db
+}
+
+func NewMemory() *Memory {
+	return &Memory{db: newDB()}
+}
+
+func (m *Memory) Close() error {
+	m.mutex.Lock()
+	m.closed = true
+	m.mutex.Unlock()
+	return nil
+}
@@ -0,0 +1,115 @@
+package store
+
+const (
+	firstUid     = 10001
+	firstGroupId = 1
+	firstMsgId   = 1
+)
+
+//群成员的角色
+const (
+	RoleMember int32 = iota
+	RoleAdmin
+	RoleOwner
+)
+
+//关系的类型
+const (
+	RelationFriend int32 = iota + 1
+	RelationBlock
+)
+
+type User struct {
+	Uid          uint64 `json:"uid"`
+	Username     string `json:"username,omitempty"`
+	Phone        string `json:"phone,omitempty"`
+	Email        string `json:"email,omitempty"`
+	Nickname     string `json:"nickname,omitempty"`
+	PasswordHash string `json:"passwordHash"`
+	CreateTime   int64  `json:"createTime"`
+}
+
+//用户名、手机号、邮箱共用一个命名空间，key带上类型前缀
+func (user *User) Identifiers() []string {
+	ids := make([]string, 0, 3)
+	if user.Username != "" {
+		ids = append(ids, UsernameKey(user.Username))
+	}
+	if user.Phone != "" {
+		ids = append(ids, PhoneKey(user.Phone))
+	}
+	if user.Email != "" {
+		ids = append(ids, EmailKey(user.Email))
+	}
+	return ids
+}
+
+func UsernameKey(username string) string {
+	return "username:" + username
+}
+
+func PhoneKey(phone string) string {
+	return "phone:" + phone
+}
+
+func EmailKey(email string) string {
+	return "email:" + email
+}
+
+type AuthKey struct {
+	Id         string `json:"id"`
+	Secret     []byte `json:"secret"`
+	Active     bool   `json:"active,omitempty"` //用于签发，其他的只用于校验
+	CreateTime int64  `json:"createTime"`
+}
+
+func (key *AuthKey) copy() *AuthKey {
+	copied := *key
+	copied.Secret = append([]byte(nil), key.Secret...)
+	return &copied
+}
+
+type Message struct {
+	Id          uint64 `json:"id"`
+	From        uint64 `json:"from"`
+	To          uint64 `json:"to"` //单聊为uid，群聊为群id
+	Group       bool   `json:"group,omitempty"`
+	ClientMsgId string `json:"clientMsgId,omitempty"` //发送方生成，用于去重
+	ContentType int32  `json:"contentType"`
+	Content     []byte `json:"content"`
+	CreateTime  int64  `json:"createTime"`
+}
+
+func (msg *Message) copy() *Message {
+	copied := *msg
+	copied.Content = append([]byte(nil), msg.Content...)
+	return &copied
+}
+
+type InboxEntry struct {
+	Uid   uint64 `json:"uid"`
+	Seq   uint64 `json:"seq"`
+	MsgId uint64 `json:"msgId"`
+}
+
+type Group struct {
+	Id         uint64 `json:"id"`
+	Name       string `json:"name"`
+	Owner      uint64 `json:"owner"`
+	CreateTime int64  `json:"createTime"`
+}
+
+type GroupMember struct {
+	GroupId  uint64 `json:"groupId"`
+	Uid      uint64 `json:"uid"`
+	Role     int32  `json:"role"`
+	JoinTime int64  `json:"joinTime"`
+}
+
+type Relation struct {
+	Uid        uint64 `json:"uid"`
+	Peer       uint64 `json:"peer"`
+	Type       int32  `json:"type"`
+	Remark     string `json:"remark,omitempty"`
+	CreateTime int64  `json:"createTime"`
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	StoreMemory = "memory"
+	StoreFile   = "file"
+)
+
+var (
+	ErrExists   = errors.New("[store] already exists")
+	ErrNotFound = errors.New("[store] not found")
+	ErrClosed   = errors.New("[store] closed")
+)
+
+//用户，标识已经规范化，为空的标识不占用唯一性
+type Users interface {
+	//分配uid并保存，任一标识已被占用时返回ErrExists，不保存任何内容
+	CreateUser(ctx context.Context, user *User) error
+	//按uid覆盖，标识被其他用户占用时返回ErrExists，用户不存在时返回ErrNotFound
+	UpdateUser(ctx context.Context, user *User) error
+	GetUser(ctx context.Context, uid uint64) (*User, error)
+	//按Identifiers中的key查找
+	LookupUser(ctx context.Context, identifier string) (*User, error)
+}
+
+//token的签名密钥，按id覆盖
+type AuthKeys interface {
+	PutAuthKey(ctx context.Context, key *AuthKey) error
+	GetAuthKey(ctx context.Context, id string) (*AuthKey, error)
+	//按创建时间排序
+	ListAuthKeys(ctx context.Context) ([]*AuthKey, error)
+	DeleteAuthKey(ctx context.Context, id string) error
+}
+
+//消息正文，单聊和群聊的消息都只保存一份
+type Messages interface {
+	//分配消息id并保存。ClientMsgId不为空且同一发送者已经保存过时返回ErrExists，
+	//msg.Id设置为已有消息的id
+	SaveMessage(ctx context.Context, msg *Message) error
+	GetMessage(ctx context.Context, id uint64) (*Message, error)
+}
+
+//每个用户的收件箱，按seq递增排列消息id
+type Inboxes interface {
+	//分配seq并追加，消息不存在时返回ErrNotFound
+	AppendInbox(ctx context.Context, uid, msgId uint64) (uint64, error)
+	//返回seq大于after的最多limit条，limit<=0时不限制，已确认的不再返回
+	ListInbox(ctx context.Context, uid, after uint64, limit int) ([]*InboxEntry, error)
+	//确认seq及之前的消息并删除，小于已确认的seq时忽略
+	AckInbox(ctx context.Context, uid, seq uint64) error
+	//最后分配的seq和已确认的seq
+	InboxState(ctx context.Context, uid uint64) (last, acked uint64, err error)
+}
+
+//群组和成员
+type Groups interface {
+	//分配群id并保存，群主作为成员加入
+	CreateGroup(ctx context.Context, group *Group) error
+	GetGroup(ctx context.Context, groupId uint64) (*Group, error)
+	//同时删除所有成员
+	DeleteGroup(ctx context.Context, groupId uint64) error
+	//群不存在时返回ErrNotFound，已是成员时返回ErrExists
+	AddMember(ctx context.Context, member *GroupMember) error
+	RemoveMember(ctx context.Context, groupId, uid uint64) error
+	//按uid排序
+	Members(ctx context.Context, groupId uint64) ([]*GroupMember, error)
+	//用户加入的群id，从小到大排序
+	UserGroups(ctx context.Context, uid uint64) ([]uint64, error)
+}
+
+//用户之间的单向关系，好友需要双方各保存一条
+type Relations interface {
+	//按uid和peer覆盖
+	PutRelation(ctx context.Context, relation *Relation) error
+	GetRelation(ctx context.Context, uid, peer uint64) (*Relation, error)
+	DeleteRelation(ctx context.Context, uid, peer uint64) error
+	//typ为0时返回全部，按peer排序
+	ListRelations(ctx context.Context, uid uint64, typ int32) ([]*Relation, error)
+}
+
+//所有业务共用的数据访问层，实现需要可以并发调用。
+//查询返回的是副本，调用方修改不影响存储；不存在时返回ErrNotFound，Close之后返回ErrClosed
+type Store interface {
+	Users
+	AuthKeys
+	Messages
+	Inboxes
+	Groups
+	Relations
+	Close() error
+}
+
+type Cfg struct {
+	Store string `yaml:"store"` //memory或file
+	Path  string `yaml:"path"`  //file的数据文件
+}
+
+//memory只在本进程内有效，用于测试；file为单进程独占的本地文件
+func New(cfg Cfg) (Store, error) {
+	switch cfg.Store {
+	case StoreMemory:
+		return NewMemory(), nil
+	case StoreFile:
+		return OpenFile(cfg.Path)
+	}
+	return nil, fmt.Errorf("[store] unknown store %q", cfg.Store)
+}
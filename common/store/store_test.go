@@ -0,0 +1,82 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/imkuqin-zw/ZWChat/common/store"
+	"github.com/imkuqin-zw/ZWChat/common/store/storetest"
+	"golang.org/x/net/context"
+)
+
+func TestMemory(t *testing.T) {
+	storetest.Run(t, storetest.Backend{
+		Open: func(t *testing.T) store.Store { return store.NewMemory() },
+	})
+}
+
+func TestFile(t *testing.T) {
+	open := func(t *testing.T, path string) store.Store {
+		s, err := store.OpenFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	storetest.Run(t, storetest.Backend{
+		Open: func(t *testing.T) store.Store {
+			return open(t, filepath.Join(t.TempDir(), "store.db"))
+		},
+		Reopen: func(t *testing.T, s store.Store) store.Store {
+			path := s.(*store.File).Name()
+			s.Close()
+			return open(t, path)
+		},
+	})
+}
+
+//模拟写入中途崩溃，重新打开后截掉不完整的记录，之后的写入正常
+func TestFileTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	s, err := store.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	alice := &store.User{Username: "alice"}
+	if err = s.CreateUser(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(`{"op":"createUser","user":{"uid":10002,"username":"bo`))
+	f.Close()
+
+	if s, err = store.OpenFile(path); err != nil {
+		t.Fatal(err)
+	}
+	bob := &store.User{Username: "bob"}
+	if err = s.CreateUser(ctx, bob); err != nil || bob.Uid != alice.Uid+1 {
+		t.Fatalf("unexpected uid %d, %v", bob.Uid, err)
+	}
+	s.Close()
+	if s, err = store.OpenFile(path); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if user, err := s.LookupUser(ctx, store.UsernameKey("bob")); err != nil || user.Uid != bob.Uid {
+		t.Fatalf("unexpected user %+v, %v", user, err)
+	}
+
+	//完整但无法解析的记录不能静默丢弃
+	corrupt := filepath.Join(t.TempDir(), "corrupt.db")
+	ioutil.WriteFile(corrupt, []byte("{\"op\":\"createUser\"}\n"), 0600)
+	if _, err = store.OpenFile(corrupt); err == nil {
+		t.Fatal("invalid record should fail")
+	}
+}
@@ -0,0 +1,489 @@
+//store的一致性测试，每个后端都要通过：
+//
+//	func TestMemory(t *testing.T) {
+//		storetest.Run(t, storetest.Backend{Open: func(t *testing.T) store.Store { return store.NewMemory() }})
+//	}
+package storetest
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/imkuqin-zw/ZWChat/common/store"
+	"golang.org/x/net/context"
+)
+
+type Backend struct {
+	//返回空的store
+	Open func(t *testing.T) store.Store
+	//关闭后在同样的数据上重新打开，不为nil时每项测试都检查重新打开后数据不变
+	Reopen func(t *testing.T, s store.Store) store.Store
+}
+
+func Run(t *testing.T, b Backend) {
+	for _, c := range []struct {
+		name string
+		test func(t *testing.T, s store.Store, reopen func(store.Store) store.Store) store.Store
+	}{
+		{"Users", testUsers},
+		{"AuthKeys", testAuthKeys},
+		{"Messages", testMessages},
+		{"Inboxes", testInboxes},
+		{"Groups", testGroups},
+		{"Relations", testRelations},
+		{"Concurrent", testConcurrent},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			reopen := func(s store.Store) store.Store {
+				if b.Reopen == nil {
+					return s
+				}
+				return b.Reopen(t, s)
+			}
+			c.test(t, b.Open(t), reopen).Close()
+		})
+	}
+	t.Run("Closed", func(t *testing.T) {
+		testClosed(t, b.Open(t))
+	})
+}
+
+func testUsers(t *testing.T, s store.Store, reopen func(store.Store) store.Store) store.Store {
+	ctx := context.Background()
+	alice := &store.User{Username: "alice", Email: "alice@example.com", PasswordHash: "h1", CreateTime: 1}
+	if err := s.CreateUser(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	if alice.Uid == 0 {
+		t.Fatal("uid not assigned")
+	}
+	//任一标识冲突时不保存
+	if err := s.CreateUser(ctx, &store.User{Username: "bob", Email: "alice@example.com"}); err != store.ErrExists {
+		t.Fatalf("want ErrExists, got %v", err)
+	}
+	if _, err := s.LookupUser(ctx, store.UsernameKey("bob")); err != store.ErrNotFound {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+	bob := &store.User{Username: "bob", Phone: "+8613800000000"}
+	if err := s.CreateUser(ctx, bob); err != nil {
+		t.Fatal(err)
+	}
+	if bob.Uid <= alice.Uid {
+		t.Fatalf("uid should increase: %d after %d", bob.Uid, alice.Uid)
+	}
+
+	//改邮箱后旧的标识释放，不能占用别人的标识
+	alice.Email = "a@example.com"
+	alice.Nickname = "A"
+	if err := s.UpdateUser(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateUser(ctx, &store.User{Uid: bob.Uid, Username: "alice"}); err != store.ErrExists {
+		t.Fatalf("want ErrExists, got %v", err)
+	}
+	if err := s.UpdateUser(ctx, &store.User{Uid: 1, Username: "carol"}); err != store.ErrNotFound {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+
+	check := func(s store.Store) {
+		user, err := s.LookupUser(ctx, store.EmailKey("a@example.com"))
+		if err != nil || user.Uid != alice.Uid || user.PasswordHash != "h1" || user.Nickname != "A" {
+			t.Fatalf("unexpected user %+v, %v", user, err)
+		}
+		if _, err = s.LookupUser(ctx, store.EmailKey("alice@example.com")); err != store.ErrNotFound {
+			t.Fatalf("old email: want ErrNotFound, got %v", err)
+		}
+		//返回的是副本
+		user.Username = "changed"
+		if user, err = s.GetUser(ctx, alice.Uid); err != nil || user.Username != "alice" {
+			t.Fatalf("unexpected user %+v, %v", user, err)
+		}
+		if user, err = s.LookupUser(ctx, store.PhoneKey("+8613800000000")); err != nil || user.Uid != bob.Uid {
+			t.Fatalf("unexpected user %+v, %v", user, err)
+		}
+		if _, err = s.GetUser(ctx, 1); err != store.ErrNotFound {
+			t.Fatalf("want ErrNotFound, got %v", err)
+		}
+	}
+	check(s)
+	s = reopen(s)
+	check(s)
+	//重新打开后继续分配
+	carol := &store.User{Username: "carol"}
+	if err := s.CreateUser(ctx, carol); err != nil || carol.Uid <= bob.Uid {
+		t.Fatalf("unexpected uid %d, %v", carol.Uid, err)
+	}
+	return s
+}
+
+func testAuthKeys(t *testing.T, s store.Store, reopen func(store.Store) store.Store) store.Store {
+	ctx := context.Background()
+	secret := []byte("secret-2")
+	for _, key := range []*store.AuthKey{
+		{Id: "k2", Secret: secret, Active: true, CreateTime: 2},
+		{Id: "k1", Secret: []byte("secret-1"), CreateTime: 1},
+		{Id: "k3", Secret: []byte("secret-3"), CreateTime: 3},
+	} {
+		if err := s.PutAuthKey(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//保存的是副本
+	secret[0] = 'S'
+	if err := s.PutAuthKey(ctx, &store.AuthKey{Id: "k3", Secret: []byte("secret-3b"), CreateTime: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteAuthKey(ctx, "k1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteAuthKey(ctx, "k1"); err != store.ErrNotFound {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+
+	check := func(s store.Store) {
+		key, err := s.GetAuthKey(ctx, "k2")
+		if err != nil || !key.Active || string(key.Secret) != "secret-2" {
+			t.Fatalf("unexpected key %+v, %v", key, err)
+		}
+		key.Secret[0] = 'S'
+		if key, _ = s.GetAuthKey(ctx, "k2"); string(key.Secret) != "secret-2" {
+			t.Fatalf("secret modified: %s", key.Secret)
+		}
+		if _, err = s.GetAuthKey(ctx, "k1"); err != store.ErrNotFound {
+			t.Fatalf("want ErrNotFound, got %v", err)
+		}
+		keys, err := s.ListAuthKeys(ctx)
+		if err != nil || len(keys) != 2 || keys[0].Id != "k2" || keys[1].Id != "k3" || string(keys[1].Secret) != "secret-3b" {
+			t.Fatalf("unexpected keys %+v, %v", keys, err)
+		}
+	}
+	check(s)
+	s = reopen(s)
+	check(s)
+	return s
+}
+
+func testMessages(t *testing.T, s store.Store, reopen func(store.Store) store.Store) store.Store {
+	ctx := context.Background()
+	first := &store.Message{From: 1, To: 2, ClientMsgId: "c1", ContentType: 1, Content: []byte("hello"), CreateTime: 1}
+	if err := s.SaveMessage(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Id == 0 {
+		t.Fatal("id not assigned")
+	}
+	//同一发送者重复发送时返回已有的id
+	dup := &store.Message{From: 1, To: 2, ClientMsgId: "c1", Content: []byte("again")}
+	if err := s.SaveMessage(ctx, dup); err != store.ErrExists || dup.Id != first.Id {
+		t.Fatalf("want ErrExists with id %d, got %d, %v", first.Id, dup.Id, err)
+	}
+	other := &store.Message{From: 3, To: 9, Group: true, ClientMsgId: "c1", Content: []byte("hi all")}
+	if err := s.SaveMessage(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	if other.Id <= first.Id {
+		t.Fatalf("id should increase: %d after %d", other.Id, first.Id)
+	}
+
+	check := func(s store.Store) {
+		msg, err := s.GetMessage(ctx, first.Id)
+		if err != nil || msg.From != 1 || msg.To != 2 || msg.ContentType != 1 || !bytes.Equal(msg.Content, []byte("hello")) {
+			t.Fatalf("unexpected message %+v, %v", msg, err)
+		}
+		if msg, err = s.GetMessage(ctx, other.Id); err != nil || !msg.Group || msg.To != 9 {
+			t.Fatalf("unexpected message %+v, %v", msg, err)
+		}
+		if _, err = s.GetMessage(ctx, other.Id+1); err != store.ErrNotFound {
+			t.Fatalf("want ErrNotFound, got %v", err)
+		}
+		dup := &store.Message{From: 1, ClientMsgId: "c1"}
+		if err = s.SaveMessage(ctx, dup); err != store.ErrExists || dup.Id != first.Id {
+			t.Fatalf("want ErrExists with id %d, got %d, %v", first.Id, dup.Id, err)
+		}
+	}
+	check(s)
+	s = reopen(s)
+	check(s)
+	return s
+}
+
+func seqs(entries []*store.InboxEntry) []uint64 {
+	result := make([]uint64, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry.Seq)
+	}
+	return result
+}
+
+func testInboxes(t *testing.T, s store.Store, reopen func(store.Store) store.Store) store.Store {
+	ctx := context.Background()
+	if _, err := s.AppendInbox(ctx, 1, 12345); err != store.ErrNotFound {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+	msgIds := make([]uint64, 0, 5)
+	for i := 0; i < 5; i++ {
+		msg := &store.Message{From: 2, To: 1, Content: []byte{byte(i)}}
+		if err := s.SaveMessage(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+		seq, err := s.AppendInbox(ctx, 1, msg.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq != uint64(i+1) {
+			t.Fatalf("want seq %d, got %d", i+1, seq)
+		}
+		msgIds = append(msgIds, msg.Id)
+	}
+	//每个用户的seq独立
+	if seq, err := s.AppendInbox(ctx, 2, msgIds[0]); err != nil || seq != 1 {
+		t.Fatalf("unexpected seq %d, %v", seq, err)
+	}
+	entries, err := s.ListInbox(ctx, 1, 1, 2)
+	if err != nil || fmt.Sprint(seqs(entries)) != "[2 3]" || entries[0].MsgId != msgIds[1] || entries[0].Uid != 1 {
+		t.Fatalf("unexpected entries %+v, %v", entries, err)
+	}
+	if err = s.AckInbox(ctx, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	//比已确认的小时忽略
+	if err = s.AckInbox(ctx, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(s store.Store) {
+		entries, err := s.ListInbox(ctx, 1, 0, 0)
+		if err != nil || fmt.Sprint(seqs(entries)) != "[3 4 5]" || entries[2].MsgId != msgIds[4] {
+			t.Fatalf("unexpected entries %+v, %v", entries, err)
+		}
+		if last, acked, err := s.InboxState(ctx, 1); err != nil || last != 5 || acked != 2 {
+			t.Fatalf("unexpected state %d %d, %v", last, acked, err)
+		}
+		if entries, err = s.ListInbox(ctx, 3, 0, 0); err != nil || len(entries) != 0 {
+			t.Fatalf("unexpected entries %+v, %v", entries, err)
+		}
+		if last, acked, err := s.InboxState(ctx, 3); err != nil || last != 0 || acked != 0 {
+			t.Fatalf("unexpected state %d %d, %v", last, acked, err)
+		}
+	}
+	check(s)
+	s = reopen(s)
+	check(s)
+	//确认的seq不超过已分配的，之后追加的不受影响
+	if err = s.AckInbox(ctx, 1, 100); err != nil {
+		t.Fatal(err)
+	}
+	if seq, err := s.AppendInbox(ctx, 1, msgIds[0]); err != nil || seq != 6 {
+		t.Fatalf("unexpected seq %d, %v", seq, err)
+	}
+	if entries, err = s.ListInbox(ctx, 1, 0, 0); err != nil || fmt.Sprint(seqs(entries)) != "[6]" {
+		t.Fatalf("unexpected entries %+v, %v", entries, err)
+	}
+	return s
+}
+
+func testGroups(t *testing.T, s store.Store, reopen func(store.Store) store.Store) store.Store {
+	ctx := context.Background()
+	g1 := &store.Group{Name: "g1", Owner: 10, CreateTime: 1}
+	g2 := &store.Group{Name: "g2", Owner: 20, CreateTime: 2}
+	for _, g := range []*store.Group{g1, g2} {
+		if err := s.CreateGroup(ctx, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g1.Id == 0 || g2.Id <= g1.Id {
+		t.Fatalf("unexpected ids %d %d", g1.Id, g2.Id)
+	}
+	for _, m := range []*store.GroupMember{
+		{GroupId: g1.Id, Uid: 30, Role: store.RoleAdmin},
+		{GroupId: g1.Id, Uid: 20},
+		{GroupId: g2.Id, Uid: 30},
+	} {
+		if err := s.AddMember(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.AddMember(ctx, &store.GroupMember{GroupId: g1.Id, Uid: 30}); err != store.ErrExists {
+		t.Fatalf("want ErrExists, got %v", err)
+	}
+	if err := s.AddMember(ctx, &store.GroupMember{GroupId: g2.Id + 100, Uid: 30}); err != store.ErrNotFound {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+	if err := s.RemoveMember(ctx, g1.Id, 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveMember(ctx, g1.Id, 20); err != store.ErrNotFound {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteGroup(ctx, g2.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(s store.Store) {
+		g, err := s.GetGroup(ctx, g1.Id)
+		if err != nil || g.Name != "g1" || g.Owner != 10 {
+			t.Fatalf("unexpected group %+v, %v", g, err)
+		}
+		if _, err = s.GetGroup(ctx, g2.Id); err != store.ErrNotFound {
+			t.Fatalf("want ErrNotFound, got %v", err)
+		}
+		members, err := s.Members(ctx, g1.Id)
+		if err != nil || len(members) != 2 || members[0].Uid != 10 || members[0].Role != store.RoleOwner ||
+			members[1].Uid != 30 || members[1].Role != store.RoleAdmin {
+			t.Fatalf("unexpected members %+v, %v", members, err)
+		}
+		if _, err = s.Members(ctx, g2.Id); err != store.ErrNotFound {
+			t.Fatalf("want ErrNotFound, got %v", err)
+		}
+		if groups, err := s.UserGroups(ctx, 30); err != nil || fmt.Sprint(groups) != fmt.Sprint([]uint64{g1.Id}) {
+			t.Fatalf("unexpected groups %v, %v", groups, err)
+		}
+		if groups, err := s.UserGroups(ctx, 20); err != nil || len(groups) != 0 {
+			t.Fatalf("unexpected groups %v, %v", groups, err)
+		}
+	}
+	check(s)
+	s = reopen(s)
+	check(s)
+	//删除的群id不会复用
+	g3 := &store.Group{Name: "g3", Owner: 10}
+	if err := s.CreateGroup(ctx, g3); err != nil || g3.Id <= g2.Id {
+		t.Fatalf("unexpected id %d, %v", g3.Id, err)
+	}
+	return s
+}
+
+func testRelations(t *testing.T, s store.Store, reopen func(store.Store) store.Store) store.Store {
+	ctx := context.Background()
+	for _, r := range []*store.Relation{
+		{Uid: 1, Peer: 3, Type: store.RelationFriend},
+		{Uid: 1, Peer: 2, Type: store.RelationFriend, Remark: "two"},
+		{Uid: 1, Peer: 4, Type: store.RelationBlock},
+		{Uid: 2, Peer: 1, Type: store.RelationFriend},
+		{Uid: 1, Peer: 5, Type: store.RelationFriend},
+	} {
+		if err := s.PutRelation(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//覆盖和删除
+	if err := s.PutRelation(ctx, &store.Relation{Uid: 1, Peer: 3, Type: store.RelationBlock}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteRelation(ctx, 1, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteRelation(ctx, 1, 5); err != store.ErrNotFound {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+
+	check := func(s store.Store) {
+		r, err := s.GetRelation(ctx, 1, 2)
+		if err != nil || r.Type != store.RelationFriend || r.Remark != "two" {
+			t.Fatalf("unexpected relation %+v, %v", r, err)
+		}
+		//关系是单向的
+		if _, err = s.GetRelation(ctx, 3, 1); err != store.ErrNotFound {
+			t.Fatalf("want ErrNotFound, got %v", err)
+		}
+		peers := func(typ int32) string {
+			relations, err := s.ListRelations(ctx, 1, typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := make([]uint64, 0, len(relations))
+			for _, r := range relations {
+				result = append(result, r.Peer)
+			}
+			return fmt.Sprint(result)
+		}
+		if got := peers(0); got != "[2 3 4]" {
+			t.Fatalf("unexpected peers %s", got)
+		}
+		if got := peers(store.RelationFriend); got != "[2]" {
+			t.Fatalf("unexpected friends %s", got)
+		}
+		if got := peers(store.RelationBlock); got != "[3 4]" {
+			t.Fatalf("unexpected blocked %s", got)
+		}
+	}
+	check(s)
+	s = reopen(s)
+	check(s)
+	return s
+}
+
+//并发写入时唯一性和分配的id不出错
+func testConcurrent(t *testing.T, s store.Store, reopen func(store.Store) store.Store) store.Store {
+	ctx := context.Background()
+	const n = 20
+	msg := &store.Message{From: 1, To: 2}
+	if err := s.SaveMessage(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		created int
+		uids    = make(map[uint64]bool)
+		seqs    = make(map[uint64]bool)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := s.CreateUser(ctx, &store.User{Username: "same"})
+			user := &store.User{Username: fmt.Sprintf("user%d", i)}
+			if err2 := s.CreateUser(ctx, user); err2 != nil {
+				t.Error(err2)
+			}
+			seq, err3 := s.AppendInbox(ctx, 1, msg.Id)
+			if err3 != nil {
+				t.Error(err3)
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err == nil {
+				created++
+			} else if err != store.ErrExists {
+				t.Error(err)
+			}
+			uids[user.Uid] = true
+			seqs[seq] = true
+		}(i)
+	}
+	wg.Wait()
+	if created != 1 {
+		t.Fatalf("same username created %d times", created)
+	}
+	if len(uids) != n || len(seqs) != n {
+		t.Fatalf("duplicate ids: %d uids, %d seqs", len(uids), len(seqs))
+	}
+	s = reopen(s)
+	if last, _, err := s.InboxState(ctx, 1); err != nil || last != n {
+		t.Fatalf("unexpected last seq %d, %v", last, err)
+	}
+	return s
+}
+
+func testClosed(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("close twice: %v", err)
+	}
+	if err := s.CreateUser(ctx, &store.User{Username: "alice"}); err != store.ErrClosed {
+		t.Fatalf("want ErrClosed, got %v", err)
+	}
+	if _, err := s.GetUser(ctx, 1); err != store.ErrClosed {
+		t.Fatalf("want ErrClosed, got %v", err)
+	}
+	if _, err := s.ListInbox(ctx, 1, 0, 0); err != store.ErrClosed {
+		t.Fatalf("want ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	commconf "github.com/imkuqin-zw/ZWChat/common/config"
+	"github.com/imkuqin-zw/ZWChat/dispatch/server"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	confPath string
+	Conf     *Config
+)
+
+type Config struct {
+	Http             *commconf.Server                 `yaml:"http"` //客户端查询接入节点的http接口
+	RpcServer        *commconf.Server                 `yaml:"rpcServer"`
+	Path             *commconf.Path                   `yaml:"path"`
+	ServiceDiscovery *commconf.ServiceDiscoveryClient `yaml:"serviceDiscovery"` //serverName为接入服务的名称
+	Etcd             *commconf.Etcd                   `yaml:"etcd"`
+	Log              *zap.Config                      `yaml:"log"`
+	Dispatch         *server.Cfg                      `yaml:"dispatch"`
+}
+
+func init() {
+	flag.StringVar(&confPath, "conf", "./dispatch.yaml", "config path")
+}
+
+func Init() (err error) {
+	var configBody []byte
+	configBody, err = ioutil.ReadFile(confPath)
+	if err != nil {
+		return
+	}
+	Conf = &Config{}
+	if err = yaml.Unmarshal(configBody, Conf); err != nil {
+		return
+	}
+	if Conf.Dispatch == nil {
+		Conf.Dispatch = &server.Cfg{}
+	}
+	Conf.Path = &commconf.Path{}
+	Conf.Path.Root, err = filepath.Abs(filepath.Dir(os.Args[0]))
+	if err != nil {
+		return
+	}
+	return
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/dispatch/config"
+	"github.com/imkuqin-zw/ZWChat/dispatch/server"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+	//注册etcd后端
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery/etcd"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+func main() {
+	flag.Parse()
+	sd := config.Conf.ServiceDiscovery
+	if sd == nil {
+		logger.Fatal("serviceDiscovery not configured")
+		return
+	}
+	discovery, err := service_discovery.New(service_discovery.Cfg{
+		Backend: sd.Backend, Target: sd.Target, Reload: sd.Reload,
+	})
+	if err != nil {
+		logger.Fatal("service discovery", zap.String("backend", sd.Backend), zap.Error(err))
+		return
+	}
+	defer discovery.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := discovery.Watch(ctx, sd.ServerName)
+	if err != nil {
+		logger.Fatal("watch access", zap.String("serverName", sd.ServerName), zap.Error(err))
+		return
+	}
+	selector := server.NewSelector(*config.Conf.Dispatch)
+	go selector.Run(ch)
+	if cfg := config.Conf.Http; cfg != nil && cfg.Addr != "" {
+		go func() {
+			if err := http.ListenAndServe(cfg.Addr, selector.Handler()); err != nil {
+				logger.Fatal("http serve", zap.String("addr", cfg.Addr), zap.Error(err))
+			}
+		}()
+		logger.Info("http init success", zap.String("addr", cfg.Addr))
+	}
+	if cfg := config.Conf.RpcServer; cfg != nil && cfg.Addr != "" {
+		rpcServer := server.NewRPCServer(selector)
+		if err = rpcServer.Serve(rpcNetwork(cfg.Proto), cfg.Addr); err != nil {
+			return
+		}
+		defer rpcServer.Stop()
+		logger.Info("rpc init success", zap.String("addr", cfg.Addr))
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+}
+
+func rpcNetwork(proto string) string {
+	if proto == "" {
+		return "tcp"
+	}
+	return proto
+}
+
+func init() {
+	if err := config.Init(); err != nil {
+		panic(err)
+	}
+	if config.Conf.Etcd != nil {
+		etcd.DialTimeout = config.Conf.Etcd.DialTimeout
+		etcd.Prefix = config.Conf.Etcd.Prefix
+	}
+	logger.InitLogger(config.Conf.Log)
+}
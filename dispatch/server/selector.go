@@ -0,0 +1,182 @@
+package server
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/dispatch"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+)
+
+const (
+	defaultLimit  = 3
+	defaultMaxCPU = 0.9
+)
+
+type Cfg struct {
+	Limit  int     `yaml:"limit"`  //每个协议最多返回的地址数
+	MaxCPU float64 `yaml:"maxCPU"` //cpu使用率达到后排在其他节点之后，sticky也不再生效
+}
+
+//按负载为客户端选择接入节点：排除排空中的节点，同区域优先，cpu过高的靠后，
+//其余按连接数/权重从小到大排列。负载每隔几秒才上报一次，期间分配出去的客户端
+//计入pending，避免所有客户端都涌向同一个节点
+type Selector struct {
+	cfg    Cfg
+	mutex  sync.Mutex
+	nodes  []*node
+	sticky map[string]*node
+}
+
+type node struct {
+	ins     *service_discovery.Instance
+	sticky  string
+	pending int64 //上次上报后分配的客户端数
+}
+
+func NewSelector(cfg Cfg) *Selector {
+	if cfg.Limit <= 0 {
+		cfg.Limit = defaultLimit
+	}
+	if cfg.MaxCPU <= 0 {
+		cfg.MaxCPU = defaultMaxCPU
+	}
+	return &Selector{cfg: cfg, sticky: make(map[string]*node)}
+}
+
+//sticky不直接暴露节点的内部地址
+func stickyKey(addr string) string {
+	h := fnv.New64a()
+	h.Write([]byte(addr))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+//消费服务发现的推送，通道关闭后返回
+func (s *Selector) Run(ch <-chan []*service_discovery.Instance) {
+	for instances := range ch {
+		s.Update(instances)
+	}
+}
+
+//负载没有变化的节点保留pending
+func (s *Selector) Update(instances []*service_discovery.Instance) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	nodes := make([]*node, 0, len(instances))
+	sticky := make(map[string]*node, len(instances))
+	for _, ins := range instances {
+		key := stickyKey(ins.Addr)
+		n := &node{ins: ins, sticky: key}
+		if old := s.sticky[key]; old != nil && old.ins.Load == ins.Load {
+			n.pending = old.pending
+		}
+		nodes = append(nodes, n)
+		sticky[key] = n
+	}
+	s.nodes, s.sticky = nodes, sticky
+}
+
+func (s *Selector) overloaded(n *node) bool {
+	return n.ins.Load.CPU >= s.cfg.MaxCPU
+}
+
+func (n *node) score() float64 {
+	return float64(n.ins.Load.Conns+n.pending) / float64(n.ins.GetWeight())
+}
+
+//调用方持有mutex
+func (s *Selector) rank(zone, sticky string) []*node {
+	candidates := make([]*node, 0, len(s.nodes))
+	for _, n := range s.nodes {
+		if !n.ins.Load.Draining {
+			candidates = append(candidates, n)
+		}
+	}
+	mismatch := func(n *node) bool {
+		return zone != "" && n.ins.Zone != zone
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if mismatch(a) != mismatch(b) {
+			return !mismatch(a)
+		}
+		if s.overloaded(a) != s.overloaded(b) {
+			return !s.overloaded(a)
+		}
+		if a.score() != b.score() {
+			return a.score() < b.score()
+		}
+		return a.ins.Addr < b.ins.Addr
+	})
+	//重连时回到原来的节点，不论区域和连接数
+	if n := s.sticky[sticky]; n != nil && !n.ins.Load.Draining && !s.overloaded(n) {
+		for i, c := range candidates {
+			if c == n {
+				copy(candidates[1:i+1], candidates[:i])
+				candidates[0] = n
+				break
+			}
+		}
+	}
+	return candidates
+}
+
+//没有可用的节点时返回ecode.NoAccessServer
+func (s *Selector) Dispatch(req *dispatch.DispatchReq) (*dispatch.DispatchReply, error) {
+	limit := s.cfg.Limit
+	if req.Limit > 0 && int(req.Limit) < limit {
+		limit = int(req.Limit)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	candidates := s.rank(req.Zone, req.Sticky)
+	protos := req.Protos
+	if len(protos) == 0 {
+		protos = allProtos(candidates)
+	}
+	reply := &dispatch.DispatchReply{}
+	var first *node
+	for _, proto := range protos {
+		endpoints := &dispatch.Endpoints{Proto: proto}
+		for _, n := range candidates {
+			addr := n.ins.Endpoints[proto]
+			if addr == "" {
+				continue
+			}
+			if first == nil {
+				first = n
+			}
+			endpoints.Addrs = append(endpoints.Addrs, addr)
+			if len(endpoints.Addrs) >= limit {
+				break
+			}
+		}
+		if len(endpoints.Addrs) > 0 {
+			reply.Endpoints = append(reply.Endpoints, endpoints)
+		}
+	}
+	if first == nil {
+		return nil, ecode.NoAccessServer
+	}
+	first.pending++
+	reply.Sticky = first.sticky
+	return reply, nil
+}
+
+func allProtos(nodes []*node) []string {
+	set := make(map[string]bool)
+	for _, n := range nodes {
+		for proto := range n.ins.Endpoints {
+			set[proto] = true
+		}
+	}
+	protos := make([]string, 0, len(set))
+	for proto := range set {
+		protos = append(protos, proto)
+	}
+	sort.Strings(protos)
+	return protos
+}
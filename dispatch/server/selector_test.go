@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/dispatch"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+)
+
+func instance(addr, zone string, conns int64) *service_discovery.Instance {
+	return &service_discovery.Instance{
+		Addr:      addr,
+		Zone:      zone,
+		Load:      service_discovery.Load{Conns: conns},
+		Endpoints: map[string]string{"tcp": addr + ":tcp", "ws": addr + ":ws"},
+	}
+}
+
+func first(t *testing.T, s *Selector, req *dispatch.DispatchReq) (string, string) {
+	t.Helper()
+	reply, err := s.Dispatch(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reply.Endpoints[0].Addrs[0], reply.Sticky
+}
+
+func TestSelector(t *testing.T) {
+	s := NewSelector(Cfg{Limit: 2})
+	if _, err := s.Dispatch(&dispatch.DispatchReq{}); err != ecode.NoAccessServer {
+		t.Fatalf("want NoAccessServer, got %v", err)
+	}
+	a, b, c := instance("a", "sh", 10), instance("b", "sh", 5), instance("c", "bj", 0)
+	c.Load.CPU = 0.95
+	s.Update([]*service_discovery.Instance{a, b, c})
+
+	reply, err := s.Dispatch(&dispatch.DispatchReq{Protos: []string{"ws", "quic"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//c的cpu过高排在最后，quic没有节点支持
+	if len(reply.Endpoints) != 1 || reply.Endpoints[0].Proto != "ws" ||
+		len(reply.Endpoints[0].Addrs) != 2 || reply.Endpoints[0].Addrs[0] != "b:ws" || reply.Endpoints[0].Addrs[1] != "a:ws" {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+
+	//分配出去的客户端计入pending，b达到a的连接数后轮到a
+	for i := 0; i < 4; i++ {
+		first(t, s, &dispatch.DispatchReq{Protos: []string{"tcp"}})
+	}
+	if addr, _ := first(t, s, &dispatch.DispatchReq{Protos: []string{"tcp"}}); addr != "a:tcp" {
+		t.Fatalf("want a, got %s", addr)
+	}
+	//重新上报负载后pending清零，负载不变时保留
+	s.Update([]*service_discovery.Instance{a, instance("b", "sh", 5), c})
+	first(t, s, &dispatch.DispatchReq{Protos: []string{"tcp"}})
+	if addr, _ := first(t, s, &dispatch.DispatchReq{Protos: []string{"tcp"}}); addr != "a:tcp" {
+		t.Fatalf("want a, got %s", addr)
+	}
+	b2 := instance("b", "sh", 8)
+	s.Update([]*service_discovery.Instance{a, b2, c})
+	addr, sticky := first(t, s, &dispatch.DispatchReq{Protos: []string{"tcp"}})
+	if addr != "b:tcp" {
+		t.Fatalf("want b, got %s", addr)
+	}
+
+	//同区域优先
+	c.Load.CPU = 0
+	s.Update([]*service_discovery.Instance{a, b2, c})
+	if addr, _ := first(t, s, &dispatch.DispatchReq{Zone: "sh"}); addr != "b:tcp" {
+		t.Fatalf("want b, got %s", addr)
+	}
+	if addr, _ := first(t, s, &dispatch.DispatchReq{Zone: "bj"}); addr != "c:tcp" {
+		t.Fatalf("want c, got %s", addr)
+	}
+
+	//sticky的节点可用时排在第一位，排空后失效
+	if addr, _ := first(t, s, &dispatch.DispatchReq{Sticky: sticky, Zone: "bj"}); addr != "b:tcp" {
+		t.Fatalf("want sticky b, got %s", addr)
+	}
+	b3 := instance("b", "sh", 5)
+	b3.Load.Draining = true
+	s.Update([]*service_discovery.Instance{a, b3, c})
+	reply, err = s.Dispatch(&dispatch.DispatchReq{Sticky: sticky, Protos: []string{"tcp"}, Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs := reply.Endpoints[0].Addrs; len(addrs) != 2 || addrs[0] != "c:tcp" || addrs[1] != "a:tcp" {
+		t.Fatalf("unexpected addrs %v", addrs)
+	}
+	if reply.Sticky == sticky {
+		t.Fatal("sticky should point to the new node")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s := NewSelector(Cfg{})
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/dispatch?uid=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("want 503, got %d", resp.StatusCode)
+	}
+	if resp, err = http.Get(server.URL + "/dispatch?uid=x"); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("want 400, got %d", resp.StatusCode)
+	}
+
+	s.Update([]*service_discovery.Instance{instance("a", "", 0)})
+	if resp, err = http.Get(server.URL + "/dispatch?protos=tcp,ws&limit=1"); err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	reply := &dispatch.DispatchReply{}
+	if err = json.NewDecoder(resp.Body).Decode(reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Endpoints) != 2 || reply.Endpoints[1].Addrs[0] != "a:ws" || reply.Sticky != stickyKey("a") {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/dispatch"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+//对客户端提供的http接口:
+//
+//	GET /dispatch?uid=1&zone=sh&protos=tcp,ws&sticky=xxx&limit=3
+//
+//返回DispatchReply的json，没有可用的接入节点时返回503
+func (s *Selector) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dispatch", s.handleDispatch)
+	return mux
+}
+
+func (s *Selector) handleDispatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	req := &dispatch.DispatchReq{Zone: query.Get("zone"), Sticky: query.Get("sticky")}
+	var err error
+	if uid := query.Get("uid"); uid != "" {
+		if req.Uid, err = strconv.ParseUint(uid, 10, 64); err != nil {
+			http.Error(w, "invalid uid", http.StatusBadRequest)
+			return
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var n int64
+		if n, err = strconv.ParseInt(limit, 10, 32); err != nil || n < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		req.Limit = int32(n)
+	}
+	for _, proto := range strings.Split(query.Get("protos"), ",") {
+		if proto = strings.TrimSpace(proto); proto != "" {
+			req.Protos = append(req.Protos, proto)
+		}
+	}
+	reply, err := s.Dispatch(req)
+	if err != nil {
+		logger.Warn("dispatch", zap.Uint64("uid", req.Uid), zap.String("zone", req.Zone), zap.Error(err))
+		http.Error(w, ecode.From(err).String(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(reply); err != nil {
+		logger.Error("dispatch writeJson", zap.Error(err))
+	}
+}
+
+//gRPC接口，错误为ecode
+type DispatchServer struct {
+	selector *Selector
+}
+
+func NewDispatchServer(selector *Selector) *DispatchServer {
+	return &DispatchServer{selector: selector}
+}
+
+func (s *DispatchServer) Dispatch(ctx context.Context, req *dispatch.DispatchReq) (*dispatch.DispatchReply, error) {
+	return s.selector.Dispatch(req)
+}
+
+type RPCServer struct {
+	server *grpc.Server
+}
+
+func NewRPCServer(selector *Selector) *RPCServer {
+	server := grpc.NewServer()
+	dispatch.RegisterDispatchServer(server, NewDispatchServer(selector))
+	return &RPCServer{server: server}
+}
+
+func (s *RPCServer) Serve(network, addr string) error {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		logger.Error("rpc listen", zap.String("addr", addr), zap.Error(err))
+		return err
+	}
+	go func() {
+		if err := s.server.Serve(l); err != nil {
+			logger.Debug("rpc serve", zap.String("addr", addr), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (s *RPCServer) Stop() {
+	s.server.GracefulStop()
+}
@@ -0,0 +1,35 @@
+package cpu
+
+import "sync"
+
+//计算两次调用之间整机的cpu使用率，可以并发调用
+type Sampler struct {
+	mutex sync.Mutex
+	idle  uint64
+	total uint64
+}
+
+func NewSampler() *Sampler {
+	s := &Sampler{}
+	s.idle, s.total, _ = times()
+	return s
+}
+
+//返回0~1，不支持的平台或读取失败时返回0
+func (s *Sampler) Usage() float64 {
+	idle, total, err := times()
+	if err != nil {
+		return 0
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if total <= s.total {
+		return 0
+	}
+	usage := 1 - float64(idle-s.idle)/float64(total-s.total)
+	s.idle, s.total = idle, total
+	if usage < 0 {
+		return 0
+	}
+	return usage
+}
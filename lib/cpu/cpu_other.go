@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package cpu
+
+import "errors"
+
+func times() (idle, total uint64, err error) {
+	return 0, 0, errors.New("cpu: not supported")
+}
@@ -0,0 +1,20 @@
+package cpu
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only linux is supported")
+	}
+	s := NewSampler()
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for time.Now().Before(deadline) {
+	}
+	if usage := s.Usage(); usage <= 0 || usage > 1 {
+		t.Fatalf("unexpected usage %v", usage)
+	}
+}
@@ -0,0 +1,111 @@
+package dynconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+)
+
+var UnknownKeyErr = errors.New("[dynconf] unknown key")
+
+//校验并应用一项配置，value为nil时表示覆盖被删除，恢复为文件中的配置。
+//返回错误时不能有任何修改
+type Handler func(value []byte) error
+
+//在文件配置之上叠加的动态配置，每一项由key区分，value的格式由Handler决定。
+//应用成功的修改写入审计日志
+type Dynamic struct {
+	mutex    sync.Mutex
+	handlers map[string]Handler
+	values   map[string][]byte //当前生效的覆盖
+	audit    io.Writer
+}
+
+//审计日志的一行，json格式
+type Record struct {
+	Time     string `json:"time"`
+	Key      string `json:"key"`
+	Old      string `json:"old"` //为空时之前使用文件中的配置
+	New      string `json:"new"` //为空时恢复为文件中的配置
+	Revision int64  `json:"revision"`
+}
+
+//audit为nil时不写审计日志
+func New(audit io.Writer) *Dynamic {
+	return &Dynamic{
+		handlers: make(map[string]Handler),
+		values:   make(map[string][]byte),
+		audit:    audit,
+	}
+}
+
+//需要在Apply之前注册
+func (d *Dynamic) Register(key string, handler Handler) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.handlers[key] = handler
+}
+
+//与当前生效的值相同时不做任何事，rev为etcd的版本，只用于审计
+func (d *Dynamic) Apply(key string, value []byte, rev int64) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	handler := d.handlers[key]
+	if handler == nil {
+		return UnknownKeyErr
+	}
+	old, exist := d.values[key]
+	if (value == nil && !exist) || (value != nil && exist && bytes.Equal(old, value)) {
+		return nil
+	}
+	if err := handler(value); err != nil {
+		return fmt.Errorf("[dynconf] %s: %v", key, err)
+	}
+	if value == nil {
+		delete(d.values, key)
+	} else {
+		d.values[key] = append([]byte(nil), value...)
+	}
+	logger.Info("dynamic config applied", zap.String("key", key), zap.String("value", string(value)),
+		zap.Int64("revision", rev))
+	d.writeAudit(&Record{
+		Time:     time.Now().Format(time.RFC3339),
+		Key:      key,
+		Old:      string(old),
+		New:      string(value),
+		Revision: rev,
+	})
+	return nil
+}
+
+//当前生效的覆盖，不包含文件中的配置
+func (d *Dynamic) Values() map[string]string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	values := make(map[string]string, len(d.values))
+	for key, value := range d.values {
+		values[key] = string(value)
+	}
+	return values
+}
+
+//调用方持有mutex
+func (d *Dynamic) writeAudit(record *Record) {
+	if d.audit == nil {
+		return
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	if _, err = d.audit.Write(append(line, '\n')); err != nil {
+		logger.Error("dynamic config audit", zap.Error(err))
+	}
+}
@@ -0,0 +1,128 @@
+package dynconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/imkuqin-zw/ZWChat/lib/etcd_lib"
+)
+
+func TestDynamic(t *testing.T) {
+	audit := &bytes.Buffer{}
+	d := New(audit)
+	applied := []string{}
+	d.Register("logLevel", func(value []byte) error {
+		if value == nil {
+			applied = append(applied, "file")
+			return nil
+		}
+		if string(value) == "bad" {
+			return errors.New("bad level")
+		}
+		applied = append(applied, string(value))
+		return nil
+	})
+
+	if err := d.Apply("unknown", []byte("x"), 1); err != UnknownKeyErr {
+		t.Fatalf("want UnknownKeyErr, got %v", err)
+	}
+	//没有覆盖时删除不做任何事
+	if err := d.Apply("logLevel", nil, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Apply("logLevel", []byte("info"), 3); err != nil {
+		t.Fatal(err)
+	}
+	//相同的值和不合法的值都不应用
+	if err := d.Apply("logLevel", []byte("info"), 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Apply("logLevel", []byte("bad"), 5); err == nil {
+		t.Fatal("invalid value should fail")
+	}
+	if values := d.Values(); values["logLevel"] != "info" {
+		t.Fatalf("unexpected values %v", values)
+	}
+	if err := d.Apply("logLevel", nil, 6); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Values()) != 0 {
+		t.Fatalf("unexpected values %v", d.Values())
+	}
+	if strings.Join(applied, ",") != "info,file" {
+		t.Fatalf("unexpected applied %v", applied)
+	}
+
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 audit records, got %q", audit.String())
+	}
+	records := make([]Record, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r := records[0]; r.Key != "logLevel" || r.Old != "" || r.New != "info" || r.Revision != 3 || r.Time == "" {
+		t.Fatalf("unexpected record %+v", r)
+	}
+	if r := records[1]; r.Old != "info" || r.New != "" || r.Revision != 6 {
+		t.Fatalf("unexpected record %+v", r)
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	if prefix := KeyPrefix("zw_chat", "access_server"); prefix != "/zw_chat/config/access_server/" {
+		t.Fatalf("unexpected prefix %s", prefix)
+	}
+}
+
+//重新全量读取时，watch中断期间被删除的配置项恢复为文件中的配置
+func TestWatcherReload(t *testing.T) {
+	d := New(nil)
+	applied := []string{}
+	for _, key := range []string{"a", "b"} {
+		key := key
+		d.Register(key, func(value []byte) error {
+			if value == nil {
+				applied = append(applied, key+"=file")
+			} else {
+				applied = append(applied, key+"="+string(value))
+			}
+			return nil
+		})
+	}
+	prefix := KeyPrefix("zw", "access")
+	w := &Watcher{prefix: prefix, dynamic: d, keys: make(map[string]bool)}
+	event := func(typ etcd_lib.EventType, key, value string, rev int64) {
+		ev := etcd_lib.Event{Type: typ, Revision: rev}
+		if key != "" {
+			ev.Key = prefix + key
+		}
+		if value != "" {
+			ev.Value = []byte(value)
+		}
+		w.onEvent(ev)
+	}
+	event(etcd_lib.EventReset, "", "", 1)
+	event(etcd_lib.EventPut, "a", "1", 1)
+	event(etcd_lib.EventPut, "b", "1", 1)
+	event(etcd_lib.EventSynced, "", "", 1)
+	event(etcd_lib.EventPut, "a", "2", 2)
+	event(etcd_lib.EventSynced, "", "", 2)
+	//b在watch中断期间被删除
+	event(etcd_lib.EventReset, "", "", 4)
+	event(etcd_lib.EventPut, "a", "2", 2)
+	event(etcd_lib.EventSynced, "", "", 4)
+	event(etcd_lib.EventDelete, "a", "", 5)
+	event(etcd_lib.EventSynced, "", "", 5)
+	if got := strings.Join(applied, ","); got != "a=1,b=1,a=2,b=file,a=file" {
+		t.Fatalf("unexpected applied %s", got)
+	}
+	if len(w.keys) != 0 || w.loading != nil {
+		t.Fatalf("unexpected keys %v", w.keys)
+	}
+}
@@ -0,0 +1,103 @@
+package dynconf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	etcdv3 "github.com/coreos/etcd/clientv3"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/lib/etcd_lib"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+type Cfg struct {
+	Target      string        `yaml:"target"`      //etcd地址，多个用逗号分隔
+	Prefix      string        `yaml:"prefix"`      //etcd的key前缀
+	Name        string        `yaml:"name"`        //服务名，同名的服务共用一组配置
+	DialTimeout time.Duration `yaml:"dialTimeout"` //连接etcd的超时
+	Audit       string        `yaml:"audit"`       //审计日志的路径，为空时不写
+}
+
+//配置项的key为/{prefix}/config/{name}/{配置项}
+func KeyPrefix(prefix, name string) string {
+	return fmt.Sprintf("/%s/config/%s/", prefix, name)
+}
+
+//监听etcd中的配置项并应用到Dynamic。
+//watch中断后重新全量读取，期间被删除的配置项恢复为文件中的配置
+type Watcher struct {
+	client  *etcdv3.Client
+	prefix  string
+	dynamic *Dynamic
+	watcher *etcd_lib.Watcher
+	keys    map[string]bool //etcd中存在的配置项，只在watch协程中访问
+	loading map[string]bool //全量读取中读到的配置项，不在读取时为nil
+}
+
+//返回前完成第一次读取，启动时的配置已经合并；读取失败时返回错误
+func NewWatcher(cfg Cfg, dynamic *Dynamic) (*Watcher, error) {
+	client, err := etcdv3.New(etcdv3.Config{
+		Endpoints:   strings.Split(cfg.Target, ","),
+		DialTimeout: cfg.DialTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		client:  client,
+		prefix:  KeyPrefix(cfg.Prefix, cfg.Name),
+		dynamic: dynamic,
+		keys:    make(map[string]bool),
+	}
+	if w.watcher, err = etcd_lib.WatchLoaded(context.Background(), client, w.prefix, w.onEvent); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+//等待watch协程退出后关闭client
+func (w *Watcher) Close() error {
+	w.watcher.Close()
+	return w.client.Close()
+}
+
+func (w *Watcher) onEvent(ev etcd_lib.Event) {
+	switch ev.Type {
+	case etcd_lib.EventReset:
+		w.loading = make(map[string]bool)
+	case etcd_lib.EventPut:
+		w.apply(ev.Key, ev.Value, ev.Revision, false)
+		if w.loading != nil {
+			w.loading[ev.Key] = true
+		} else {
+			w.keys[ev.Key] = true
+		}
+	case etcd_lib.EventDelete:
+		w.apply(ev.Key, nil, ev.Revision, true)
+		delete(w.keys, ev.Key)
+	case etcd_lib.EventSynced:
+		if w.loading == nil {
+			return
+		}
+		for key := range w.keys {
+			if !w.loading[key] {
+				w.apply(key, nil, ev.Revision, true)
+			}
+		}
+		w.keys, w.loading = w.loading, nil
+	}
+}
+
+//删除时以nil应用，恢复为文件中的配置，写入的空值转换为非nil。不合法的值只记录日志，继续使用之前的配置
+func (w *Watcher) apply(key string, value []byte, rev int64, deleted bool) {
+	if !deleted && value == nil {
+		value = []byte{}
+	}
+	if err := w.dynamic.Apply(strings.TrimPrefix(key, w.prefix), value, rev); err != nil {
+		logger.Error("dynamic config rejected", zap.String("key", key), zap.String("value", string(value)),
+			zap.Int64("revision", rev), zap.Error(err))
+	}
+}
@@ -0,0 +1,200 @@
+package etcd_lib
+
+import (
+	"sync"
+	"time"
+
+	etcdv3 "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+const (
+	retryMinGap = 100 * time.Millisecond
+	retryMaxGap = 5 * time.Second
+)
+
+//一个进程写入的key共用一个租约，进程崩溃后在ttl内过期。
+//租约失效(如与etcd长时间断开)或写入失败后在后台重新申请租约，并把记录的key写回，直到成功或关闭
+type LeaseKeeper struct {
+	client    *etcdv3.Client
+	ttl       time.Duration
+	name      string //日志中区分使用方
+	ctx       context.Context
+	cancel    context.CancelFunc
+	mutex     sync.Mutex
+	lease     etcdv3.LeaseID    //为0时下次写入前申请
+	values    map[string]string //写回时使用最新的值
+	restoring bool
+}
+
+//ttl不足1s时按1s，Close时不关闭client
+func NewLeaseKeeper(client *etcdv3.Client, ttl time.Duration, name string) *LeaseKeeper {
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	k := &LeaseKeeper{client: client, ttl: ttl, name: name, values: make(map[string]string)}
+	k.ctx, k.cancel = context.WithCancel(context.Background())
+	return k
+}
+
+func (k *LeaseKeeper) TTL() time.Duration {
+	return k.ttl
+}
+
+//共用租约的使用方通过它读写etcd
+func (k *LeaseKeeper) Client() *etcdv3.Client {
+	return k.client
+}
+
+//写入并记录，重复写入时覆盖原来的值。失败时返回错误并在后台写回
+func (k *LeaseKeeper) Put(ctx context.Context, key, value string) error {
+	k.mutex.Lock()
+	k.values[key] = value
+	k.mutex.Unlock()
+	lease, err := k.getLease(ctx)
+	if err == nil {
+		_, err = k.client.Put(ctx, key, value, etcdv3.WithLease(lease))
+		//租约已过期但keepalive还没有通知，重新申请租约
+		if err == rpctypes.ErrLeaseNotFound {
+			k.resetLease(lease)
+		}
+	}
+	if err != nil {
+		k.retry()
+	}
+	return err
+}
+
+//删除后不再写回
+func (k *LeaseKeeper) Delete(ctx context.Context, key string) error {
+	k.mutex.Lock()
+	delete(k.values, key)
+	k.mutex.Unlock()
+	_, err := k.client.Delete(ctx, key)
+	return err
+}
+
+//撤销租约，写入的key立即删除
+func (k *LeaseKeeper) Close() {
+	k.cancel()
+	k.mutex.Lock()
+	lease := k.lease
+	k.lease = 0
+	k.mutex.Unlock()
+	if lease != 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		k.client.Revoke(ctx, lease)
+		cancel()
+	}
+}
+
+func (k *LeaseKeeper) getLease(ctx context.Context) (etcdv3.LeaseID, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if k.lease != 0 {
+		return k.lease, nil
+	}
+	if err := k.ctx.Err(); err != nil {
+		return 0, err
+	}
+	resp, err := k.client.Grant(ctx, int64(k.ttl/time.Second))
+	if err != nil {
+		return 0, err
+	}
+	ch, err := k.client.KeepAlive(k.ctx, resp.ID)
+	if err != nil {
+		return 0, err
+	}
+	k.lease = resp.ID
+	go k.keepAlive(resp.ID, ch)
+	return resp.ID, nil
+}
+
+//keepalive的通道关闭说明租约已过期或已关闭
+func (k *LeaseKeeper) keepAlive(lease etcdv3.LeaseID, ch <-chan *etcdv3.LeaseKeepAliveResponse) {
+	for range ch {
+	}
+	if k.ctx.Err() == nil && k.resetLease(lease) {
+		logger.Warn("etcd lease lost, restoring", zap.String("keeper", k.name), zap.Int64("lease", int64(lease)))
+		k.retry()
+	}
+}
+
+//只有当前租约才清除，避免重复恢复
+func (k *LeaseKeeper) resetLease(lease etcdv3.LeaseID) bool {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if k.lease != lease {
+		return false
+	}
+	k.lease = 0
+	return true
+}
+
+//同一时间只有一个restore
+func (k *LeaseKeeper) retry() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if k.restoring || k.ctx.Err() != nil {
+		return
+	}
+	k.restoring = true
+	go k.restore()
+}
+
+//重新申请租约并写回记录的key，直到成功或关闭
+func (k *LeaseKeeper) restore() {
+	defer func() {
+		k.mutex.Lock()
+		k.restoring = false
+		k.mutex.Unlock()
+	}()
+	gap := retryMinGap
+	for k.ctx.Err() == nil {
+		err := k.putAll()
+		if err == nil {
+			return
+		}
+		logger.Error("etcd lease restore", zap.String("keeper", k.name), zap.Duration("retry", gap), zap.Error(err))
+		select {
+		case <-k.ctx.Done():
+			return
+		case <-time.After(gap):
+		}
+		if gap *= 2; gap > retryMaxGap {
+			gap = retryMaxGap
+		}
+	}
+}
+
+func (k *LeaseKeeper) putAll() error {
+	lease, err := k.getLease(k.ctx)
+	if err != nil {
+		return err
+	}
+	k.mutex.Lock()
+	keys := make([]string, 0, len(k.values))
+	for key := range k.values {
+		keys = append(keys, key)
+	}
+	k.mutex.Unlock()
+	for _, key := range keys {
+		//写回期间已经删除的key跳过
+		k.mutex.Lock()
+		value, ok := k.values[key]
+		k.mutex.Unlock()
+		if !ok {
+			continue
+		}
+		if _, err = k.client.Put(k.ctx, key, value, etcdv3.WithLease(lease)); err != nil {
+			if err == rpctypes.ErrLeaseNotFound {
+				k.resetLease(lease)
+			}
+			return err
+		}
+	}
+	return nil
+}
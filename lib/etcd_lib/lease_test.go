@@ -0,0 +1,42 @@
+package etcd_lib
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+//关闭后不再申请租约，写入直接失败且不在后台写回
+func TestLeaseKeeperClosed(t *testing.T) {
+	k := NewLeaseKeeper(nil, 0, "test")
+	if k.ttl != time.Second {
+		t.Fatalf("want ttl 1s, got %v", k.ttl)
+	}
+	k.Close()
+	if err := k.Put(context.Background(), "/a", "1"); err != context.Canceled {
+		t.Fatalf("want Canceled, got %v", err)
+	}
+	k.mutex.Lock()
+	restoring, lease := k.restoring, k.lease
+	k.mutex.Unlock()
+	if restoring || lease != 0 {
+		t.Fatal("want no restore after close")
+	}
+}
+
+//只有当前租约才清除
+func TestLeaseKeeperResetLease(t *testing.T) {
+	k := NewLeaseKeeper(nil, 2*time.Second, "test")
+	defer k.cancel()
+	k.lease = 1
+	if k.resetLease(2) || k.lease != 1 {
+		t.Fatal("want other lease ignored")
+	}
+	if !k.resetLease(1) || k.lease != 0 {
+		t.Fatal("want current lease reset")
+	}
+	if k.resetLease(1) {
+		t.Fatal("want reset once")
+	}
+}
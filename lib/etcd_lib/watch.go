@@ -0,0 +1,145 @@
+package etcd_lib
+
+import (
+	"fmt"
+	"time"
+
+	etcdv3 "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+const loadTimeout = 3 * time.Second
+
+type EventType int8
+
+const (
+	EventReset  EventType = iota //开始全量读取，之前的状态作废
+	EventPut                     //写入或全量读取到的key
+	EventDelete                  //删除
+	EventSynced                  //全量读取或一次监听到的变化通知完成
+)
+
+//Revision在写入时为ModRevision，删除时为删除的版本，Reset和Synced时为读取或监听到的版本
+type Event struct {
+	Type     EventType
+	Key      string
+	Value    []byte
+	Revision int64
+}
+
+//先全量读取再从下一个版本开始监听前缀下的key，整个生命周期只有一个watch。
+//watch中断(如版本被压缩)时重新全量读取，直到成功或关闭
+type Watcher struct {
+	client  *etcdv3.Client
+	prefix  string
+	onEvent func(ev Event) //只在watch协程中调用
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+//在后台完成第一次读取，parent结束时watch也结束
+func Watch(parent context.Context, client *etcdv3.Client, prefix string, onEvent func(ev Event)) *Watcher {
+	w := newWatcher(parent, client, prefix, onEvent)
+	go w.watch(0)
+	return w
+}
+
+//返回前完成第一次读取，读取失败时返回错误
+func WatchLoaded(parent context.Context, client *etcdv3.Client, prefix string, onEvent func(ev Event)) (*Watcher, error) {
+	w := newWatcher(parent, client, prefix, onEvent)
+	rev, err := w.load()
+	if err != nil {
+		w.cancel()
+		return nil, err
+	}
+	go w.watch(rev)
+	return w, nil
+}
+
+func newWatcher(parent context.Context, client *etcdv3.Client, prefix string, onEvent func(ev Event)) *Watcher {
+	w := &Watcher{client: client, prefix: prefix, onEvent: onEvent, done: make(chan struct{})}
+	w.ctx, w.cancel = context.WithCancel(parent)
+	return w
+}
+
+//返回后不再调用onEvent
+func (w *Watcher) Close() {
+	w.cancel()
+	<-w.done
+}
+
+//rev为0时先全量读取
+func (w *Watcher) watch(rev int64) {
+	defer close(w.done)
+	gap := retryMinGap
+	for w.ctx.Err() == nil {
+		var err error
+		if rev == 0 {
+			rev, err = w.load()
+		}
+		if err == nil {
+			gap = retryMinGap
+			err = w.watchFrom(rev + 1)
+			rev = 0
+		}
+		if w.ctx.Err() != nil {
+			return
+		}
+		logger.Error("etcd watch", zap.String("prefix", w.prefix), zap.Duration("retry", gap), zap.Error(err))
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-time.After(gap):
+		}
+		if gap *= 2; gap > retryMaxGap {
+			gap = retryMaxGap
+		}
+	}
+}
+
+func (w *Watcher) load() (int64, error) {
+	ctx, cancel := context.WithTimeout(w.ctx, loadTimeout)
+	defer cancel()
+	resp, err := w.client.Get(ctx, w.prefix, etcdv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+	rev := resp.Header.Revision
+	w.onEvent(Event{Type: EventReset, Revision: rev})
+	for _, kv := range resp.Kvs {
+		w.onEvent(Event{Type: EventPut, Key: string(kv.Key), Value: kv.Value, Revision: kv.ModRevision})
+	}
+	w.onEvent(Event{Type: EventSynced, Revision: rev})
+	return rev, nil
+}
+
+//返回时watch已中断
+func (w *Watcher) watchFrom(rev int64) error {
+	ctx, cancel := context.WithCancel(w.ctx)
+	defer cancel()
+	for resp := range w.client.Watch(ctx, w.prefix, etcdv3.WithPrefix(), etcdv3.WithRev(rev)) {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range resp.Events {
+			event := Event{Key: string(ev.Kv.Key), Revision: ev.Kv.ModRevision}
+			switch ev.Type {
+			case mvccpb.PUT:
+				event.Type, event.Value = EventPut, ev.Kv.Value
+			case mvccpb.DELETE:
+				event.Type = EventDelete
+			default:
+				continue
+			}
+			w.onEvent(event)
+		}
+		if len(resp.Events) > 0 {
+			w.onEvent(Event{Type: EventSynced, Revision: resp.Header.Revision})
+		}
+	}
+	return fmt.Errorf("watch closed")
+}
@@ -0,0 +1,108 @@
+package net_client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"golang.org/x/net/context"
+)
+
+var UnknownCmdErr = errors.New("[client] unknown command")
+
+//服务端回复的external.Error
+type ServerError struct {
+	Cmd  uint32 //出错的请求命令号
+	Code uint32 //ecode
+	Msg  string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("[client] cmd %d error %d: %s", e.Cmd, e.Code, e.Msg)
+}
+
+//按命令表解析服务端的响应或推送，错误回复返回*ServerError
+func Decode(p *net_lib.Envelope) (proto.Message, error) {
+	command := external.Lookup(p.Cmd)
+	if command == nil || command.Resp == nil {
+		return nil, UnknownCmdErr
+	}
+	msg := command.Resp()
+	if err := p.Unmarshal(msg); err != nil {
+		return nil, err
+	}
+	if e, ok := msg.(*external.Error); ok {
+		return nil, &ServerError{Cmd: e.Cmd, Code: e.ErrCoed, Msg: e.ErrMsg}
+	}
+	return msg, nil
+}
+
+//发送命令并把响应解析到resp，服务端回复错误时返回*ServerError
+func (c *Client) Call(ctx context.Context, cmd external.Cmd, req, resp proto.Message) error {
+	p, err := c.Request(ctx, uint32(cmd), req)
+	if err != nil {
+		return err
+	}
+	if p.Cmd == uint32(external.Cmd_ERROR) {
+		_, err = Decode(p)
+		return err
+	}
+	return p.Unmarshal(resp)
+}
+
+//连接建立后协商协议版本，version为空时使用当前版本
+func (c *Client) Handshake(ctx context.Context, req *external.Handshake) (*external.HandshakeAck, error) {
+	if req.Version == external.Version_UNKNOWN_VERSION {
+		req.Version = external.Version_V1
+	}
+	resp := &external.HandshakeAck{}
+	return resp, c.Call(ctx, external.Cmd_HANDSHAKE, req, resp)
+}
+
+func (c *Client) Login(ctx context.Context, uid uint64, token string) (*external.LoginAck, error) {
+	resp := &external.LoginAck{}
+	return resp, c.Call(ctx, external.Cmd_LOGIN, &external.Login{Uid: uid, Token: token}, resp)
+}
+
+func (c *Client) Logout(ctx context.Context) error {
+	return c.Call(ctx, external.Cmd_LOGOUT, &external.Logout{}, &external.LogoutAck{})
+}
+
+func (c *Client) Heartbeat(ctx context.Context) (*external.HeartbeatAck, error) {
+	resp := &external.HeartbeatAck{}
+	return resp, c.Call(ctx, external.Cmd_HEARTBEAT, &external.Heartbeat{ClientTime: nowMillis()}, resp)
+}
+
+func (c *Client) SendP2PMsg(ctx context.Context, msg *external.SendP2PMsg) (*external.SendMsgAck, error) {
+	resp := &external.SendMsgAck{}
+	return resp, c.Call(ctx, external.Cmd_SEND_P2P_MSG, msg, resp)
+}
+
+func (c *Client) SendGroupMsg(ctx context.Context, msg *external.SendGroupMsg) (*external.SendMsgAck, error) {
+	resp := &external.SendMsgAck{}
+	return resp, c.Call(ctx, external.Cmd_SEND_GROUP_MSG, msg, resp)
+}
+
+//确认已收到seq及之前的推送消息，不等待响应
+func (c *Client) AckMsg(seq uint64) error {
+	_, err := c.Send(uint32(external.Cmd_MSG_ACK), &external.MsgAck{Seq: seq})
+	return err
+}
+
+//拉取序号大于offset的消息，hasMore为true时需要继续拉取
+func (c *Client) Sync(ctx context.Context, offset uint64, limit uint32) (*external.SyncAck, error) {
+	resp := &external.SyncAck{}
+	return resp, c.Call(ctx, external.Cmd_SYNC, &external.SyncReq{Offset: offset, Limit: limit}, resp)
+}
+
+func (c *Client) SubscribePresence(ctx context.Context, uids []uint64, unsub bool) (*external.PresenceSubAck, error) {
+	resp := &external.PresenceSubAck{}
+	return resp, c.Call(ctx, external.Cmd_PRESENCE_SUB, &external.PresenceSub{Uids: uids, Unsub: unsub}, resp)
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
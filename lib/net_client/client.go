@@ -0,0 +1,349 @@
+package net_client
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"golang.org/x/net/context"
+)
+
+const defaultMaxMsgSize = 1 << 20
+
+var ClientClosedErr = errors.New("[client] client closed")
+var NotConnectedErr = errors.New("[client] not connected")
+
+type Options struct {
+	Network      string        //tcp、ws或http
+	Addr         string        //服务端地址
+	Path         string        //ws握手和http请求的路径
+	AuthKeyId    []byte        //8字节的authKeyId
+	ShareKey     []byte        //32字节的共享密钥，为空时不加密
+	DialTimeout  time.Duration //连接超时
+	WriteTimeout time.Duration //写超时
+	MaxMsgSize   uint32        //单条消息的最大字节数，默认1M，超过时断开重连
+	LegacyFrame  bool          //tcp使用旧版本的帧格式
+	Compress     []string      //支持的压缩算法，按优先级排列，为空时不压缩
+	//小于该字节数的消息不压缩，默认512
+	CompressThreshold int
+	RecvChanSize      int           //接收通道的大小
+	MinBackoff        time.Duration //重连的初始间隔
+	MaxBackoff        time.Duration //重连的最大间隔
+	MaxRetries        int           //最大连续重连次数，0表示不限制
+	//重连成功后调用，可用于重新登录，返回错误时断开重连
+	OnReconnect func(c *Client) error
+}
+
+func (opts *Options) init() error {
+	if opts.Network == "" {
+		opts.Network = "tcp"
+	}
+	if len(opts.AuthKeyId) == 0 {
+		opts.AuthKeyId = make([]byte, authKeyIdLen)
+	}
+	if len(opts.AuthKeyId) != authKeyIdLen {
+		return errors.New("[client] authKeyId must be 8 bytes")
+	}
+	if len(opts.ShareKey) != 0 && len(opts.ShareKey) != 32 {
+		return ShareKeyLenErr
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+	//长度来自服务端，必须有上限，否则异常的长度会导致分配失败或panic
+	if opts.MaxMsgSize == 0 {
+		opts.MaxMsgSize = defaultMaxMsgSize
+	}
+	if opts.RecvChanSize <= 0 {
+		opts.RecvChanSize = 64
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 100 * time.Millisecond
+	}
+	if opts.CompressThreshold <= 0 {
+		opts.CompressThreshold = 512
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	return nil
+}
+
+type Client struct {
+	opts      Options
+	conn      transport
+	connMutex sync.RWMutex
+	sendMutex sync.Mutex
+	msgId     uint64
+	seqNo     uint32
+	pending   map[uint64]*call //等待响应的请求
+	pendMutex sync.Mutex
+	recvChan  chan *net_lib.Envelope
+	closeFlag int32
+	closeChan chan struct{}
+}
+
+type call struct {
+	req  *net_lib.Envelope
+	done chan *net_lib.Envelope
+}
+
+//连接服务端，连接断开后自动重连，直到调用Close
+func Dial(opts Options) (*Client, error) {
+	if err := opts.init(); err != nil {
+		return nil, err
+	}
+	conn, err := dialTransport(&opts)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		opts:      opts,
+		conn:      conn,
+		pending:   make(map[uint64]*call),
+		recvChan:  make(chan *net_lib.Envelope, opts.RecvChanSize),
+		closeChan: make(chan struct{}),
+	}
+	go c.readLoop(conn)
+	return c, nil
+}
+
+//服务端主动推送的消息，客户端关闭后通道关闭
+func (c *Client) Receive() <-chan *net_lib.Envelope {
+	return c.recvChan
+}
+
+//发送命令，不等待响应，返回消息id
+func (c *Client) Send(cmd uint32, msg proto.Message) (uint64, error) {
+	p, err := c.newEnvelope(cmd, msg)
+	if err != nil {
+		return 0, err
+	}
+	return p.MsgId, c.write(p)
+}
+
+//发送命令并等待AckId为该消息id的响应，断线期间请求会在重连后重发
+func (c *Client) Request(ctx context.Context, cmd uint32, msg proto.Message) (*net_lib.Envelope, error) {
+	p, err := c.newEnvelope(cmd, msg)
+	if err != nil {
+		return nil, err
+	}
+	cl := &call{req: p, done: make(chan *net_lib.Envelope, 1)}
+	c.pendMutex.Lock()
+	c.pending[p.MsgId] = cl
+	c.pendMutex.Unlock()
+	defer func() {
+		c.pendMutex.Lock()
+		delete(c.pending, p.MsgId)
+		c.pendMutex.Unlock()
+	}()
+	if err = c.write(p); err != nil && err != NotConnectedErr {
+		return nil, err
+	}
+	select {
+	case resp := <-cl.done:
+		return resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.closeChan:
+		return nil, ClientClosedErr
+	}
+}
+
+func (c *Client) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closeFlag, 0, 1) {
+		return ClientClosedErr
+	}
+	close(c.closeChan)
+	c.connMutex.Lock()
+	defer c.connMutex.Unlock()
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+func (c *Client) IsClosed() bool {
+	return atomic.LoadInt32(&c.closeFlag) == 1
+}
+
+func (c *Client) newEnvelope(cmd uint32, msg proto.Message) (*net_lib.Envelope, error) {
+	env, err := net_lib.NewEnvelope(cmd, msg)
+	if err != nil {
+		return nil, err
+	}
+	env.MsgId = atomic.AddUint64(&c.msgId, 1)
+	env.SeqNo = atomic.AddUint32(&c.seqNo, 1)
+	return env, nil
+}
+
+func (c *Client) write(p *net_lib.Envelope) error {
+	if c.IsClosed() {
+		return ClientClosedErr
+	}
+	c.connMutex.RLock()
+	conn := c.conn
+	c.connMutex.RUnlock()
+	if conn == nil {
+		return NotConnectedErr
+	}
+	data, flags, err := c.compress(conn, p.Marshal())
+	if err != nil {
+		return err
+	}
+	frame, err := seal(c.opts.AuthKeyId, c.opts.ShareKey, data)
+	if err != nil {
+		return err
+	}
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+	if c.opts.WriteTimeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(c.opts.WriteTimeout))
+		defer conn.SetWriteDeadline(time.Time{})
+	}
+	if err = conn.WriteFrame(frame, flags); err != nil {
+		//写失败时关闭连接，由readLoop负责重连
+		conn.Close()
+	}
+	return err
+}
+
+func (c *Client) readLoop(conn transport) {
+	defer close(c.recvChan)
+	for {
+		frame, flags, err := conn.ReadFrame()
+		if err != nil {
+			conn.Close()
+			if conn = c.reconnect(); conn == nil {
+				return
+			}
+			continue
+		}
+		if flags&net_lib.FlagControl != 0 {
+			//服务端重启或关闭，断开后重连到其他节点或新进程，未响应的请求在重连后重发
+			if _, ok := net_lib.ParseControl(frame[authKeyIdLen+msgKeyLen:])[net_lib.ControlGoAway]; ok {
+				conn.Close()
+			}
+			continue
+		}
+		data, err := open(c.opts.ShareKey, frame)
+		if err != nil {
+			continue
+		}
+		if flags&net_lib.FlagCompressed != 0 {
+			if data, err = c.decompress(conn, data); err != nil {
+				continue
+			}
+		}
+		p, err := net_lib.UnmarshalEnvelope(data)
+		if err != nil {
+			continue
+		}
+		c.dispatch(p)
+	}
+}
+
+//压缩在加密之前进行，压缩后没有变小时不压缩
+func (c *Client) compress(conn transport, data []byte) ([]byte, uint8, error) {
+	compressor := conn.Compressor()
+	if compressor == nil || len(data) < c.opts.CompressThreshold {
+		return data, 0, nil
+	}
+	result, err := compressor.Compress(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(result) >= len(data) {
+		return data, 0, nil
+	}
+	return result, net_lib.FlagCompressed, nil
+}
+
+func (c *Client) decompress(conn transport, data []byte) ([]byte, error) {
+	compressor := conn.Compressor()
+	if compressor == nil {
+		return nil, net_lib.CompressorErr
+	}
+	return compressor.Decompress(data, c.opts.MaxMsgSize)
+}
+
+//AckId对应等待中的请求时交给该请求，其它消息放入接收通道
+func (c *Client) dispatch(p *net_lib.Envelope) {
+	c.pendMutex.Lock()
+	cl, ok := c.pending[p.AckId]
+	if ok {
+		delete(c.pending, p.AckId)
+	}
+	c.pendMutex.Unlock()
+	if ok {
+		cl.done <- p
+		return
+	}
+	select {
+	case c.recvChan <- p:
+	case <-c.closeChan:
+	}
+}
+
+//指数退避重连，成功后恢复会话并重发未响应的请求
+func (c *Client) reconnect() transport {
+	c.connMutex.Lock()
+	c.conn = nil
+	c.connMutex.Unlock()
+	backoff := c.opts.MinBackoff
+	for retries := 1; ; retries++ {
+		if c.opts.MaxRetries > 0 && retries > c.opts.MaxRetries {
+			c.Close()
+			return nil
+		}
+		jitter := time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+		select {
+		case <-time.After(backoff/2 + jitter):
+		case <-c.closeChan:
+			return nil
+		}
+		if backoff *= 2; backoff > c.opts.MaxBackoff {
+			backoff = c.opts.MaxBackoff
+		}
+		conn, err := dialTransport(&c.opts)
+		if err != nil {
+			continue
+		}
+		c.connMutex.Lock()
+		if c.IsClosed() {
+			c.connMutex.Unlock()
+			conn.Close()
+			return nil
+		}
+		c.conn = conn
+		c.connMutex.Unlock()
+		//OnReconnect中的请求需要读循环返回响应，所以异步执行
+		go c.resume(conn)
+		return conn
+	}
+}
+
+//重新登录并重发未响应的请求，失败时关闭连接等待下一次重连
+func (c *Client) resume(conn transport) {
+	if c.opts.OnReconnect != nil {
+		if err := c.opts.OnReconnect(c); err != nil {
+			conn.Close()
+			return
+		}
+	}
+	c.pendMutex.Lock()
+	calls := make([]*call, 0, len(c.pending))
+	for _, cl := range c.pending {
+		calls = append(calls, cl)
+	}
+	c.pendMutex.Unlock()
+	for _, cl := range calls {
+		if err := c.write(cl.req); err != nil {
+			return
+		}
+	}
+}
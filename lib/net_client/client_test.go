@@ -0,0 +1,224 @@
+package net_client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+	"golang.org/x/net/context"
+)
+
+var testShareKey = bytes.Repeat([]byte{7}, 32)
+var testAuthKeyId = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+type handler func(conn int, session *net_lib.Session, env *net_lib.Envelope)
+
+func echo(conn int, session *net_lib.Session, env *net_lib.Envelope) {
+	env.AckId = env.MsgId
+	env.Flags |= net_lib.EnvelopeResponse
+	session.Send(env)
+}
+
+//每个连接一个读协程，conn为连接的序号(从1开始)
+func serve(t *testing.T, encrypt bool, h handler) string {
+	server, err := net_lib.Serve("tcp", "127.0.0.1:0", &net_lib.SessionCfg{
+		MaxMsgSize:        64 << 10,
+		Compress:          []string{"gzip"},
+		CompressThreshold: 16,
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	var conns int32
+	go func() {
+		for {
+			session, err := server.Accept()
+			if err != nil {
+				return
+			}
+			conn := int(atomic.AddInt32(&conns, 1))
+			go func() {
+				if encrypt {
+					session.SetShareKeyId(testAuthKeyId)
+					session.SetShareKey(testShareKey)
+				}
+				if err := session.InitCodec(); err != nil {
+					return
+				}
+				for {
+					env, err := session.Receive()
+					if err != nil {
+						return
+					}
+					h(conn, session, env)
+				}
+			}()
+		}
+	}()
+	return server.Listener().Addr().String()
+}
+
+func request(t *testing.T, c *Client, payload string) (*external.Error, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	p, err := c.Request(ctx, uint32(external.Cmd_ERROR), &external.Error{ErrMsg: payload})
+	if err != nil {
+		return nil, err
+	}
+	resp := &external.Error{}
+	return resp, p.Unmarshal(resp)
+}
+
+func TestRoundTrip(t *testing.T) {
+	payload := strings.Repeat("zwchat", 200)
+	for _, c := range []struct {
+		name     string
+		opts     Options
+		encrypt  bool
+		compress bool
+	}{
+		{name: "tcp", opts: Options{Network: "tcp"}},
+		{name: "tcp legacy", opts: Options{Network: "tcp", LegacyFrame: true}},
+		{name: "tcp encrypt", opts: Options{Network: "tcp", ShareKey: testShareKey}, encrypt: true},
+		{name: "tcp gzip", opts: Options{Network: "tcp", Compress: []string{"gzip"}}, compress: true},
+		{name: "tcp gzip encrypt", opts: Options{Network: "tcp", Compress: []string{"gzip"}, ShareKey: testShareKey},
+			encrypt: true, compress: true},
+		{name: "ws", opts: Options{Network: "ws"}},
+		{name: "http", opts: Options{Network: "http"}},
+		{name: "http gzip", opts: Options{Network: "http", Compress: []string{"gzip"}}, compress: true},
+	} {
+		opts := c.opts
+		opts.Addr = serve(t, c.encrypt, echo)
+		opts.AuthKeyId = testAuthKeyId
+		client, err := Dial(opts)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		//http的压缩算法在第一次响应后才确定
+		for i := 0; i < 2; i++ {
+			resp, err := request(t, client, payload)
+			if err != nil || resp.ErrMsg != payload {
+				t.Fatalf("%s: unexpected response %v", c.name, err)
+			}
+		}
+		client.connMutex.RLock()
+		compressor := client.conn.Compressor()
+		client.connMutex.RUnlock()
+		if (compressor != nil) != c.compress {
+			t.Fatalf("%s: unexpected compressor %v", c.name, compressor)
+		}
+		client.Close()
+	}
+}
+
+//连接断开后重连，调用OnReconnect并重发未响应的请求
+func TestReconnectResume(t *testing.T) {
+	addr := serve(t, false, func(conn int, session *net_lib.Session, env *net_lib.Envelope) {
+		if conn == 1 {
+			session.Close()
+			return
+		}
+		echo(conn, session, env)
+	})
+	var reconnects int32
+	client, err := Dial(Options{
+		Addr:       addr,
+		MinBackoff: 10 * time.Millisecond,
+		OnReconnect: func(c *Client) error {
+			atomic.AddInt32(&reconnects, 1)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if resp, err := request(t, client, "resend"); err != nil || resp.ErrMsg != "resend" {
+		t.Fatalf("unexpected response %+v, %v", resp, err)
+	}
+	if n := atomic.LoadInt32(&reconnects); n != 1 {
+		t.Fatalf("want 1 reconnect, got %d", n)
+	}
+	client.Close()
+	if _, err = request(t, client, "closed"); err != ClientClosedErr {
+		t.Fatalf("want ClientClosedErr, got %v", err)
+	}
+}
+
+//服务端发来超过MaxMsgSize的消息时断开，不交给调用方
+func TestOversizeMessage(t *testing.T) {
+	for _, network := range []string{"tcp", "ws", "http"} {
+		var reconnects int32
+		client, err := Dial(Options{
+			Network:    network,
+			Addr:       serve(t, false, echo),
+			MaxMsgSize: 1024,
+			MinBackoff: 10 * time.Millisecond,
+			OnReconnect: func(c *Client) error {
+				atomic.AddInt32(&reconnects, 1)
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		_, err = client.Request(ctx, uint32(external.Cmd_ERROR), &external.Error{ErrMsg: strings.Repeat("x", 2048)})
+		cancel()
+		if err != context.DeadlineExceeded {
+			t.Fatalf("%s: want DeadlineExceeded, got %v", network, err)
+		}
+		if atomic.LoadInt32(&reconnects) == 0 {
+			t.Fatalf("%s: connection not dropped", network)
+		}
+		client.Close()
+	}
+}
+
+//异常的长度在分配内存之前被拒绝
+func TestHostileLength(t *testing.T) {
+	huge := make([]byte, binary.MaxVarintLen64)
+	huge = huge[:binary.PutUvarint(huge, 1<<63)]
+	wsExt := make([]byte, 8)
+	binary.BigEndian.PutUint64(wsExt, 1<<63)
+	for _, c := range []struct {
+		name string
+		data []byte
+		t    func(conn net.Conn) transport
+	}{
+		{"tcp varint", append([]byte{net_lib.FrameMagic | net_lib.FrameVersion, 0}, huge...), func(conn net.Conn) transport {
+			return newTestTransport(conn, false)
+		}},
+		{"tcp fragments", append(append([]byte{net_lib.FrameMagic | net_lib.FrameVersion, net_lib.FlagMoreFragments, 16},
+			make([]byte, 16)...), append([]byte{net_lib.FrameMagic | net_lib.FrameVersion, 0}, huge...)...),
+			func(conn net.Conn) transport { return newTestTransport(conn, false) }},
+		{"tcp legacy", []byte{0xff, 0xff, 0xff, 0xff}, func(conn net.Conn) transport {
+			return newTestTransport(conn, true)
+		}},
+		{"ws extended length", append([]byte{0x82, 127}, wsExt...), func(conn net.Conn) transport {
+			return &wsTransport{*newTestTransport(conn, false)}
+		}},
+	} {
+		conn, peer := net.Pipe()
+		go peer.Write(c.data)
+		if _, _, err := c.t(conn).ReadFrame(); err != net_lib.DataLenErr {
+			t.Fatalf("%s: want DataLenErr, got %v", c.name, err)
+		}
+		conn.Close()
+		peer.Close()
+	}
+}
+
+func newTestTransport(conn net.Conn, legacy bool) *tcpTransport {
+	opts := &Options{}
+	opts.init()
+	return &tcpTransport{conn: conn, r: bufio.NewReader(conn), maxMsgSize: opts.MaxMsgSize, legacy: legacy}
+}
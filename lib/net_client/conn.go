@@ -0,0 +1,408 @@
+package net_client
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+)
+
+var HttpStatusErr = errors.New("[client] http response status error")
+var WsHandshakeErr = errors.New("[client] websocket handshake failed")
+var WsClosedErr = errors.New("[client] websocket closed by server")
+var ControlErr = errors.New("[client] unexpected control frame")
+
+//底层连接，负责帧的读写，network支持tcp、ws和http
+//flags为net_lib中帧的标识，目前只使用FlagCompressed
+type transport interface {
+	ReadFrame() ([]byte, uint8, error)
+	WriteFrame(frame []byte, flags uint8) error
+	//握手时协商的压缩算法，没有时为nil
+	Compressor() net_lib.Compressor
+	SetWriteDeadline(t time.Time) error
+	Close() error
+}
+
+func dialTransport(opts *Options) (transport, error) {
+	switch opts.Network {
+	case "ws":
+		return dialWs(opts)
+	case "http":
+		conn, err := net.DialTimeout("tcp", opts.Addr, opts.DialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return &httpTransport{tcpTransport{conn: conn, r: bufio.NewReader(conn), maxMsgSize: opts.MaxMsgSize}, opts}, nil
+	default:
+		conn, err := net.DialTimeout("tcp", opts.Addr, opts.DialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		t := &tcpTransport{conn: conn, r: bufio.NewReader(conn), maxMsgSize: opts.MaxMsgSize, legacy: opts.LegacyFrame}
+		if !t.legacy && len(opts.Compress) > 0 {
+			if err = t.handshake(opts); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+		return t, nil
+	}
+}
+
+//版本1的帧头 + authKeyId + msgKey(加密时) + data，legacy为true时使用旧的长度(4字节小端)帧头
+type tcpTransport struct {
+	conn       net.Conn
+	r          *bufio.Reader
+	maxMsgSize uint32
+	legacy     bool
+	compressor net_lib.Compressor
+}
+
+//通过控制帧协商压缩算法
+func (t *tcpTransport) handshake(opts *Options) error {
+	if opts.DialTimeout > 0 {
+		t.conn.SetDeadline(time.Now().Add(opts.DialTimeout))
+		defer t.conn.SetDeadline(time.Time{})
+	}
+	payload := net_lib.FormatControl(map[string]string{"compress": strings.Join(opts.Compress, ",")})
+	w := net_lib.NewWriter(nil)
+	w.WriteFrameHeader(net_lib.FlagControl, authKeyIdLen+len(payload))
+	w.Write(opts.AuthKeyId, payload)
+	if _, err := t.conn.Write(w.Bytes()); err != nil {
+		return err
+	}
+	frame, flags, err := t.ReadFrame()
+	if err != nil {
+		return err
+	}
+	if flags&net_lib.FlagControl == 0 {
+		return ControlErr
+	}
+	values := net_lib.ParseControl(frame[authKeyIdLen+msgKeyLen:])
+	if name := values["compress"]; name != "" {
+		if t.compressor = net_lib.GetCompressor(name); t.compressor == nil {
+			return net_lib.CompressorErr
+		}
+	}
+	return nil
+}
+
+func (t *tcpTransport) Compressor() net_lib.Compressor {
+	return t.compressor
+}
+
+//返回的帧统一为 authKeyId + msgKey + data，不加密时msgKey全为0
+//分片的帧只能是不加密不压缩的
+func (t *tcpTransport) ReadFrame() ([]byte, uint8, error) {
+	if t.legacy {
+		frame, err := t.readLegacyFrame()
+		return frame, 0, err
+	}
+	var frame []byte
+	for {
+		head := make([]byte, 2)
+		if _, err := io.ReadFull(t.r, head); err != nil {
+			return nil, 0, err
+		}
+		if head[0] != net_lib.FrameMagic|net_lib.FrameVersion {
+			return nil, 0, net_lib.FrameVersionErr
+		}
+		header := &net_lib.FrameHeader{Version: net_lib.FrameVersion, Flags: head[1]}
+		length, err := binary.ReadUvarint(t.r)
+		if err != nil {
+			return nil, 0, err
+		}
+		minLen := uint64(authKeyIdLen)
+		if header.Encrypted() {
+			minLen += msgKeyLen
+		}
+		//先和上限比较再相加，避免超大的length溢出
+		if length < minLen || length > uint64(t.maxMsgSize) || uint64(len(frame))+length > uint64(t.maxMsgSize) {
+			return nil, 0, net_lib.DataLenErr
+		}
+		if (header.MoreFragments() || frame != nil) &&
+			(header.Encrypted() || header.Compressed() || header.Control()) {
+			return nil, 0, net_lib.FrameFlagErr
+		}
+		buf := make([]byte, length)
+		if _, err := io.ReadFull(t.r, buf); err != nil {
+			return nil, 0, err
+		}
+		if frame == nil {
+			frame = append(frame, buf[:authKeyIdLen]...)
+			if header.Encrypted() {
+				frame = append(frame, buf[authKeyIdLen:minLen]...)
+			} else {
+				frame = append(frame, make([]byte, msgKeyLen)...)
+			}
+		}
+		frame = append(frame, buf[minLen:]...)
+		if !header.MoreFragments() {
+			return frame, header.Flags, nil
+		}
+	}
+}
+
+func (t *tcpTransport) readLegacyFrame() ([]byte, error) {
+	var head [4]byte
+	if _, err := io.ReadFull(t.r, head[:]); err != nil {
+		return nil, err
+	}
+	length := binary.LittleEndian.Uint32(head[:])
+	if length < authKeyIdLen+msgKeyLen || length > t.maxMsgSize {
+		return nil, net_lib.DataLenErr
+	}
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(t.r, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+func (t *tcpTransport) WriteFrame(frame []byte, flags uint8) error {
+	w := net_lib.NewWriter(nil)
+	if t.legacy {
+		w.WriteUint32(uint32(len(frame)))
+		w.Write(frame)
+	} else if msgKey := frame[authKeyIdLen : authKeyIdLen+msgKeyLen]; net_lib.IsBytesAllZero(msgKey) {
+		w.WriteFrameHeader(flags, len(frame)-msgKeyLen)
+		w.Write(frame[:authKeyIdLen], frame[authKeyIdLen+msgKeyLen:])
+	} else {
+		w.WriteFrameHeader(flags|net_lib.FlagEncrypted, len(frame))
+		w.Write(frame)
+	}
+	_, err := t.conn.Write(w.Bytes())
+	return err
+}
+
+func (t *tcpTransport) SetWriteDeadline(deadline time.Time) error {
+	return t.conn.SetWriteDeadline(deadline)
+}
+
+func (t *tcpTransport) Close() error {
+	return t.conn.Close()
+}
+
+type wsTransport struct {
+	tcpTransport
+}
+
+func dialWs(opts *Options) (transport, error) {
+	conn, err := net.DialTimeout("tcp", opts.Addr, opts.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	t := &wsTransport{tcpTransport{conn: conn, r: bufio.NewReader(conn), maxMsgSize: opts.MaxMsgSize}}
+	if opts.DialTimeout > 0 {
+		conn.SetDeadline(time.Now().Add(opts.DialTimeout))
+	}
+	if err = t.handshake(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return t, nil
+}
+
+func (t *wsTransport) handshake(opts *Options) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	secKey := base64.StdEncoding.EncodeToString(nonce)
+	path := opts.Path
+	if path == "" {
+		path = "/"
+	}
+	req := &http.Request{
+		Method:     "GET",
+		URL:        &url.URL{Path: path},
+		Host:       opts.Addr,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Upgrade":                {"websocket"},
+			"Connection":             {"Upgrade"},
+			"Sec-WebSocket-Key":      {secKey},
+			"Sec-WebSocket-Version":  {"13"},
+			"Sec-WebSocket-Protocol": {net_lib.SubprotocolProto},
+		},
+	}
+	if err := req.Write(t.conn); err != nil {
+		return err
+	}
+	resp, err := http.ReadResponse(t.r, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-WebSocket-Accept") != net_lib.ComputeAcceptedKey(secKey) ||
+		resp.Header.Get("Sec-WebSocket-Protocol") != net_lib.SubprotocolProto {
+		return WsHandshakeErr
+	}
+	return nil
+}
+
+//读取一条完整的消息，合并分片并处理控制帧
+func (t *wsTransport) ReadFrame() ([]byte, uint8, error) {
+	message, err := t.readMessage()
+	return message, 0, err
+}
+
+func (t *wsTransport) readMessage() ([]byte, error) {
+	var message []byte
+	for {
+		p := make([]byte, 2)
+		if _, err := io.ReadFull(t.r, p); err != nil {
+			return nil, err
+		}
+		final := p[0]&0x80 != 0
+		frameType := int(p[0] & 0x0f)
+		length := uint64(p[1] & 0x7f)
+		switch length {
+		case 126:
+			if _, err := io.ReadFull(t.r, p); err != nil {
+				return nil, err
+			}
+			length = uint64(binary.BigEndian.Uint16(p))
+		case 127:
+			ext := make([]byte, 8)
+			if _, err := io.ReadFull(t.r, ext); err != nil {
+				return nil, err
+			}
+			length = binary.BigEndian.Uint64(ext)
+		}
+		if length > uint64(t.maxMsgSize) || uint64(len(message))+length > uint64(t.maxMsgSize) {
+			return nil, net_lib.DataLenErr
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(t.r, payload); err != nil {
+			return nil, err
+		}
+		switch frameType {
+		case net_lib.CloseMessage:
+			return nil, WsClosedErr
+		case net_lib.PingMessage:
+			if err := t.writeFrame(net_lib.PongMessage, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case net_lib.PongMessage:
+			continue
+		}
+		message = append(message, payload...)
+		if final {
+			return message, nil
+		}
+	}
+}
+
+func (t *wsTransport) WriteFrame(frame []byte, flags uint8) error {
+	return t.writeFrame(net_lib.BinaryMessage, frame)
+}
+
+//客户端发送的帧必须掩码
+func (t *wsTransport) writeFrame(frameType int, data []byte) error {
+	length := len(data)
+	w := net_lib.NewWriter(nil)
+	w.WriteByte(byte(frameType) | 0x80)
+	switch {
+	case length >= 65536:
+		w.WriteByte(0x80 | 127)
+		ext := make([]byte, 8)
+		binary.BigEndian.PutUint64(ext, uint64(length))
+		w.Write(ext)
+	case length > 125:
+		w.WriteByte(0x80 | 126)
+		ext := make([]byte, 2)
+		binary.BigEndian.PutUint16(ext, uint16(length))
+		w.Write(ext)
+	default:
+		w.WriteByte(0x80 | byte(length))
+	}
+	maskKey := make([]byte, 4)
+	if _, err := rand.Read(maskKey); err != nil {
+		return err
+	}
+	masked := make([]byte, length)
+	for i := range data {
+		masked[i] = data[i] ^ maskKey[i&3]
+	}
+	w.Write(maskKey, masked)
+	_, err := t.conn.Write(w.Bytes())
+	return err
+}
+
+//每条消息是一个keep-alive的POST请求，消息体为authKeyId + msgKey + data，只能收到请求的响应
+type httpTransport struct {
+	tcpTransport
+	opts *Options
+}
+
+//响应头中的X-Accept-Compress为服务端选择的压缩算法
+func (t *httpTransport) ReadFrame() ([]byte, uint8, error) {
+	resp, err := http.ReadResponse(t.r, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, HttpStatusErr
+	}
+	if resp.ContentLength > int64(t.maxMsgSize) {
+		return nil, 0, net_lib.DataLenErr
+	}
+	if name := resp.Header.Get(net_lib.HeaderAcceptCompress); name != "" {
+		t.compressor = net_lib.GetCompressor(name)
+	}
+	var flags uint8
+	if name := resp.Header.Get(net_lib.HeaderCompress); name != "" {
+		if t.compressor == nil || t.compressor.Name() != name {
+			return nil, 0, net_lib.CompressorErr
+		}
+		flags |= net_lib.FlagCompressed
+	}
+	//chunked的响应没有Content-Length，读取时限制长度
+	frame, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(t.maxMsgSize)+1))
+	if resp.Close {
+		//服务端GOAWAY，下一次读取失败后重连
+		t.conn.Close()
+	}
+	if err == nil && len(frame) > int(t.maxMsgSize) {
+		return nil, 0, net_lib.DataLenErr
+	}
+	return frame, flags, err
+}
+
+func (t *httpTransport) WriteFrame(frame []byte, flags uint8) error {
+	path := t.opts.Path
+	if path == "" {
+		path = "/"
+	}
+	req, err := http.NewRequest("POST", "http://"+t.opts.Addr+path, bytes.NewReader(frame))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Connection", "Keep-Alive")
+	if len(t.opts.Compress) > 0 {
+		req.Header.Set(net_lib.HeaderAcceptCompress, strings.Join(t.opts.Compress, ","))
+	}
+	if flags&net_lib.FlagCompressed != 0 {
+		req.Header.Set(net_lib.HeaderCompress, t.compressor.Name())
+	}
+	return req.Write(t.conn)
+}
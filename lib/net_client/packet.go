@@ -0,0 +1,48 @@
+package net_client
+
+import (
+	"errors"
+
+	"github.com/imkuqin-zw/ZWChat/lib/net_lib"
+)
+
+const (
+	authKeyIdLen = 8
+	msgKeyLen    = 16
+)
+
+var PacketLenErr = errors.New("[client] packet length error")
+var ShareKeyLenErr = errors.New("[client] share key must be 32 bytes")
+
+//authKeyId(8) + msgKey(16) + data，shareKey为空时msgKey全为0且不加密
+func seal(authKeyId, shareKey, data []byte) ([]byte, error) {
+	w := net_lib.NewWriter(nil)
+	if len(shareKey) == 0 {
+		w.Write(authKeyId, make([]byte, msgKeyLen), data)
+		return w.Bytes(), nil
+	}
+	msgKey := net_lib.DeriveMsgKey(shareKey, data)
+	key, iv := net_lib.DeriveAESKey(shareKey, msgKey)
+	enBytes, err := net_lib.AESCBCPadEncrypt(nil, data, key, iv)
+	if err != nil {
+		return nil, err
+	}
+	w.Write(authKeyId, msgKey, enBytes)
+	return w.Bytes(), nil
+}
+
+func open(shareKey, frame []byte) ([]byte, error) {
+	if len(frame) < authKeyIdLen+msgKeyLen {
+		return nil, PacketLenErr
+	}
+	msgKey := frame[authKeyIdLen : authKeyIdLen+msgKeyLen]
+	data := frame[authKeyIdLen+msgKeyLen:]
+	if net_lib.IsBytesAllZero(msgKey) {
+		return data, nil
+	}
+	if len(shareKey) == 0 {
+		return nil, ShareKeyLenErr
+	}
+	key, iv := net_lib.DeriveAESKey(shareKey, msgKey)
+	return net_lib.AESCBCDecrypt(nil, data, key, iv)
+}
@@ -2,25 +2,70 @@ package net_lib
 
 import (
 	"encoding/base64"
+	"errors"
 	"github.com/imkuqin-zw/ZWChat/common/logger"
 	"go.uber.org/zap"
-	"errors"
 )
 
 var ProtoTcp = new(ProtoTcpCode)
 var ProtoHttp = new(ProtoHttpCode)
 var ProtoWs = new(ProtoWsCode)
+var JsonWs = new(JsonWsCode)
 var DataLenErr = errors.New("receive data length error")
+var ShareKeyErr = errors.New("share key not found")
 
 type Codec interface {
-	Packet(src interface{}, session *Session) ([]byte, error)
-	UnPack(session *Session) ([]byte, error)
+	Packet(env *Envelope, session *Session) ([]byte, error)
+	UnPack(session *Session) (*Envelope, error)
+}
+
+//编码信封并按需压缩、加密，返回authKeyId、msgKey(不加密时为nil)、数据和压缩使用的算法(没有压缩时为nil)
+func seal(env *Envelope, session *Session, compress bool) (authKeyId, msgKey, data []byte, compressed Compressor, err error) {
+	authKeyId = session.GetShareKeyId()
+	shareKey := session.GetShareKey(authKeyId)
+	if len(authKeyId) == 0 {
+		authKeyId = make([]byte, 8)
+	}
+	data = env.Marshal()
+	if compress {
+		if data, compressed, err = session.compress(data); err != nil {
+			return
+		}
+	}
+	if len(shareKey) != 0 {
+		msgKey, data, err = encrypt(shareKey, data)
+	}
+	return
+}
+
+//authKeyId(8) + msgKey(16) + data，msgKey全为0时不加密
+func openFrame(session *Session, frame []byte) ([]byte, error) {
+	if len(frame) < 24 {
+		logger.Error("Proto openFrame err: ", zap.Error(DataLenErr))
+		return nil, DataLenErr
+	}
+	authKey, msgKey, data := frame[:8], frame[8:24], frame[24:]
+	if !IsBytesAllZero(authKey) {
+		session.SetShareKeyId(authKey)
+	}
+	if IsBytesAllZero(msgKey) {
+		return data, nil
+	}
+	return decrypt(session.GetShareKey(authKey), msgKey, data)
+}
+
+func unmarshalEnvelope(data []byte) (*Envelope, error) {
+	env, err := UnmarshalEnvelope(data)
+	if err != nil {
+		logger.Error("Proto UnmarshalEnvelope err: ", zap.Error(err))
+		return nil, err
+	}
+	return env, nil
 }
 
 func encrypt(shareKey, data []byte) ([]byte, []byte, error) {
-	var key, iv []byte
 	msgKey := DeriveMsgKey(shareKey, data)
-	DeriveAESKey(shareKey, msgKey, key, iv)
+	key, iv := DeriveAESKey(shareKey, msgKey)
 	logger.Debug("Proto Packet: ",
 		zap.String("msgKey", base64.StdEncoding.EncodeToString(msgKey)),
 		zap.String("AESKey", base64.StdEncoding.EncodeToString(key)),
@@ -34,8 +79,11 @@ func encrypt(shareKey, data []byte) ([]byte, []byte, error) {
 }
 
 func decrypt(shareKey, msgKey, data []byte) ([]byte, error) {
-	var key, iv []byte
-	DeriveAESKey(shareKey, msgKey, key, iv)
+	if len(shareKey) != 32 {
+		logger.Error("Proto decrypt err: ", zap.Error(ShareKeyErr))
+		return nil, ShareKeyErr
+	}
+	key, iv := DeriveAESKey(shareKey, msgKey)
 	result, err := AESCBCDecrypt(nil, data, key, iv)
 	if err != nil {
 		logger.Error("Proto decrypt err: ", zap.Error(err))
@@ -2,85 +2,94 @@ package net_lib
 
 import (
 	"fmt"
-	"time"
-	"net/http"
 	"github.com/imkuqin-zw/ZWChat/common/logger"
 	"go.uber.org/zap"
-	"io/ioutil"
-	"github.com/golang/protobuf/proto"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//请求头中的X-Accept-Compress为客户端支持的压缩算法，响应头中为服务端选择的算法；
+//X-Compress表示本次的消息体数据使用该算法压缩
+const (
+	HeaderAcceptCompress = "X-Accept-Compress"
+	HeaderCompress       = "X-Compress"
 )
 
 type ProtoHttpCode struct{}
 
-func (codec *ProtoHttpCode) Packet(msg interface{}, session *Session) ([]byte, error) {
-	authKeyId := session.GetShareKeyId()
-	shareKey := session.GetShareKey(authKeyId)
-	body, err := proto.Marshal(msg.(proto.Message))
+func (codec *ProtoHttpCode) Packet(env *Envelope, session *Session) ([]byte, error) {
+	authKeyId, msgKey, body, compressed, err := seal(env, session, true)
 	if err != nil {
-		logger.Error("Proto Packet Marshal err: ", zap.Error(err))
+		logger.Error("ProtoHttpCode Packet seal err: ", zap.Error(err))
 		return nil, err
 	}
+	if msgKey == nil { // 不加密
+		msgKey = make([]byte, 16)
+	}
+	content := new(Writer)
+	content.Write(authKeyId, msgKey, body)
 	result := new(Writer)
-
-	if len(shareKey) == 0 { // 不加密
-		result.WriteUint32(24 + uint32(len(body)))
-		result.Write(authKeyId, make([]byte, 16), body)
-	} else { // 加密
-		msgKey, enBytes, err := encrypt(shareKey, body)
-		if err != nil {
-			return nil, err
-		}
-		result.WriteUint32(24 + uint32(len(enBytes)))
-		result.Write(authKeyId, msgKey, enBytes)
+	result.WriteStrings("HTTP/1.1 200 OK\r\n")
+	result.WriteStrings("Content-Type: text/plain\r\n")
+	if session.IsWaiting() {
+		//GOAWAY之后通知客户端关闭连接
+		result.WriteStrings("Connection: close\r\n")
+	} else {
+		result.WriteStrings("Connection: Keep-Alive\r\n")
+	}
+	if c := session.GetCompressor(); c != nil {
+		result.WriteStrings(fmt.Sprintf("%s: %s\r\n", HeaderAcceptCompress, c.Name()))
 	}
-	header := new(Writer)
-	header.WriteStrings("HTTP/1.1 200 OK\r\n")
-	header.WriteStrings("Content-Type: text/plain\r\n")
-	header.WriteStrings("Connection: Keep-Alive\r\n")
-	header.WriteStrings(fmt.Sprintf("Content-Length: %d\r\n", result.Len()))
+	if compressed != nil {
+		result.WriteStrings(fmt.Sprintf("%s: %s\r\n", HeaderCompress, compressed.Name()))
+	}
+	result.WriteStrings(fmt.Sprintf("Content-Length: %d\r\n", content.Len()))
 	TimeFormat := "Mon, 02 Jan 2006 15:04:05 GMT"
 	dataStr := time.Now().UTC().Format(TimeFormat)
-	header.WriteStrings(fmt.Sprintf("Date:%s\r\n\r\n", dataStr))
-	result.Write(header.Bytes())
+	result.WriteStrings(fmt.Sprintf("Date:%s\r\n\r\n", dataStr))
+	result.Write(content.Bytes())
 	return result.Bytes(), nil
 }
 
-func (codec *ProtoHttpCode) UnPack(session *Session) ([]byte, error) {
+func (codec *ProtoHttpCode) UnPack(session *Session) (*Envelope, error) {
 	if session.cfg.ReadDeadLine > 0 {
 		deadTime := time.Now().Add(time.Second * time.Duration(session.cfg.ReadDeadLine))
 		session.conn.SetReadDeadline(deadTime)
 	}
 	r, err := http.ReadRequest(session.r.r)
 	if err != nil {
-		logger.Error("ProtoHttpCode UnPack ReadRequest err: ", zap.Error(err))
+		session.logReadErr("ProtoHttpCode UnPack ReadRequest err: ", err)
 		return nil, err
 	}
 	defer r.Body.Close()
+	session.setForwardedIp(r.Header.Get("X-Forwarded-For"), r.Header.Get("X-Real-IP"))
+	//经过代理的http连接上每个请求的客户端可能不同，逐个请求检查封禁
+	if session.limiter != nil && session.limiter.IsBanned(session.RemoteIp) {
+		return nil, ClientRejectedErr
+	}
 	if session.cfg.ReadDeadLine > 0 {
 		session.conn.SetReadDeadline(time.Time{})
 	}
-	body, err := ioutil.ReadAll(r.Body)
+	body, err := readLimited(r.Body, session.cfg.MaxMsgSize, DataLenErr)
 	if err != nil {
 		return nil, err
 	}
-	if len(body) < 24 {
-		logger.Error("ProtoHttpCode UnPack err: ", zap.Error(DataLenErr))
-		return nil, DataLenErr
+	result, err := openFrame(session, body)
+	if err != nil {
+		return nil, err
 	}
-	authKey := body[:8]
-	if !IsBytesAllZero(authKey) {
-		session.SetShareKeyId(authKey)
+	if offered := r.Header.Get(HeaderAcceptCompress); offered != "" {
+		session.SetCompressor(NegotiateCompressor(strings.Split(offered, ","), session.cfg.Compress))
 	}
-	msgKey := body[9:25]
-	data := body[25:]
-	result := data
-	if msgKey != nil {
-		shareKey := session.GetShareKey(authKey)
-		result, err = decrypt(shareKey, msgKey, data)
-		if err != nil {
+	if name := r.Header.Get(HeaderCompress); name != "" {
+		var c Compressor
+		if current := session.GetCompressor(); current != nil && current.Name() == name {
+			c = current
+		}
+		if result, err = session.decompress(c, result, 0); err != nil {
 			return nil, err
 		}
 	}
-	return result, nil
+	return unmarshalEnvelope(result)
 }
-
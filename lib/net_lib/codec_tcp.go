@@ -1,48 +1,113 @@
 package net_lib
 
 import (
-	"github.com/golang/protobuf/proto"
 	"github.com/imkuqin-zw/ZWChat/common/logger"
 	"go.uber.org/zap"
+	"strings"
 	"time"
 )
 
 type ProtoTcpCode struct{}
 
-func (codec *ProtoTcpCode) Packet(msg interface{}, session *Session) ([]byte, error) {
-	authKeyId := session.GetShareKeyId()
-	shareKey := session.GetShareKey(authKeyId)
-	body, err := proto.Marshal(msg.(proto.Message))
+func (codec *ProtoTcpCode) Packet(env *Envelope, session *Session) ([]byte, error) {
+	authKeyId, msgKey, body, compressed, err := seal(env, session, !session.legacyFrame)
 	if err != nil {
-		logger.Error("Proto Packet Marshal err: ", zap.Error(err))
+		logger.Error("Proto Packet seal err: ", zap.Error(err))
 		return nil, err
 	}
 	result := new(Writer)
-	if len(shareKey) == 0 { // 不加密
+	if session.legacyFrame {
+		if msgKey == nil {
+			msgKey = make([]byte, 16)
+		}
 		result.WriteUint32(24 + uint32(len(body)))
-		result.Write(authKeyId, make([]byte, 16), body)
+		result.Write(authKeyId, msgKey, body)
+		return result.Bytes(), nil
+	}
+	var flags uint8
+	if compressed != nil {
+		flags |= FlagCompressed
+	}
+	if msgKey == nil { // 不加密
+		result.WriteFrameHeader(flags, 8+len(body))
+		result.Write(authKeyId, body)
 	} else { // 加密
-		msgKey, enBytes, err := encrypt(shareKey, body)
-		if err != nil {
-			return nil, err
+		result.WriteFrameHeader(flags|FlagEncrypted, 24+len(body))
+		result.Write(authKeyId, msgKey, body)
+	}
+	return result.Bytes(), nil
+}
+
+//处理控制帧，协商压缩算法后回复选择的结果
+func (codec *ProtoTcpCode) handleControl(session *Session, data []byte) error {
+	values := ParseControl(data)
+	reply := make(map[string]string)
+	if offered, ok := values["compress"]; ok {
+		c := NegotiateCompressor(strings.Split(offered, ","), session.cfg.Compress)
+		session.SetCompressor(c)
+		reply["compress"] = ""
+		if c != nil {
+			reply["compress"] = c.Name()
 		}
-		result.WriteUint32(24 + uint32(len(enBytes)))
-		result.Write(authKeyId, msgKey, enBytes)
 	}
-	return body, nil
+	return codec.WriteControl(session, reply)
+}
+
+//发送控制帧，旧版本的帧格式不支持控制帧
+func (codec *ProtoTcpCode) WriteControl(session *Session, values map[string]string) error {
+	if session.legacyFrame {
+		return FrameFlagErr
+	}
+	payload := FormatControl(values)
+	authKeyId := session.GetShareKeyId()
+	if len(authKeyId) == 0 {
+		authKeyId = make([]byte, 8)
+	}
+	frame := new(Writer)
+	frame.WriteFrameHeader(FlagControl, 8+len(payload))
+	frame.Write(authKeyId, payload)
+	return session.Write(frame.Bytes())
+}
+
+//根据第一帧的帧头判断客户端是否使用旧版本的帧格式
+func (codec *ProtoTcpCode) DetectVersion(session *Session) error {
+	if session.cfg.ReadDeadLine > 0 {
+		deadTime := time.Now().Add(time.Second * time.Duration(session.cfg.ReadDeadLine))
+		session.conn.SetReadDeadline(deadTime)
+	}
+	head, err := session.r.Peek(4)
+	if session.cfg.ReadDeadLine > 0 {
+		session.conn.SetReadDeadline(time.Time{})
+	}
+	if err != nil {
+		session.logReadErr("Proto DetectVersion err: ", err)
+		return err
+	}
+	session.legacyFrame = IsLegacyFrame(head, session.cfg.MaxMsgSize)
+	if session.legacyFrame {
+		logger.Debug("Proto DetectVersion: legacy frame", zap.Uint64("session", session.id))
+	}
+	return nil
 }
 
-func (codec *ProtoTcpCode) UnPack(session *Session) ([]byte, error) {
+func (codec *ProtoTcpCode) UnPack(session *Session) (*Envelope, error) {
+	if !session.legacyFrame {
+		data, err := codec.unPackFrame(session)
+		if err != nil {
+			return nil, err
+		}
+		return unmarshalEnvelope(data)
+	}
 	if session.cfg.ReadDeadLine > 0 {
 		deadTime := time.Now().Add(time.Second * time.Duration(session.cfg.ReadDeadLine))
 		session.conn.SetReadDeadline(deadTime)
 	}
 	length, err := codec.getDataLen(session.r)
 	if err != nil {
-		logger.Error("Proto UnPack getDataLen err: ", zap.Error(err))
+		session.logReadErr("Proto UnPack getDataLen err: ", err)
 		return nil, err
 	}
-	if length <= 28 || length < session.cfg.MaxMsgSize {
+	if length < 24 || (session.cfg.MaxMsgSize > 0 && length > session.cfg.MaxMsgSize) {
 		logger.Error("Proto UnPack length error:", zap.Uint32("length", length))
 		return nil, DataLenErr
 	}
@@ -50,11 +115,11 @@ func (codec *ProtoTcpCode) UnPack(session *Session) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	msgKey, err := codec.getMsgKey(session.r)
+	msgKey, err := codec.getMsgKey(session)
 	if err != nil {
 		return nil, err
 	}
-	data, err := codec.getData(session.r, int(length-24))
+	data, err := codec.getData(session, int(length-24))
 	if err != nil {
 		return nil, err
 	}
@@ -69,13 +134,82 @@ func (codec *ProtoTcpCode) UnPack(session *Session) ([]byte, error) {
 			return nil, err
 		}
 	}
-	return result, nil
+	return unmarshalEnvelope(result)
+}
+
+//读取版本1的帧，带有后续分片标识的帧会合并为一条消息
+func (codec *ProtoTcpCode) unPackFrame(session *Session) ([]byte, error) {
+	var result []byte
+	for {
+		if session.cfg.ReadDeadLine > 0 {
+			deadTime := time.Now().Add(time.Second * time.Duration(session.cfg.ReadDeadLine))
+			session.conn.SetReadDeadline(deadTime)
+		}
+		header, err := session.r.ReadFrameHeader()
+		if err != nil {
+			session.logReadErr("Proto UnPack ReadFrameHeader err: ", err)
+			return nil, err
+		}
+		if header.Control() && (header.MoreFragments() || result != nil) {
+			logger.Error("Proto UnPack err: ", zap.Error(FrameFlagErr), zap.Uint8("flags", header.Flags))
+			return nil, FrameFlagErr
+		}
+		minLen := uint32(8)
+		if header.Encrypted() {
+			minLen = 24
+		}
+		if header.Length < minLen ||
+			(session.cfg.MaxMsgSize > 0 && uint32(len(result))+header.Length > session.cfg.MaxMsgSize) {
+			logger.Error("Proto UnPack length error:", zap.Uint32("length", header.Length))
+			return nil, DataLenErr
+		}
+		authKey, err := codec.getAuthKeyId(session)
+		if err != nil {
+			return nil, err
+		}
+		var msgKey []byte
+		if header.Encrypted() {
+			if msgKey, err = codec.getMsgKey(session); err != nil {
+				return nil, err
+			}
+		}
+		data, err := codec.getData(session, int(header.Length-minLen))
+		if err != nil {
+			return nil, err
+		}
+		if session.cfg.ReadDeadLine > 0 {
+			session.conn.SetReadDeadline(time.Time{})
+		}
+		if msgKey != nil {
+			shareKey := session.GetShareKey(authKey)
+			if data, err = decrypt(shareKey, msgKey, data); err != nil {
+				return nil, err
+			}
+		}
+		if header.Compressed() {
+			if data, err = session.decompress(session.GetCompressor(), data, uint32(len(result))); err != nil {
+				return nil, err
+			}
+		}
+		if header.Control() {
+			if err = codec.handleControl(session, data); err != nil {
+				return nil, err
+			}
+			session.commitRead()
+			continue
+		}
+		session.recvFlags = header.Flags
+		result = append(result, data...)
+		if !header.MoreFragments() {
+			return result, nil
+		}
+	}
 }
 
-func (codec *ProtoTcpCode) getData(r *Reader, length int) ([]byte, error) {
-	buf, err := r.ReadN(length)
+func (codec *ProtoTcpCode) getData(session *Session, length int) ([]byte, error) {
+	buf, err := session.r.ReadN(length)
 	if err != nil {
-		logger.Error("Proto getData err: ", zap.Error(err))
+		session.logReadErr("Proto getData err: ", err)
 		return nil, err
 	}
 	return buf, nil
@@ -84,7 +218,7 @@ func (codec *ProtoTcpCode) getData(r *Reader, length int) ([]byte, error) {
 func (codec *ProtoTcpCode) getAuthKeyId(session *Session) ([]byte, error) {
 	buf, err := session.r.ReadN(8)
 	if err != nil {
-		logger.Error("Proto getAuthKeyId err: ", zap.Error(err))
+		session.logReadErr("Proto getAuthKeyId err: ", err)
 		return nil, err
 	}
 	if !IsBytesAllZero(buf) {
@@ -94,10 +228,10 @@ func (codec *ProtoTcpCode) getAuthKeyId(session *Session) ([]byte, error) {
 	return nil, nil
 }
 
-func (codec *ProtoTcpCode) getMsgKey(r *Reader) ([]byte, error) {
-	buf, err := r.ReadN(16)
+func (codec *ProtoTcpCode) getMsgKey(session *Session) ([]byte, error) {
+	buf, err := session.r.ReadN(16)
 	if err != nil {
-		logger.Error("Proto getAuthKeyId err: ", zap.Error(err))
+		session.logReadErr("Proto getMsgKey err: ", err)
 		return nil, err
 	}
 	if !IsBytesAllZero(buf) {
@@ -0,0 +1,436 @@
+package net_lib
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var testShareKey = bytes.Repeat([]byte{7}, 32)
+var testAuthKeyId = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+type codecCase struct {
+	name     string
+	encrypt  bool
+	compress bool
+	legacy   bool
+}
+
+func codecCases(legacy bool) []codecCase {
+	var cases []codecCase
+	for _, encrypt := range []bool{false, true} {
+		for _, compress := range []bool{false, true} {
+			if legacy && compress {
+				continue
+			}
+			name := fmt.Sprintf("encrypt=%v,compress=%v", encrypt, compress)
+			cases = append(cases, codecCase{name: name, encrypt: encrypt, compress: compress, legacy: legacy})
+		}
+	}
+	return cases
+}
+
+func newTestSession(t *testing.T, codec Codec, c codecCase) *Session {
+	conn, peer := net.Pipe()
+	t.Cleanup(func() {
+		conn.Close()
+		peer.Close()
+	})
+	session := newSession(nil, conn, codec, 0, SessionCfg{MaxMsgSize: 64 << 10, CompressThreshold: 16})
+	session.legacyFrame = c.legacy
+	if c.encrypt {
+		session.SetShareKeyId(testAuthKeyId)
+		session.SetShareKey(testShareKey)
+	}
+	if c.compress {
+		session.SetCompressor(GetCompressor("gzip"))
+	}
+	return session
+}
+
+func setInput(session *Session, data []byte) {
+	session.r = NewReader(bufio.NewReader(bytes.NewReader(data)))
+}
+
+func testEnvelope() *Envelope {
+	return &Envelope{
+		Cmd:     3,
+		MsgId:   1<<40 + 5,
+		SeqNo:   9,
+		AckId:   1<<33 + 1,
+		Flags:   EnvelopeNeedAck,
+		Payload: bytes.Repeat([]byte("zwchat"), 100),
+	}
+}
+
+func checkEnvelope(t *testing.T, want, got *Envelope) {
+	t.Helper()
+	if got.Cmd != want.Cmd || got.MsgId != want.MsgId || got.SeqNo != want.SeqNo ||
+		got.AckId != want.AckId || got.Flags != want.Flags || !bytes.Equal(got.Payload, want.Payload) {
+		t.Fatalf("envelope mismatch: want %+v, got %+v", want, got)
+	}
+}
+
+func TestEnvelopeMarshal(t *testing.T) {
+	env := testEnvelope()
+	got, err := UnmarshalEnvelope(env.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEnvelope(t, env, got)
+	if _, err = UnmarshalEnvelope(make([]byte, EnvelopeHeadLen-1)); err != EnvelopeLenErr {
+		t.Fatalf("want EnvelopeLenErr, got %v", err)
+	}
+}
+
+func TestTcpCodecRoundTrip(t *testing.T) {
+	for _, c := range append(codecCases(false), codecCases(true)...) {
+		t.Run(fmt.Sprintf("legacy=%v,%s", c.legacy, c.name), func(t *testing.T) {
+			session := newTestSession(t, ProtoTcp, c)
+			env := testEnvelope()
+			buf, err := session.codec.Packet(env, session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if IsLegacyFrame(buf, session.cfg.MaxMsgSize) != c.legacy {
+				t.Fatalf("legacy frame detection mismatch")
+			}
+			if !c.legacy {
+				header, err := NewReader(bufio.NewReader(bytes.NewReader(buf))).ReadFrameHeader()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if header.Encrypted() != c.encrypt || header.Compressed() != c.compress {
+					t.Fatalf("unexpected frame flags %08b", header.Flags)
+				}
+			}
+			setInput(session, buf)
+			got, err := session.codec.UnPack(session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkEnvelope(t, env, got)
+		})
+	}
+}
+
+func TestTcpCodecFragments(t *testing.T) {
+	session := newTestSession(t, ProtoTcp, codecCase{})
+	data := testEnvelope().Marshal()
+	w := new(Writer)
+	w.WriteFrameHeader(FlagMoreFragments, 8+10)
+	w.Write(make([]byte, 8), data[:10])
+	w.WriteFrameHeader(0, 8+len(data)-10)
+	w.Write(make([]byte, 8), data[10:])
+	setInput(session, w.Bytes())
+	got, err := session.codec.UnPack(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEnvelope(t, testEnvelope(), got)
+}
+
+func TestTcpCodecLength(t *testing.T) {
+	session := newTestSession(t, ProtoTcp, codecCase{})
+	w := new(Writer)
+	w.WriteFrameHeader(0, int(session.cfg.MaxMsgSize)+1)
+	setInput(session, w.Bytes())
+	if _, err := session.codec.UnPack(session); err != DataLenErr {
+		t.Fatalf("want DataLenErr, got %v", err)
+	}
+}
+
+func TestHttpCodecRoundTrip(t *testing.T) {
+	for _, c := range codecCases(false) {
+		t.Run(c.name, func(t *testing.T) {
+			session := newTestSession(t, ProtoHttp, c)
+			session.cfg.Compress = []string{"gzip"}
+			env := testEnvelope()
+			buf, err := session.codec.Packet(env, session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf)), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compressed := resp.Header.Get(HeaderCompress) != ""
+			if compressed != c.compress {
+				t.Fatalf("unexpected %s header %q", HeaderCompress, resp.Header.Get(HeaderCompress))
+			}
+
+			//将响应的内容作为请求发回，服务端应能解出相同的信封
+			req, err := http.NewRequest("POST", "http://localhost/", bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.compress {
+				req.Header.Set(HeaderAcceptCompress, "gzip")
+				req.Header.Set(HeaderCompress, "gzip")
+			}
+			var reqBuf bytes.Buffer
+			if err = req.Write(&reqBuf); err != nil {
+				t.Fatal(err)
+			}
+			setInput(session, reqBuf.Bytes())
+			got, err := session.codec.UnPack(session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkEnvelope(t, env, got)
+		})
+	}
+}
+
+func TestWsCodecRoundTrip(t *testing.T) {
+	for _, c := range codecCases(false) {
+		if c.compress {
+			continue
+		}
+		t.Run(c.name, func(t *testing.T) {
+			session := newTestSession(t, ProtoWs, c)
+			session.wsConn = &WsConn{readFinal: true}
+			env := testEnvelope()
+			buf, err := session.codec.Packet(env, session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			setInput(session, buf)
+			got, err := session.codec.UnPack(session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkEnvelope(t, env, got)
+		})
+	}
+}
+
+func TestJsonWsCodecRoundTrip(t *testing.T) {
+	session := newTestSession(t, JsonWs, codecCase{})
+	session.wsConn = &WsConn{readFinal: true}
+	session.format = FormatJSON
+	env := testEnvelope()
+	env.Format = FormatJSON
+	env.Payload = []byte(`{"msg":"hello"}`)
+	buf, err := session.codec.Packet(env, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setInput(session, buf)
+	got, err := session.codec.UnPack(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEnvelope(t, env, got)
+	if got.Format != FormatJSON {
+		t.Fatalf("want json format, got %v", got.Format)
+	}
+
+	//protobuf编码的消息体不能通过json会话发送
+	if _, err = session.codec.Packet(testEnvelope(), session); err != EnvelopeFormatErr {
+		t.Fatalf("want EnvelopeFormatErr, got %v", err)
+	}
+}
+
+func TestNegotiateSubprotocol(t *testing.T) {
+	cases := []struct {
+		offered  string
+		protocol string
+		format   WireFormat
+		err      error
+	}{
+		{"", "", FormatProto, nil},
+		{SubprotocolProto, SubprotocolProto, FormatProto, nil},
+		{"chat, " + SubprotocolJSON + ", " + SubprotocolProto, SubprotocolJSON, FormatJSON, nil},
+		{"chat, zwchat.json.v2", "", FormatProto, WsSubprotocolErr},
+	}
+	for _, c := range cases {
+		protocol, format, err := NegotiateSubprotocol(c.offered)
+		if protocol != c.protocol || format != c.format || err != c.err {
+			t.Errorf("NegotiateSubprotocol(%q) = %q, %v, %v", c.offered, protocol, format, err)
+		}
+	}
+}
+
+//解压后超过MaxMsgSize时返回DecompressLenErr，不会把整个炸弹解压到内存
+func TestDecompressBomb(t *testing.T) {
+	for _, name := range []string{"gzip", "deflate"} {
+		for _, size := range []int{64<<10 + 1, 16 << 20} {
+			t.Run(fmt.Sprintf("%s/%d", name, size), func(t *testing.T) {
+				compressor := GetCompressor(name)
+				bomb, err := compressor.Compress(make([]byte, size))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				session := newTestSession(t, ProtoTcp, codecCase{})
+				if len(bomb)+8 > int(session.cfg.MaxMsgSize) {
+					t.Fatalf("compressed size %d is not below the limit", len(bomb))
+				}
+				session.SetCompressor(compressor)
+				w := new(Writer)
+				w.WriteFrameHeader(FlagCompressed, 8+len(bomb))
+				w.Write(make([]byte, 8), bomb)
+				setInput(session, w.Bytes())
+				if _, err = session.codec.UnPack(session); err != DecompressLenErr {
+					t.Fatalf("tcp: want DecompressLenErr, got %v", err)
+				}
+
+				session = newTestSession(t, ProtoHttp, codecCase{})
+				session.cfg.Compress = []string{name}
+				req, err := http.NewRequest("POST", "http://localhost/", bytes.NewReader(append(make([]byte, 24), bomb...)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set(HeaderAcceptCompress, name)
+				req.Header.Set(HeaderCompress, name)
+				var buf bytes.Buffer
+				if err = req.Write(&buf); err != nil {
+					t.Fatal(err)
+				}
+				setInput(session, buf.Bytes())
+				if _, err = session.codec.UnPack(session); err != DecompressLenErr {
+					t.Fatalf("http: want DecompressLenErr, got %v", err)
+				}
+			})
+		}
+	}
+}
+
+//发送协程打包时读协程重新协商压缩算法，每一帧都能用其中一种算法解压
+func TestTcpCompressRenegotiate(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	const n = 200
+	//发送队列满时Send直接返回错误，队列能放下所有消息
+	session := newSession(nil, conn, ProtoTcp, n, SessionCfg{MaxMsgSize: 64 << 10, CompressThreshold: 16,
+		Compress: []string{"gzip", "deflate"}})
+	defer session.Close()
+	frames := make(chan error, 1)
+	go func() {
+		r := NewReader(bufio.NewReader(peer))
+		for received := 0; received < n; {
+			header, err := r.ReadFrameHeader()
+			if err != nil {
+				frames <- err
+				return
+			}
+			body, err := r.ReadN(int(header.Length))
+			if err != nil {
+				frames <- err
+				return
+			}
+			if header.Control() {
+				continue
+			}
+			received++
+			if !header.Compressed() {
+				continue
+			}
+			if _, err = GetCompressor("gzip").Decompress(body[8:], 64<<10); err != nil {
+				if _, err = GetCompressor("deflate").Decompress(body[8:], 64<<10); err != nil {
+					frames <- err
+					return
+				}
+			}
+		}
+		frames <- nil
+		//继续读取剩余的控制帧回复，避免协商时写阻塞
+		ioutil.ReadAll(peer)
+	}()
+	go func() {
+		for i := 0; i < n; i++ {
+			session.Send(testEnvelope())
+		}
+	}()
+	offers := []string{"gzip", "deflate", ""}
+	for i := 0; i < n; i++ {
+		control := FormatControl(map[string]string{"compress": offers[i%len(offers)]})
+		if err := ProtoTcp.handleControl(session, control); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case err := <-frames:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("frames not received")
+	}
+}
+
+//字段名与json名不同的消息，用于检查jsonpb的OrigName
+type jsonTestMsg struct {
+	ClientMsgId string `protobuf:"bytes,1,opt,name=client_msg_id,json=clientMsgId" json:"client_msg_id,omitempty"`
+	SendTime    int64  `protobuf:"varint,2,opt,name=send_time,json=sendTime" json:"send_time,omitempty"`
+	To          uint64 `protobuf:"varint,3,opt,name=to" json:"to,omitempty"`
+}
+
+func (m *jsonTestMsg) Reset()         { *m = jsonTestMsg{} }
+func (m *jsonTestMsg) String() string { return proto.CompactTextString(m) }
+func (*jsonTestMsg) ProtoMessage()    {}
+
+//json子协议的消息体经过jsonpb编解码：输出使用proto字段名，64位整数为字符串，
+//输入同时接受两种字段名并忽略未知字段，便于服务端先于客户端升级
+func TestJsonWsPayload(t *testing.T) {
+	session := newTestSession(t, JsonWs, codecCase{})
+	session.wsConn = &WsConn{readFinal: true}
+	session.format = FormatJSON
+
+	msg := &jsonTestMsg{ClientMsgId: "c1", SendTime: 1 << 40, To: 7}
+	env, err := session.NewEnvelope(5, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"client_msg_id":"c1","send_time":"1099511627776","to":"7"}`; string(env.Payload) != want {
+		t.Fatalf("want payload %s, got %s", want, env.Payload)
+	}
+	buf, err := session.codec.Packet(env, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf, env.Payload) {
+		t.Fatalf("payload not embedded as json: %q", buf)
+	}
+
+	for _, payload := range []string{
+		`{"client_msg_id":"c1","send_time":"1099511627776","to":"7","future":{"a":[1,2]},"flag":true}`,
+		`{"clientMsgId":"c1","sendTime":1099511627776,"to":7,"future":null}`,
+	} {
+		in := &Envelope{Cmd: 5, MsgId: 1, Format: FormatJSON, Payload: []byte(payload)}
+		buf, err := session.codec.Packet(in, session)
+		if err != nil {
+			t.Fatal(err)
+		}
+		setInput(session, buf)
+		got, err := session.codec.UnPack(session)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &jsonTestMsg{}
+		if err = got.Unmarshal(decoded); err != nil {
+			t.Fatalf("%s: %v", payload, err)
+		}
+		if *decoded != *msg {
+			t.Fatalf("%s: want %+v, got %+v", payload, msg, decoded)
+		}
+	}
+
+	//消息体不是合法的json对象时报错
+	bad := &Envelope{Cmd: 5, Format: FormatJSON, Payload: []byte(`{"to":"x"}`)}
+	if err = bad.Unmarshal(&jsonTestMsg{}); err == nil {
+		t.Fatal("invalid field value should fail")
+	}
+}
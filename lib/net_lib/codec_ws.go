@@ -1,7 +1,6 @@
 package net_lib
 
 import (
-	"github.com/golang/protobuf/proto"
 	"github.com/imkuqin-zw/ZWChat/common/logger"
 	"go.uber.org/zap"
 	"io"
@@ -11,39 +10,38 @@ import (
 
 type ProtoWsCode struct{}
 
-func (codec *ProtoWsCode) Packet(msg interface{}, session *Session) ([]byte, error) {
-	if data, ok := msg.([]byte); ok {
-		return session.flushFrame(data), nil
-	}
-	authKeyId := session.GetShareKeyId()
-	shareKey := session.GetShareKey(authKeyId)
-	body, err := proto.Marshal(msg.(proto.Message))
+func (codec *ProtoWsCode) Packet(env *Envelope, session *Session) ([]byte, error) {
+	authKeyId, msgKey, body, _, err := seal(env, session, false)
 	if err != nil {
-		logger.Error("Proto Packet Marshal err: ", zap.Error(err))
+		logger.Error("ProtoWsCode Packet seal err: ", zap.Error(err))
 		return nil, err
 	}
+	if msgKey == nil { // 不加密
+		msgKey = make([]byte, 16)
+	}
 	extral := new(Writer)
-	if len(shareKey) == 0 {
-		extral.Write(authKeyId, make([]byte, 16), body)
+	extral.Write(authKeyId, msgKey, body)
+	return session.flushFrame(extral.Bytes()), nil
+}
+
+func (codec *ProtoWsCode) UnPack(session *Session) (*Envelope, error) {
+	data, err := readWsMessage(session)
+	if err != nil {
+		return nil, err
 	}
-	if len(shareKey) != 0 {
-		msgKey, enBytes, err := encrypt(shareKey, body)
-		if err != nil {
-			return nil, err
-		}
-		extral.Write(authKeyId, msgKey, enBytes)
+	result, err := openFrame(session, data)
+	if err != nil {
+		return nil, err
 	}
-	return session.flushFrame(extral.Bytes()), nil
+	return unmarshalEnvelope(result)
 }
 
-func (codec *ProtoWsCode) UnPack(session *Session) ([]byte, error) {
+//读取一条完整的文本或二进制消息，控制帧在advanceFrame中处理
+func readWsMessage(session *Session) ([]byte, error) {
 	if session.cfg.ReadDeadLine > 0 {
 		deadTime := time.Now().Add(time.Second * time.Duration(session.cfg.ReadDeadLine))
 		session.conn.SetReadDeadline(deadTime)
 	}
-	if session.cfg.ReadDeadLine > 0 {
-		session.conn.SetReadDeadline(time.Time{})
-	}
 	for session.wsConn.readErr == nil {
 		frameType, err := session.advanceFrame()
 		if err != nil {
@@ -63,34 +61,7 @@ func (codec *ProtoWsCode) UnPack(session *Session) ([]byte, error) {
 		if session.cfg.ReadDeadLine > 0 {
 			session.conn.SetReadDeadline(time.Time{})
 		}
-		//authKey := codec.getAuthKeyId(data, session)
-		//msgKey := codec.getMsgKey(data)
-		var result = data
-		//if msgKey != nil {
-		//	shareKey := session.GetShareKey(authKey)
-		//	result, err = decrypt(shareKey, msgKey, data)
-		//	if err != nil {
-		//		return nil, err
-		//	}
-		//}
-		return result, nil
+		return data, nil
 	}
 	return nil, session.wsConn.readErr
 }
-
-func (codec *ProtoWsCode) getAuthKeyId(data []byte, session *Session) []byte {
-	buf, data := data[:8], data[8:]
-	if !IsBytesAllZero(buf) {
-		session.SetShareKeyId(buf)
-		return buf
-	}
-	return nil
-}
-
-func (codec *ProtoWsCode) getMsgKey(data []byte) []byte {
-	buf, data := data[:16], data[16:]
-	if !IsBytesAllZero(buf) {
-		return buf
-	}
-	return nil
-}
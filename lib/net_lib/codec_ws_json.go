@@ -0,0 +1,68 @@
+package net_lib
+
+import (
+	"encoding/json"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+)
+
+//json格式的websocket消息，每条文本消息为一个信封:
+//
+//	{"cmd":1,"msgId":"2","seqNo":3,"ackId":"0","flags":0,"payload":{...}}
+//
+//64位的id与jsonpb一致使用字符串，payload为jsonpb编码的消息。json格式不加密也不压缩，
+//用于浏览器调试，生产环境应使用protobuf格式或在TLS之后使用。
+type JsonWsCode struct{}
+
+type jsonEnvelope struct {
+	Cmd     uint32          `json:"cmd"`
+	MsgId   uint64          `json:"msgId,string"`
+	SeqNo   uint32          `json:"seqNo"`
+	AckId   uint64          `json:"ackId,string,omitempty"`
+	Flags   uint32          `json:"flags,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+func (codec *JsonWsCode) Packet(env *Envelope, session *Session) ([]byte, error) {
+	if env.Format != FormatJSON && len(env.Payload) != 0 {
+		logger.Error("JsonWsCode Packet err: ", zap.Error(EnvelopeFormatErr), zap.Uint32("cmd", env.Cmd))
+		return nil, EnvelopeFormatErr
+	}
+	data, err := json.Marshal(&jsonEnvelope{
+		Cmd:     env.Cmd,
+		MsgId:   env.MsgId,
+		SeqNo:   env.SeqNo,
+		AckId:   env.AckId,
+		Flags:   env.Flags,
+		Payload: env.Payload,
+	})
+	if err != nil {
+		logger.Error("JsonWsCode Packet Marshal err: ", zap.Error(err))
+		return nil, err
+	}
+	return session.flushFrame(data), nil
+}
+
+func (codec *JsonWsCode) UnPack(session *Session) (*Envelope, error) {
+	data, err := readWsMessage(session)
+	if err != nil {
+		return nil, err
+	}
+	var msg jsonEnvelope
+	if err = json.Unmarshal(data, &msg); err != nil {
+		logger.Error("JsonWsCode UnPack Unmarshal err: ", zap.Error(err))
+		return nil, err
+	}
+	if string(msg.Payload) == "null" {
+		msg.Payload = nil
+	}
+	return &Envelope{
+		Cmd:     msg.Cmd,
+		MsgId:   msg.MsgId,
+		SeqNo:   msg.SeqNo,
+		AckId:   msg.AckId,
+		Flags:   msg.Flags,
+		Payload: msg.Payload,
+		Format:  FormatJSON,
+	}, nil
+}
@@ -0,0 +1,130 @@
+package net_lib
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+const defaultCompressThreshold = 512
+
+var DecompressLenErr = errors.New("decompressed data exceeds max message size")
+var CompressorErr = errors.New("compressor not supported")
+
+//压缩算法，实现后通过RegisterCompressor注册
+type Compressor interface {
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	//解压后的数据超过maxSize时返回DecompressLenErr，maxSize为0时不限制
+	Decompress(data []byte, maxSize uint32) ([]byte, error)
+}
+
+var (
+	compressors     = make(map[string]Compressor)
+	compressorMutex sync.RWMutex
+)
+
+func init() {
+	RegisterCompressor(gzipCompressor{})
+	RegisterCompressor(deflateCompressor{})
+}
+
+func RegisterCompressor(c Compressor) {
+	compressorMutex.Lock()
+	defer compressorMutex.Unlock()
+	compressors[c.Name()] = c
+}
+
+func GetCompressor(name string) Compressor {
+	compressorMutex.RLock()
+	defer compressorMutex.RUnlock()
+	return compressors[name]
+}
+
+//按服务端配置的顺序选择第一个客户端也支持的压缩算法
+func NegotiateCompressor(offered, allowed []string) Compressor {
+	for _, name := range allowed {
+		for _, item := range offered {
+			if strings.TrimSpace(item) == name {
+				if c := GetCompressor(name); c != nil {
+					return c
+				}
+			}
+		}
+	}
+	return nil
+}
+
+//最多读取maxSize字节，超过时返回lenErr
+func readLimited(r io.Reader, maxSize uint32, lenErr error) ([]byte, error) {
+	if maxSize == 0 {
+		return ioutil.ReadAll(r)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if uint32(len(data)) > maxSize {
+		return nil, lenErr
+	}
+	return data, nil
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string {
+	return "gzip"
+}
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte, maxSize uint32) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readLimited(r, maxSize, DecompressLenErr)
+}
+
+type deflateCompressor struct{}
+
+func (deflateCompressor) Name() string {
+	return "deflate"
+}
+
+func (deflateCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (deflateCompressor) Decompress(data []byte, maxSize uint32) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	return readLimited(r, maxSize, DecompressLenErr)
+}
@@ -0,0 +1,125 @@
+package net_lib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+//msgId(8) + seqNo(4) + ackId(8) + cmd(4) + flags(4)，全部为小端
+const EnvelopeHeadLen = 28
+
+//信封的标识
+const (
+	EnvelopeNeedAck  = 1 << 0 //需要对端回复ack
+	EnvelopeResponse = 1 << 1 //是对AckId的响应
+)
+
+//消息体的编码格式，websocket连接可以通过子协议选择json
+type WireFormat int8
+
+const (
+	FormatProto WireFormat = iota
+	FormatJSON
+)
+
+func (f WireFormat) String() string {
+	if f == FormatJSON {
+		return "json"
+	}
+	return "proto"
+}
+
+var EnvelopeLenErr = errors.New("envelope length error")
+var EnvelopeFormatErr = errors.New("envelope payload format mismatch")
+
+var jsonMarshaler = &jsonpb.Marshaler{OrigName: true}
+var jsonUnmarshaler = &jsonpb.Unmarshaler{AllowUnknownFields: true}
+
+//所有传输方式共用的消息结构，编解码器负责分帧、压缩和加密
+type Envelope struct {
+	Cmd     uint32 //命令号
+	MsgId   uint64 //消息id
+	SeqNo   uint32 //序列号
+	AckId   uint64 //响应或确认的消息id
+	Flags   uint32
+	Payload []byte     //按Format编码的消息体
+	Format  WireFormat //不参与编码，由codec根据会话的格式设置
+}
+
+func NewEnvelope(cmd uint32, msg proto.Message) (*Envelope, error) {
+	return NewFormatEnvelope(FormatProto, cmd, msg)
+}
+
+//使用指定的格式编码消息体
+func NewFormatEnvelope(format WireFormat, cmd uint32, msg proto.Message) (*Envelope, error) {
+	env := &Envelope{Cmd: cmd, Format: format}
+	if msg == nil {
+		return env, nil
+	}
+	if format == FormatJSON {
+		var buf bytes.Buffer
+		if err := jsonMarshaler.Marshal(&buf, msg); err != nil {
+			return nil, err
+		}
+		env.Payload = buf.Bytes()
+		return env, nil
+	}
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	env.Payload = payload
+	return env, nil
+}
+
+//将消息体解析为protobuf结构
+func (env *Envelope) Unmarshal(msg proto.Message) error {
+	if env.Format == FormatJSON {
+		if len(env.Payload) == 0 {
+			msg.Reset()
+			return nil
+		}
+		return jsonUnmarshaler.Unmarshal(bytes.NewReader(env.Payload), msg)
+	}
+	return proto.Unmarshal(env.Payload, msg)
+}
+
+//生成对该消息的响应，格式与该消息相同
+func (env *Envelope) Reply(cmd uint32, msg proto.Message) (*Envelope, error) {
+	resp, err := NewFormatEnvelope(env.Format, cmd, msg)
+	if err != nil {
+		return nil, err
+	}
+	resp.AckId = env.MsgId
+	resp.Flags |= EnvelopeResponse
+	return resp, nil
+}
+
+func (env *Envelope) Marshal() []byte {
+	buf := make([]byte, EnvelopeHeadLen+len(env.Payload))
+	binary.LittleEndian.PutUint64(buf[0:8], env.MsgId)
+	binary.LittleEndian.PutUint32(buf[8:12], env.SeqNo)
+	binary.LittleEndian.PutUint64(buf[12:20], env.AckId)
+	binary.LittleEndian.PutUint32(buf[20:24], env.Cmd)
+	binary.LittleEndian.PutUint32(buf[24:28], env.Flags)
+	copy(buf[EnvelopeHeadLen:], env.Payload)
+	return buf
+}
+
+func UnmarshalEnvelope(data []byte) (*Envelope, error) {
+	if len(data) < EnvelopeHeadLen {
+		return nil, EnvelopeLenErr
+	}
+	return &Envelope{
+		MsgId:   binary.LittleEndian.Uint64(data[0:8]),
+		SeqNo:   binary.LittleEndian.Uint32(data[8:12]),
+		AckId:   binary.LittleEndian.Uint64(data[12:20]),
+		Cmd:     binary.LittleEndian.Uint32(data[20:24]),
+		Flags:   binary.LittleEndian.Uint32(data[24:28]),
+		Payload: data[EnvelopeHeadLen:],
+	}, nil
+}
@@ -0,0 +1,119 @@
+package net_lib
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+//tcp帧格式(版本1):
+//
+//	magic/version(1) | flags(1) | length(varint) | authKeyId(8) | msgKey(16, 加密时) | data
+//
+//length为authKeyId之后所有字节的长度。旧版本的帧为 length(4字节小端) | authKeyId(8) | msgKey(16) | data，
+//新帧的前4字节按旧格式解析时长度不小于512KB，所以maxMsgSize小于512KB时可以准确区分新旧帧。
+//
+//带FlagControl的帧为控制帧，不交给上层处理，内容为每行一个的key=value，目前用于握手时协商压缩算法:
+//
+//	客户端: compress=gzip,deflate
+//	服务端: compress=gzip
+const (
+	FrameMagic   = 0xE0 //高4位为魔数
+	FrameVersion = 1    //低4位为版本号
+
+	FlagEncrypted     = 1 << 0 //数据已加密，帧中带msgKey
+	FlagCompressed    = 1 << 1 //数据已压缩
+	FlagMoreFragments = 1 << 2 //后面还有分片
+	FlagControl       = 1 << 3 //控制帧
+	flagPriorityShift = 6
+	flagPriorityMask  = 3 << flagPriorityShift //优先级(0-3)
+
+	legacyFrameMin = 512 << 10
+)
+
+var FrameVersionErr = errors.New("unsupported frame version")
+var FrameFlagErr = errors.New("unsupported frame flags")
+
+type FrameHeader struct {
+	Version uint8
+	Flags   uint8
+	Length  uint32
+}
+
+func (h *FrameHeader) Encrypted() bool {
+	return h.Flags&FlagEncrypted != 0
+}
+
+func (h *FrameHeader) Compressed() bool {
+	return h.Flags&FlagCompressed != 0
+}
+
+func (h *FrameHeader) MoreFragments() bool {
+	return h.Flags&FlagMoreFragments != 0
+}
+
+func (h *FrameHeader) Control() bool {
+	return h.Flags&FlagControl != 0
+}
+
+func (h *FrameHeader) Priority() uint8 {
+	return (h.Flags & flagPriorityMask) >> flagPriorityShift
+}
+
+//设置优先级(0-3)
+func WithPriority(flags uint8, priority uint8) uint8 {
+	return flags&^flagPriorityMask | (priority<<flagPriorityShift)&flagPriorityMask
+}
+
+//判断是否为旧版本的帧头，head至少为帧的前4个字节
+func IsLegacyFrame(head []byte, maxMsgSize uint32) bool {
+	if len(head) < 4 || head[0]&0xF0 != FrameMagic {
+		return true
+	}
+	length := binary.LittleEndian.Uint32(head[0:4])
+	return length < legacyFrameMin && maxMsgSize > 0 && length <= maxMsgSize
+}
+
+func (w *Writer) WriteFrameHeader(flags uint8, length int) {
+	w.WriteByte(FrameMagic | FrameVersion)
+	w.WriteByte(flags)
+	w.WriteUvarint(uint64(length))
+}
+
+func (r *Reader) ReadFrameHeader() (*FrameHeader, error) {
+	head, err := r.ReadN(2)
+	if err != nil {
+		return nil, err
+	}
+	if head[0]&0xF0 != FrameMagic || head[0]&0x0F != FrameVersion {
+		return nil, FrameVersionErr
+	}
+	length, err := r.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if length > 1<<32-1 {
+		return nil, DataLenErr
+	}
+	return &FrameHeader{Version: head[0] & 0x0F, Flags: head[1], Length: uint32(length)}, nil
+}
+
+//解析控制帧的内容
+func ParseControl(data []byte) map[string]string {
+	result := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) == 2 {
+			result[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return result
+}
+
+func FormatControl(values map[string]string) []byte {
+	w := new(Writer)
+	for k, v := range values {
+		w.WriteStrings(k, "=", v, "\n")
+	}
+	return w.Bytes()
+}
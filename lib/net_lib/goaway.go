@@ -0,0 +1,110 @@
+package net_lib
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+)
+
+//tcp控制帧中GOAWAY的key，值为原因，客户端收到后应当重新连接
+const ControlGoAway = "goaway"
+
+const (
+	GoAwayRestart  = "restart"
+	GoAwayShutdown = "shutdown"
+)
+
+//通知客户端服务即将关闭，已经收到的消息仍然会处理:
+//tcp发送goaway控制帧，websocket发送1012(重启)或1001(关闭)的关闭帧，
+//http在下一个响应中返回Connection: close。没有完成握手或使用旧帧格式的连接直接关闭
+func (session *Session) GoAway(reason string) error {
+	if session.IsClosed() {
+		return SessionClosedErr
+	}
+	session.SetWaite()
+	if !session.Handshaked() || (session.connType == TCP && session.legacyFrame) {
+		return session.CloseWithReason(CloseServerStop, nil)
+	}
+	var err error
+	switch session.connType {
+	case TCP:
+		err = ProtoTcp.WriteControl(session, map[string]string{ControlGoAway: reason})
+	case WS:
+		code := CloseGoingAway
+		if reason == GoAwayRestart {
+			code = CloseServiceRestart
+		}
+		var buf []byte
+		if buf, err = wsControlFrame(CloseMessage, FormatCloseMessage(code, reason)); err == nil {
+			err = session.Write(buf)
+		}
+	}
+	if err != nil {
+		session.closeOnError(err, CloseWriteErr)
+	}
+	return err
+}
+
+func (manager *Manager) countSession(delta int64) {
+	atomic.AddInt64(&manager.count, delta)
+}
+
+//未关闭的连接数，包括登录和未登录的
+func (manager *Manager) Count() int {
+	return int(atomic.LoadInt64(&manager.count))
+}
+
+//遍历所有连接，f返回false时停止。遍历时不持有锁，f中可以关闭连接
+func (manager *Manager) Range(f func(session *Session) bool) {
+	var sessions []*Session
+	for i := 0; i < sessionMapNum; i++ {
+		smap := &manager.sessionMaps[i]
+		smap.RLock()
+		for _, session := range smap.sessions {
+			sessions = append(sessions, session)
+		}
+		smap.RUnlock()
+		lsMap := &manager.loginSessionMaps[i]
+		lsMap.RLock()
+		for _, userSessionMap := range lsMap.sessions {
+			for _, session := range userSessionMap {
+				sessions = append(sessions, session)
+			}
+		}
+		lsMap.RUnlock()
+	}
+	for _, session := range sessions {
+		if !f(session) {
+			return
+		}
+	}
+}
+
+//向所有连接发送GOAWAY
+func (manager *Manager) GoAway(reason string) {
+	manager.Range(func(session *Session) bool {
+		session.GoAway(reason)
+		return true
+	})
+}
+
+//平滑关闭: 停止接受新连接，向所有连接发送GOAWAY，
+//等待客户端断开或超时后关闭服务，返回超时时被强制关闭的连接数
+func (server *Server) Drain(reason string, timeout time.Duration) int {
+	server.listenerMutex.Lock()
+	for _, item := range server.listeners {
+		item.listener.Close()
+	}
+	server.listenerMutex.Unlock()
+	server.manager.GoAway(reason)
+	deadline := time.Now().Add(timeout)
+	for server.manager.Count() > 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	remain := server.manager.Count()
+	logger.Info("Server drain", zap.String("reason", reason), zap.Int("remain", remain))
+	server.Stop()
+	return remain
+}
@@ -0,0 +1,144 @@
+package net_lib
+
+import (
+	"io"
+	"net"
+	"sync"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+)
+
+//连接关闭的原因
+type CloseReason int8
+
+const (
+	CloseNormal       CloseReason = iota //调用Close主动关闭
+	ClosePeer                            //对端关闭连接
+	CloseTimeout                         //读写超时
+	CloseReadErr                         //读取或解码失败
+	CloseWriteErr                        //写入或编码失败
+	CloseHandshakeErr                    //握手失败
+	CloseReplaced                        //同一用户相同类型的新连接顶替
+	CloseServerStop                      //服务关闭
+	CloseKicked                          //被业务层踢下线
+)
+
+var closeReasonNames = [...]string{"normal", "peer", "timeout", "read_error", "write_error",
+	"handshake_error", "replaced", "server_stop", "kicked"}
+
+func (r CloseReason) String() string {
+	if int(r) < len(closeReasonNames) {
+		return closeReasonNames[r]
+	}
+	return "unknown"
+}
+
+//根据读写错误判断关闭原因
+func closeReasonOf(err error, def CloseReason) CloseReason {
+	if err == io.EOF || err == io.ErrUnexpectedEOF || err == errClientClose {
+		return ClosePeer
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return CloseTimeout
+	}
+	return def
+}
+
+//连接生命周期的回调，通过Server.SetHooks注册。
+//回调在单独的协程中执行，不会阻塞读写；同一个连接的事件按发生顺序回调。
+type Hooks interface {
+	OnAccept(session *Session)                               //接受连接
+	OnHandshake(session *Session)                            //确定连接类型和编解码器
+	OnBind(session *Session, userId uint64)                  //连接绑定用户
+	OnUnbind(session *Session, userId uint64)                //连接解绑用户，绑定的连接关闭时先回调
+	OnClose(session *Session, reason CloseReason, err error) //连接关闭，err为导致关闭的错误
+	OnError(session *Session, err error)                     //读写或编解码出错，不包括对端正常关闭
+}
+
+//空实现，嵌入后只需实现关心的回调
+type NopHooks struct{}
+
+func (NopHooks) OnAccept(*Session)                    {}
+func (NopHooks) OnHandshake(*Session)                 {}
+func (NopHooks) OnBind(*Session, uint64)              {}
+func (NopHooks) OnUnbind(*Session, uint64)            {}
+func (NopHooks) OnClose(*Session, CloseReason, error) {}
+func (NopHooks) OnError(*Session, error)              {}
+
+const hookWorkerNum = 8
+
+//按连接id分到固定的协程执行，队列不限长度，保证投递不阻塞
+type hookDispatcher struct {
+	hooks   Hooks
+	once    sync.Once
+	workers [hookWorkerNum]hookWorker
+}
+
+type hookWorker struct {
+	sync.Mutex
+	cond   *sync.Cond
+	queue  []func()
+	closed bool
+}
+
+func newHookDispatcher(hooks Hooks) *hookDispatcher {
+	d := &hookDispatcher{hooks: hooks}
+	for i := range d.workers {
+		w := &d.workers[i]
+		w.cond = sync.NewCond(w)
+		go w.loop()
+	}
+	return d
+}
+
+func (w *hookWorker) loop() {
+	for {
+		w.Lock()
+		for len(w.queue) == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if len(w.queue) == 0 {
+			w.Unlock()
+			return
+		}
+		queue := w.queue
+		w.queue = nil
+		w.Unlock()
+		for _, f := range queue {
+			w.call(f)
+		}
+	}
+}
+
+func (w *hookWorker) call(f func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Error("hook panic", zap.Any("err", err))
+		}
+	}()
+	f()
+}
+
+func (d *hookDispatcher) post(session *Session, f func(hooks Hooks)) {
+	w := &d.workers[session.id%hookWorkerNum]
+	w.Lock()
+	if !w.closed {
+		w.queue = append(w.queue, func() { f(d.hooks) })
+		w.cond.Signal()
+	}
+	w.Unlock()
+}
+
+//执行完已投递的回调后退出
+func (d *hookDispatcher) close() {
+	d.once.Do(func() {
+		for i := range d.workers {
+			w := &d.workers[i]
+			w.Lock()
+			w.closed = true
+			w.cond.Signal()
+			w.Unlock()
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package net_lib
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordHooks struct {
+	sync.Mutex
+	events map[uint64][]string
+	closed chan uint64
+}
+
+func (h *recordHooks) add(session *Session, event string) {
+	h.Lock()
+	h.events[session.Id()] = append(h.events[session.Id()], event)
+	h.Unlock()
+}
+
+func (h *recordHooks) get(session *Session) []string {
+	h.Lock()
+	defer h.Unlock()
+	return h.events[session.Id()]
+}
+
+func (h *recordHooks) OnAccept(session *Session)    { h.add(session, "accept") }
+func (h *recordHooks) OnHandshake(session *Session) { h.add(session, "handshake") }
+func (h *recordHooks) OnBind(session *Session, userId uint64) {
+	h.add(session, fmt.Sprintf("bind:%d", userId))
+}
+func (h *recordHooks) OnUnbind(session *Session, userId uint64) {
+	h.add(session, fmt.Sprintf("unbind:%d", userId))
+}
+func (h *recordHooks) OnClose(session *Session, reason CloseReason, err error) {
+	h.add(session, "close:"+reason.String())
+	h.closed <- session.Id()
+}
+func (h *recordHooks) OnError(session *Session, err error) { h.add(session, "error") }
+
+func acceptTcp(t *testing.T, server *Server) (*Session, net.Conn) {
+	conn, err := net.Dial("tcp", server.Listener().Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := server.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := new(Writer)
+	data := testEnvelope().Marshal()
+	w.WriteFrameHeader(0, 8+len(data))
+	w.Write(make([]byte, 8), data)
+	if _, err = conn.Write(w.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err = session.InitCodec(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = session.Receive(); err != nil {
+		t.Fatal(err)
+	}
+	return session, conn
+}
+
+func waitClosed(t *testing.T, h *recordHooks, session *Session) {
+	t.Helper()
+	for {
+		select {
+		case id := <-h.closed:
+			if id == session.Id() {
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("session %d close hook not called", session.Id())
+		}
+	}
+}
+
+func checkEvents(t *testing.T, h *recordHooks, session *Session, want ...string) {
+	t.Helper()
+	if got := h.get(session); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("session %d events: want %v, got %v", session.Id(), want, got)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	server, err := Serve("tcp", "127.0.0.1:0", &SessionCfg{MaxMsgSize: 64 << 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &recordHooks{events: make(map[uint64][]string), closed: make(chan uint64, 8)}
+	server.SetHooks(h)
+
+	first, _ := acceptTcp(t, server)
+	first.Bind(42)
+	second, conn := acceptTcp(t, server)
+	second.Bind(42)
+	waitClosed(t, h, first)
+	checkEvents(t, h, first, "accept", "handshake", "bind:42", "unbind:42", "close:replaced")
+	if sessions := server.Manager().GetSessionMapByUid(42); sessions[TCP] != second {
+		t.Fatalf("want the new session bound, got %v", sessions)
+	}
+
+	conn.Close()
+	if _, err = second.Receive(); err == nil {
+		t.Fatal("want receive error after peer close")
+	}
+	waitClosed(t, h, second)
+	checkEvents(t, h, second, "accept", "handshake", "bind:42", "unbind:42", "close:peer")
+	if sessions := server.Manager().GetSessionMapByUid(42); len(sessions) != 0 {
+		t.Fatalf("want no sessions bound, got %v", sessions)
+	}
+
+	third, _ := acceptTcp(t, server)
+	server.Stop()
+	waitClosed(t, h, third)
+	checkEvents(t, h, third, "accept", "handshake", "close:server_stop")
+}
+
+//登录期间连接已关闭时不再绑定，不回调OnBind，也不会顶替同一用户的连接
+func TestBindAfterClose(t *testing.T) {
+	server, err := Serve("tcp", "127.0.0.1:0", &SessionCfg{MaxMsgSize: 64 << 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	h := &recordHooks{events: make(map[uint64][]string), closed: make(chan uint64, 8)}
+	server.SetHooks(h)
+
+	online, _ := acceptTcp(t, server)
+	online.Bind(42)
+	closed, _ := acceptTcp(t, server)
+	closed.Close()
+	waitClosed(t, h, closed)
+	if closed.Bind(42) {
+		t.Fatal("want bind refused after close")
+	}
+	closed.Unbind()
+	if sessions := server.Manager().GetSessionMapByUid(42); len(sessions) != 1 || sessions[TCP] != online {
+		t.Fatalf("want only the live session bound, got %v", sessions)
+	}
+	if online.IsClosed() {
+		t.Fatal("live session replaced by a closed one")
+	}
+	if session := server.Manager().GetSessionByConnId(closed.Id()); session != nil {
+		t.Fatal("closed session left in the session map")
+	}
+	checkEvents(t, h, closed, "accept", "handshake", "close:normal")
+}
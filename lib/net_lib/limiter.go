@@ -0,0 +1,310 @@
+package net_lib
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var BanTargetErr = errors.New("[limiter] ban target must be an ip or cidr")
+var ClientRejectedErr = errors.New("[limiter] client ip banned or over limit")
+
+//连接数限制，值为0时不限制。来自受信任代理的连接在accept时只受全局限制和封禁列表约束，
+//握手解析出客户端地址后再按客户端地址检查封禁和单个ip的限制
+type LimitCfg struct {
+	MaxConns         int      `yaml:"maxConns"`         //全局最大连接数
+	MaxConnsPerIp    int      `yaml:"maxConnsPerIp"`    //单个ip的最大并发连接数
+	AcceptRatePerIp  float64  `yaml:"acceptRatePerIp"`  //单个ip每秒最多建立的连接数
+	AcceptBurstPerIp int      `yaml:"acceptBurstPerIp"` //单个ip允许的突发连接数，默认等于acceptRatePerIp
+	Bans             []string `yaml:"bans"`             //永久封禁的ip或cidr
+}
+
+//当前连接数和各原因被拒绝的连接数
+type LimitStats struct {
+	Conns          int64  //当前连接数
+	Banned         uint64 //在封禁列表中
+	OverMaxConns   uint64 //超过全局最大连接数
+	OverIpConns    uint64 //超过单个ip的并发连接数
+	OverAcceptRate uint64 //超过单个ip的建连速率
+}
+
+type Ban struct {
+	Target string    //ip或cidr
+	Expire time.Time //为零值时永久封禁
+}
+
+//在Accept之后、创建会话之前检查连接，被拒绝的连接直接关闭
+type Limiter struct {
+	cfg   LimitCfg
+	conns int64
+	stats LimitStats
+
+	ipMutex   sync.Mutex
+	ips       map[string]*ipState
+	lastSweep time.Time
+
+	banMutex sync.RWMutex
+	banIps   map[string]Ban //单个ip
+	banNets  map[string]*banNet
+}
+
+type ipState struct {
+	conns  int
+	tokens float64
+	last   time.Time
+}
+
+type banNet struct {
+	ipNet *net.IPNet
+	ban   Ban
+}
+
+const limiterSweepInterval = time.Minute
+
+func NewLimiter(cfg LimitCfg) (*Limiter, error) {
+	if cfg.AcceptRatePerIp > 0 && cfg.AcceptBurstPerIp <= 0 {
+		cfg.AcceptBurstPerIp = int(cfg.AcceptRatePerIp)
+		if cfg.AcceptBurstPerIp < 1 {
+			cfg.AcceptBurstPerIp = 1
+		}
+	}
+	limiter := &Limiter{
+		cfg:       cfg,
+		ips:       make(map[string]*ipState),
+		lastSweep: time.Now(),
+		banIps:    make(map[string]Ban),
+		banNets:   make(map[string]*banNet),
+	}
+	for _, target := range cfg.Bans {
+		if err := limiter.Ban(target, 0); err != nil {
+			return nil, err
+		}
+	}
+	return limiter, nil
+}
+
+//检查是否允许该ip建立连接，允许时计入连接数，连接关闭后需要调用release
+func (limiter *Limiter) allow(ip string) bool {
+	if !limiter.allowShared(ip) {
+		return false
+	}
+	if counter := limiter.acquireIp(ip); counter != nil {
+		return limiter.reject(counter)
+	}
+	return true
+}
+
+//受信任代理转发的连接在握手后按客户端地址检查，全局连接数已经在accept时计入。
+//允许时计入该ip的连接数，连接关闭后以该ip调用release
+func (limiter *Limiter) allowClient(ip string) bool {
+	if limiter.IsBanned(ip) {
+		atomic.AddUint64(&limiter.stats.Banned, 1)
+		return false
+	}
+	if counter := limiter.acquireIp(ip); counter != nil {
+		atomic.AddUint64(counter, 1)
+		return false
+	}
+	return true
+}
+
+//检查单个ip的并发连接数和建连速率，允许时计入该ip的连接数并返回nil，拒绝时返回对应的拒绝计数
+func (limiter *Limiter) acquireIp(ip string) *uint64 {
+	//unix socket等没有ip的连接只受全局限制
+	if (limiter.cfg.MaxConnsPerIp <= 0 && limiter.cfg.AcceptRatePerIp <= 0) || net.ParseIP(ip) == nil {
+		return nil
+	}
+	now := time.Now()
+	limiter.ipMutex.Lock()
+	defer limiter.ipMutex.Unlock()
+	if now.Sub(limiter.lastSweep) > limiterSweepInterval {
+		limiter.sweep(now)
+	}
+	state := limiter.ips[ip]
+	if state == nil {
+		state = &ipState{tokens: float64(limiter.cfg.AcceptBurstPerIp), last: now}
+		limiter.ips[ip] = state
+	}
+	if limiter.cfg.MaxConnsPerIp > 0 && state.conns >= limiter.cfg.MaxConnsPerIp {
+		return &limiter.stats.OverIpConns
+	}
+	if limiter.cfg.AcceptRatePerIp > 0 {
+		state.refill(now, limiter.cfg.AcceptRatePerIp, limiter.cfg.AcceptBurstPerIp)
+		if state.tokens < 1 {
+			return &limiter.stats.OverAcceptRate
+		}
+		state.tokens--
+	}
+	state.conns++
+	return nil
+}
+
+//只检查封禁和全局连接数，用于受信任代理转发的连接：代理后面的客户端共用代理的ip，
+//不能按代理的ip限制。客户端地址由allowClient检查
+func (limiter *Limiter) allowShared(ip string) bool {
+	if limiter.IsBanned(ip) {
+		atomic.AddUint64(&limiter.stats.Banned, 1)
+		return false
+	}
+	conns := atomic.AddInt64(&limiter.conns, 1)
+	if limiter.cfg.MaxConns > 0 && conns > int64(limiter.cfg.MaxConns) {
+		return limiter.reject(&limiter.stats.OverMaxConns)
+	}
+	return true
+}
+
+//撤销allow中计入的连接数并增加对应的拒绝计数
+func (limiter *Limiter) reject(counter *uint64) bool {
+	atomic.AddInt64(&limiter.conns, -1)
+	atomic.AddUint64(counter, 1)
+	return false
+}
+
+func (limiter *Limiter) release(ip string) {
+	atomic.AddInt64(&limiter.conns, -1)
+	if (limiter.cfg.MaxConnsPerIp <= 0 && limiter.cfg.AcceptRatePerIp <= 0) || net.ParseIP(ip) == nil {
+		return
+	}
+	limiter.ipMutex.Lock()
+	if state := limiter.ips[ip]; state != nil && state.conns > 0 {
+		state.conns--
+	}
+	limiter.ipMutex.Unlock()
+}
+
+func (state *ipState) refill(now time.Time, rate float64, burst int) {
+	state.tokens += now.Sub(state.last).Seconds() * rate
+	if state.tokens > float64(burst) {
+		state.tokens = float64(burst)
+	}
+	state.last = now
+}
+
+//删除没有连接且令牌已满的ip，调用时需持有ipMutex
+func (limiter *Limiter) sweep(now time.Time) {
+	limiter.lastSweep = now
+	for ip, state := range limiter.ips {
+		if state.conns != 0 {
+			continue
+		}
+		if limiter.cfg.AcceptRatePerIp > 0 {
+			state.refill(now, limiter.cfg.AcceptRatePerIp, limiter.cfg.AcceptBurstPerIp)
+			if state.tokens < float64(limiter.cfg.AcceptBurstPerIp) {
+				continue
+			}
+		}
+		delete(limiter.ips, ip)
+	}
+}
+
+//封禁ip或cidr，ttl为0时永久封禁，重复封禁时更新过期时间
+func (limiter *Limiter) Ban(target string, ttl time.Duration) error {
+	target = strings.TrimSpace(target)
+	ban := Ban{Target: target}
+	if ttl > 0 {
+		ban.Expire = time.Now().Add(ttl)
+	}
+	limiter.banMutex.Lock()
+	defer limiter.banMutex.Unlock()
+	limiter.pruneBans(time.Now())
+	if ip := net.ParseIP(target); ip != nil {
+		ban.Target = ip.String()
+		limiter.banIps[ban.Target] = ban
+		return nil
+	}
+	_, ipNet, err := net.ParseCIDR(target)
+	if err != nil {
+		return BanTargetErr
+	}
+	ban.Target = ipNet.String()
+	limiter.banNets[ban.Target] = &banNet{ipNet: ipNet, ban: ban}
+	return nil
+}
+
+//解除封禁，target需要与封禁时相同，返回是否存在
+func (limiter *Limiter) Unban(target string) bool {
+	target = strings.TrimSpace(target)
+	if ip := net.ParseIP(target); ip != nil {
+		target = ip.String()
+	} else if _, ipNet, err := net.ParseCIDR(target); err == nil {
+		target = ipNet.String()
+	}
+	limiter.banMutex.Lock()
+	defer limiter.banMutex.Unlock()
+	if _, ok := limiter.banIps[target]; ok {
+		delete(limiter.banIps, target)
+		return true
+	}
+	if _, ok := limiter.banNets[target]; ok {
+		delete(limiter.banNets, target)
+		return true
+	}
+	return false
+}
+
+//未过期的封禁列表
+func (limiter *Limiter) Bans() []Ban {
+	limiter.banMutex.Lock()
+	limiter.pruneBans(time.Now())
+	bans := make([]Ban, 0, len(limiter.banIps)+len(limiter.banNets))
+	for _, ban := range limiter.banIps {
+		bans = append(bans, ban)
+	}
+	for _, item := range limiter.banNets {
+		bans = append(bans, item.ban)
+	}
+	limiter.banMutex.Unlock()
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Target < bans[j].Target })
+	return bans
+}
+
+func (limiter *Limiter) IsBanned(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	now := time.Now()
+	limiter.banMutex.RLock()
+	defer limiter.banMutex.RUnlock()
+	if ban, ok := limiter.banIps[parsed.String()]; ok && !ban.expired(now) {
+		return true
+	}
+	for _, item := range limiter.banNets {
+		if !item.ban.expired(now) && item.ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+//删除已过期的封禁，调用时需持有banMutex的写锁
+func (limiter *Limiter) pruneBans(now time.Time) {
+	for target, ban := range limiter.banIps {
+		if ban.expired(now) {
+			delete(limiter.banIps, target)
+		}
+	}
+	for target, item := range limiter.banNets {
+		if item.ban.expired(now) {
+			delete(limiter.banNets, target)
+		}
+	}
+}
+
+func (ban Ban) expired(now time.Time) bool {
+	return !ban.Expire.IsZero() && now.After(ban.Expire)
+}
+
+func (limiter *Limiter) Stats() LimitStats {
+	return LimitStats{
+		Conns:          atomic.LoadInt64(&limiter.conns),
+		Banned:         atomic.LoadUint64(&limiter.stats.Banned),
+		OverMaxConns:   atomic.LoadUint64(&limiter.stats.OverMaxConns),
+		OverIpConns:    atomic.LoadUint64(&limiter.stats.OverIpConns),
+		OverAcceptRate: atomic.LoadUint64(&limiter.stats.OverAcceptRate),
+	}
+}
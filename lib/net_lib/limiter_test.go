@@ -0,0 +1,249 @@
+package net_lib
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLimiterConns(t *testing.T) {
+	limiter, err := NewLimiter(LimitCfg{MaxConns: 3, MaxConnsPerIp: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !limiter.allow("10.0.0.1") || !limiter.allow("10.0.0.1") {
+		t.Fatal("want first two connections allowed")
+	}
+	if limiter.allow("10.0.0.1") {
+		t.Fatal("want per ip limit")
+	}
+	if !limiter.allow("10.0.0.2") {
+		t.Fatal("want other ip allowed")
+	}
+	if limiter.allow("10.0.0.3") {
+		t.Fatal("want global limit")
+	}
+	limiter.release("10.0.0.1")
+	if !limiter.allow("10.0.0.1") {
+		t.Fatal("want connection allowed after release")
+	}
+	stats := limiter.Stats()
+	if stats.Conns != 3 || stats.OverIpConns != 1 || stats.OverMaxConns != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestLimiterAcceptRate(t *testing.T) {
+	limiter, err := NewLimiter(LimitCfg{AcceptRatePerIp: 100, AcceptBurstPerIp: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !limiter.allow("10.0.0.1") || !limiter.allow("10.0.0.1") {
+		t.Fatal("want burst allowed")
+	}
+	if limiter.allow("10.0.0.1") {
+		t.Fatal("want accept rate limit")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if !limiter.allow("10.0.0.1") {
+		t.Fatal("want token refilled")
+	}
+	if stats := limiter.Stats(); stats.OverAcceptRate != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestLimiterBans(t *testing.T) {
+	limiter, err := NewLimiter(LimitCfg{Bans: []string{"192.168.0.0/16"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = limiter.Ban("10.0.0.1", 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err = limiter.Ban("not an ip", 0); err != BanTargetErr {
+		t.Fatalf("want BanTargetErr, got %v", err)
+	}
+	if limiter.allow("192.168.3.4") || limiter.allow("10.0.0.1") {
+		t.Fatal("want banned ip rejected")
+	}
+	if len(limiter.Bans()) != 2 {
+		t.Fatalf("unexpected bans %v", limiter.Bans())
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !limiter.allow("10.0.0.1") {
+		t.Fatal("want ban expired")
+	}
+	if !limiter.Unban("192.168.0.0/16") || limiter.Unban("192.168.0.0/16") {
+		t.Fatal("want unban once")
+	}
+	if !limiter.allow("192.168.3.4") || len(limiter.Bans()) != 0 {
+		t.Fatal("want ban removed")
+	}
+	if stats := limiter.Stats(); stats.Banned != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestServerLimit(t *testing.T) {
+	server, err := Serve("tcp", "127.0.0.1:0", &SessionCfg{MaxMsgSize: 64 << 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	if err = server.SetLimit(LimitCfg{MaxConnsPerIp: 1}); err != nil {
+		t.Fatal(err)
+	}
+	addr := server.Listener().Addr().String()
+	first, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	session, err := server.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := make(chan *Session, 1)
+	go func() {
+		session, _ := server.Accept()
+		accepted <- session
+	}()
+	//第一个连接未关闭时第二个连接被直接关闭
+	rejected, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rejected.Close()
+	rejected.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err = rejected.Read(make([]byte, 1)); err == nil {
+		t.Fatal("want rejected connection closed")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("rejected connection not closed")
+	}
+	session.Close()
+	third, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer third.Close()
+	select {
+	case session = <-accepted:
+		if session == nil {
+			t.Fatal("want third connection accepted")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("third connection not accepted")
+	}
+	if stats := server.Limiter().Stats(); stats.OverIpConns != 1 || stats.Conns != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+//负载均衡后面的客户端共用代理的ip，不受单个ip的限制，全局限制和封禁仍然生效
+func TestServerLimitTrustedProxy(t *testing.T) {
+	server, err := Serve("tcp", "127.0.0.1:0", &SessionCfg{MaxMsgSize: 64 << 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	if err = server.SetTrustedProxies([]string{"127.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = server.SetLimit(LimitCfg{MaxConns: 2, MaxConnsPerIp: 1, AcceptRatePerIp: 1}); err != nil {
+		t.Fatal(err)
+	}
+	addr := server.Listener().Addr().String()
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err = server.Accept(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//超过全局限制的连接被直接关闭
+	rejected, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rejected.Close()
+	rejected.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err = rejected.Read(make([]byte, 1)); err == nil {
+		t.Fatal("want rejected connection closed")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("rejected connection not closed")
+	}
+	stats := server.Limiter().Stats()
+	if stats.OverIpConns != 0 || stats.OverAcceptRate != 0 || stats.OverMaxConns != 1 || stats.Conns != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if len(server.Limiter().ips) != 0 {
+		t.Fatal("want no per ip state for trusted proxy")
+	}
+
+	if err = server.Limiter().Ban("127.0.0.1", 0); err != nil {
+		t.Fatal(err)
+	}
+	if server.Limiter().allowShared("127.0.0.1") {
+		t.Fatal("want banned proxy rejected")
+	}
+}
+
+//经过PROXY协议转发的连接握手后按客户端地址检查封禁和单个ip的限制
+func TestServerLimitProxyClient(t *testing.T) {
+	server, err := Serve("tcp", "127.0.0.1:0", &SessionCfg{MaxMsgSize: 64 << 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	if err = server.SetTrustedProxies([]string{"127.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = server.SetLimit(LimitCfg{MaxConnsPerIp: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = server.Limiter().Ban("192.0.2.1", 0); err != nil {
+		t.Fatal(err)
+	}
+	addr := server.Listener().Addr().String()
+	dial := func(client string) (*Session, error) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		header := proxyV2Header(1, 0x11, proxyV2Addr(net.ParseIP(client).To4(), net.ParseIP("127.0.0.1").To4(), 5678, 443))
+		if _, err = conn.Write(append(header, "GET / HTTP/1.1\r\nHost: zwchat\r\n\r\n"...)); err != nil {
+			t.Fatal(err)
+		}
+		session, err := server.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return session, session.InitCodec()
+	}
+	if _, err = dial("192.0.2.1"); err != ClientRejectedErr {
+		t.Fatalf("want banned client rejected, got %v", err)
+	}
+	first, err := dial("192.0.2.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.RemoteIp != "192.0.2.2" {
+		t.Fatalf("want client ip from PROXY header, got %s", first.RemoteIp)
+	}
+	if _, err = dial("192.0.2.2"); err != ClientRejectedErr {
+		t.Fatalf("want client over per ip limit rejected, got %v", err)
+	}
+	first.Close()
+	if _, err = dial("192.0.2.2"); err != nil {
+		t.Fatalf("want per ip count released on close, got %v", err)
+	}
+	stats := server.Limiter().Stats()
+	if stats.Banned != 1 || stats.OverIpConns != 1 || stats.Conns != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,154 @@
+package net_lib
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+var ListenerProtoErr = errors.New("[listener] unknown protocol")
+var ProtoNotAllowedErr = errors.New("[session] protocol not allowed on this listener")
+
+//监听地址的配置，同一个Server的所有监听共用一个Manager
+type ListenerCfg struct {
+	Name    string   `yaml:"name"`
+	Network string   `yaml:"network"` //tcp(默认)、tcp4、tcp6、unix
+	Addr    string   `yaml:"addr"`
+	Protos  []string `yaml:"protos"` //允许的协议: tcp、http、ws，为空时全部允许
+	TLS     *TLSCfg  `yaml:"tls"`
+	//覆盖Server的会话配置，只覆盖非零值
+	SessionCfg *SessionCfg `yaml:"sessionCfg"`
+}
+
+type TLSCfg struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+var protoNames = map[string]int8{"tcp": TCP, "http": HTTP, "ws": WS}
+
+//协议名转为连接类型的位集合，0表示全部允许
+func parseProtos(protos []string) (uint8, error) {
+	var mask uint8
+	for _, name := range protos {
+		connType, ok := protoNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return 0, ListenerProtoErr
+		}
+		mask |= 1 << uint8(connType)
+	}
+	return mask, nil
+}
+
+//按配置创建监听，unix socket会先删除遗留的socket文件
+func (cfg *ListenerCfg) Listen() (net.Listener, error) {
+	l, _, err := cfg.listen()
+	return l, err
+}
+
+func (cfg *ListenerCfg) network() string {
+	if cfg.Network == "" {
+		return "tcp"
+	}
+	return cfg.Network
+}
+
+//返回的raw为tls包装之前的监听，平滑升级时传递它的文件描述符
+func (cfg *ListenerCfg) listen() (l net.Listener, raw net.Listener, err error) {
+	var tlsConfig *tls.Config
+	if cfg.TLS != nil {
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	if raw, err = listen(cfg.network(), cfg.Addr); err != nil {
+		return nil, nil, err
+	}
+	l = raw
+	if tlsConfig != nil {
+		l = tls.NewListener(raw, tlsConfig)
+	}
+	return l, raw, nil
+}
+
+//和net.Listen相同，由Upgrade启动的进程优先使用从旧进程继承的监听
+func Listen(network, addr string) (net.Listener, error) {
+	return listen(network, addr)
+}
+
+func listen(network, addr string) (net.Listener, error) {
+	if l := inheritListener(network, addr); l != nil {
+		return l, nil
+	}
+	if network == "unix" {
+		if info, err := os.Stat(addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(addr)
+		}
+	}
+	return net.Listen(network, addr)
+}
+
+//检查数值范围和压缩算法是否已注册
+func (cfg SessionCfg) Validate() error {
+	if cfg.ReadDeadLine < 0 || cfg.WriteDeadLine < 0 || cfg.MaxAttempts < 0 || cfg.Duration < 0 ||
+		cfg.Interval < 0 || cfg.Count < 0 || cfg.CompressThreshold < 0 {
+		return fmt.Errorf("session config: negative value")
+	}
+	for _, name := range cfg.Compress {
+		if GetCompressor(name) == nil {
+			return fmt.Errorf("session config: compressor %q not supported", name)
+		}
+	}
+	return nil
+}
+
+//用override中的非零值覆盖cfg
+func (cfg SessionCfg) Override(override *SessionCfg) SessionCfg {
+	if override == nil {
+		return cfg
+	}
+	if override.ReadDeadLine != 0 {
+		cfg.ReadDeadLine = override.ReadDeadLine
+	}
+	if override.WriteDeadLine != 0 {
+		cfg.WriteDeadLine = override.WriteDeadLine
+	}
+	if override.MaxMsgSize != 0 {
+		cfg.MaxMsgSize = override.MaxMsgSize
+	}
+	if override.MaxAttempts != 0 {
+		cfg.MaxAttempts = override.MaxAttempts
+	}
+	if override.Duration != 0 {
+		cfg.Duration = override.Duration
+	}
+	if override.Interval != 0 {
+		cfg.Interval = override.Interval
+	}
+	if override.Count != 0 {
+		cfg.Count = override.Count
+	}
+	if override.Compress != nil {
+		cfg.Compress = override.Compress
+	}
+	if override.CompressThreshold != 0 {
+		cfg.CompressThreshold = override.CompressThreshold
+	}
+	return cfg
+}
+
+type listener struct {
+	name       string
+	network    string //配置的网络和地址，新进程按它们匹配继承的监听
+	addr       string
+	listener   net.Listener
+	raw        net.Listener //tls包装之前的监听
+	protos     uint8
+	override   *SessionCfg //监听配置中的会话配置
+	sessionCfg SessionCfg  //合并后的配置，由Server的listenerMutex保护
+}
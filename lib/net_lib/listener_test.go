@@ -0,0 +1,223 @@
+package net_lib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func acceptSession(t *testing.T, server *Server) *Session {
+	t.Helper()
+	accepted := make(chan *Session, 1)
+	go func() {
+		session, _ := server.Accept()
+		accepted <- session
+	}()
+	select {
+	case session := <-accepted:
+		if session == nil {
+			t.Fatal("accept failed")
+		}
+		return session
+	case <-time.After(2 * time.Second):
+		t.Fatal("accept timeout")
+	}
+	return nil
+}
+
+func writeTcpFrame(t *testing.T, conn net.Conn) {
+	t.Helper()
+	w := new(Writer)
+	data := testEnvelope().Marshal()
+	w.WriteFrameHeader(0, 8+len(data))
+	w.Write(make([]byte, 8), data)
+	if _, err := conn.Write(w.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServeListeners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zwchat-listener")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "access.sock")
+	server, err := ServeListeners([]*ListenerCfg{
+		{Name: "mobile", Addr: "127.0.0.1:0", Protos: []string{"tcp"}},
+		{Name: "sidecar", Network: "unix", Addr: sock, Protos: []string{"tcp"},
+			SessionCfg: &SessionCfg{MaxMsgSize: 1 << 20}},
+	}, &SessionCfg{MaxMsgSize: 64 << 10, ReadDeadLine: 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	if len(server.Listeners()) != 2 {
+		t.Fatalf("want 2 listeners, got %d", len(server.Listeners()))
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	session := acceptSession(t, server)
+	if session.cfg.MaxMsgSize != 1<<20 || session.cfg.ReadDeadLine != 10 {
+		t.Fatalf("session config not overridden: %+v", session.cfg)
+	}
+	writeTcpFrame(t, conn)
+	if err = session.InitCodec(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = session.Receive(); err != nil {
+		t.Fatal(err)
+	}
+
+	//只允许tcp的监听拒绝websocket握手
+	ws, err := net.Dial("tcp", server.Listener().Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	ws.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	session = acceptSession(t, server)
+	if err = session.InitCodec(); err != ProtoNotAllowedErr {
+		t.Fatalf("want ProtoNotAllowedErr, got %v", err)
+	}
+	if !session.IsClosed() {
+		t.Fatal("want session closed")
+	}
+
+	server.Stop()
+	if _, err = server.Accept(); err == nil {
+		t.Fatal("want accept error after stop")
+	}
+}
+
+//修改后的配置只对新连接生效，监听配置仍然覆盖
+func TestSetSessionCfg(t *testing.T) {
+	server, err := ServeListeners([]*ListenerCfg{
+		{Name: "mobile", Addr: "127.0.0.1:0"},
+		{Name: "web", Addr: "127.0.0.1:0", SessionCfg: &SessionCfg{ReadDeadLine: 300}},
+	}, &SessionCfg{MaxMsgSize: 64 << 10, ReadDeadLine: 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	dial := func(i int) *Session {
+		conn, err := net.Dial("tcp", server.Listeners()[i].Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return acceptSession(t, server)
+	}
+	old := dial(0)
+	server.SetSessionCfg(SessionCfg{MaxMsgSize: 1 << 20, ReadDeadLine: 20})
+	if cfg := server.SessionCfg(); cfg.MaxMsgSize != 1<<20 {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if cfg := dial(0).Config(); cfg.MaxMsgSize != 1<<20 || cfg.ReadDeadLine != 20 {
+		t.Fatalf("new config not applied: %+v", cfg)
+	}
+	if cfg := dial(1).Config(); cfg.MaxMsgSize != 1<<20 || cfg.ReadDeadLine != 300 {
+		t.Fatalf("listener config not overridden: %+v", cfg)
+	}
+	if cfg := old.Config(); cfg.MaxMsgSize != 64<<10 {
+		t.Fatalf("existing session changed: %+v", cfg)
+	}
+	if err = (SessionCfg{Compress: []string{"br"}}).Validate(); err == nil {
+		t.Fatal("unknown compressor should fail")
+	}
+	if err = (SessionCfg{ReadDeadLine: -1}).Validate(); err == nil {
+		t.Fatal("negative value should fail")
+	}
+}
+
+func TestTlsListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zwchat-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCert(t, dir)
+	server, err := ServeListeners([]*ListenerCfg{
+		{Name: "tls", Addr: "127.0.0.1:0", TLS: &TLSCfg{CertFile: certFile, KeyFile: keyFile}},
+	}, &SessionCfg{MaxMsgSize: 64 << 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	//服务端在第一次读取时才完成tls握手，客户端需要在另一个协程中连接
+	dialErr := make(chan error, 1)
+	go func() {
+		conn, err := tls.Dial("tcp", server.Listener().Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			dialErr <- err
+			return
+		}
+		defer conn.Close()
+		data := testEnvelope().Marshal()
+		w := new(Writer)
+		w.WriteFrameHeader(0, 8+len(data))
+		w.Write(make([]byte, 8), data)
+		_, err = conn.Write(w.Bytes())
+		dialErr <- err
+		conn.Read(make([]byte, 1))
+	}()
+	session := acceptSession(t, server)
+	if err = session.InitCodec(); err != nil {
+		t.Fatal(err)
+	}
+	env, err := session.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEnvelope(t, testEnvelope(), env)
+	if err = <-dialErr; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
@@ -3,6 +3,7 @@ package net_lib
 import (
 	"net"
 	"sync"
+	"sync/atomic"
 )
 
 const sessionMapNum = 32
@@ -10,10 +11,17 @@ const sessionMapNum = 32
 type Manager struct {
 	sessionMaps      [sessionMapNum]sessionMap      //未登录的连接
 	loginSessionMaps [sessionMapNum]loginSessionMap //登陆后的连接
-	disposeFlag      bool
+	roomMaps         [sessionMapNum]roomMap         //按房间id的哈希分片
+	disposeFlag      int32                          //连接可能在其他协程中关闭，需要原子读写
 	disposeOnce      sync.Once
 	disposeWait      sync.WaitGroup
+	shareKeyFunc     ShareKeyFunc
+	hooks            *hookDispatcher
+	count            int64 //未关闭的连接数
 }
+
+//根据authKeyId查找共享密钥，找不到时返回nil
+type ShareKeyFunc func(authKeyId []byte) []byte
 type loginSessionMap struct {
 	sessions map[uint64]map[int8]*Session
 	sync.RWMutex
@@ -29,6 +37,7 @@ func NewManager() *Manager {
 	for i := 0; i < sessionMapNum; i++ {
 		manager.sessionMaps[i].sessions = make(map[uint64]*Session)
 		manager.loginSessionMaps[i].sessions = make(map[uint64]map[int8]*Session)
+		manager.roomMaps[i].rooms = make(map[string]map[uint64]*Session)
 	}
 	return manager
 }
@@ -39,26 +48,51 @@ func (manager *Manager) NewSession(conn net.Conn, defaultCodec Codec, sendChanSi
 	return session
 }
 
+func (manager *Manager) newPollSession(conn net.Conn, defaultCodec Codec, cfg SessionCfg) *Session {
+	session := newBaseSession(manager, conn, defaultCodec, cfg)
+	manager.putSession(session)
+	return session
+}
+
+//设置会话没有共享密钥时的查找函数
+func (manager *Manager) SetShareKeyFunc(f ShareKeyFunc) {
+	manager.shareKeyFunc = f
+}
+
+//注册生命周期回调，需要在接受连接之前设置
+func (manager *Manager) SetHooks(hooks Hooks) {
+	if manager.hooks != nil {
+		manager.hooks.close()
+		manager.hooks = nil
+	}
+	if hooks != nil {
+		manager.hooks = newHookDispatcher(hooks)
+	}
+}
+
 func (manager *Manager) Dispose() {
 	manager.disposeOnce.Do(func() {
-		manager.disposeFlag = true
+		atomic.StoreInt32(&manager.disposeFlag, 1)
 		for i := 0; i < sessionMapNum; i++ {
 			smap := &manager.sessionMaps[i]
 			smap.Lock()
 			for _, session := range smap.sessions {
-				session.Close()
+				session.CloseWithReason(CloseServerStop, nil)
 			}
 			smap.Unlock()
 			lsMap := &manager.loginSessionMaps[i]
 			lsMap.Lock()
 			for _, userSessionMap := range lsMap.sessions {
 				for _, session := range userSessionMap {
-					session.Close()
+					session.CloseWithReason(CloseServerStop, nil)
 				}
 			}
 			lsMap.Unlock()
 		}
 		manager.disposeWait.Wait()
+		if manager.hooks != nil {
+			manager.hooks.close()
+		}
 	})
 }
 
@@ -76,6 +110,7 @@ func (manager *Manager) putSession(session *Session) {
 	defer smap.Unlock()
 	smap.sessions[session.id] = session
 	manager.disposeWait.Add(1)
+	manager.countSession(1)
 }
 
 func (manager *Manager) GetSessionMapByUid(uid uint64) map[int8]*Session {
@@ -86,38 +121,129 @@ func (manager *Manager) GetSessionMapByUid(uid uint64) map[int8]*Session {
 	return session
 }
 
-func (manager *Manager) putLoginSession(session *Session) {
+//用户所有连接的快照，不在线时返回nil
+func (manager *Manager) UserSessions(uid uint64) []*Session {
+	smap := &manager.loginSessionMaps[uid%sessionMapNum]
+	smap.RLock()
+	defer smap.RUnlock()
+	userSessionMap := smap.sessions[uid]
+	if len(userSessionMap) == 0 {
+		return nil
+	}
+	sessions := make([]*Session, 0, len(userSessionMap))
+	for _, session := range userSessionMap {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+//按连接id查找，已登录的连接需要提供uid
+func (manager *Manager) GetSession(uid, sessionId uint64) *Session {
+	if uid == 0 {
+		return manager.GetSessionByConnId(sessionId)
+	}
+	for _, session := range manager.UserSessions(uid) {
+		if session.id == sessionId {
+			return session
+		}
+	}
+	return nil
+}
+
+//绑定用户，同一用户相同连接类型的旧连接会被关闭。
+//连接已关闭时不绑定并返回false，避免关闭后的连接留在登录表中
+func (manager *Manager) Bind(session *Session, userId uint64) bool {
+	if userId == 0 {
+		return false
+	}
+	session.bindMutex.Lock()
+	if session.IsClosed() {
+		session.bindMutex.Unlock()
+		return false
+	}
+	if session.userId == userId {
+		session.bindMutex.Unlock()
+		return true
+	}
+	if session.userId != 0 {
+		manager.unbind(session)
+	}
 	smap := &manager.sessionMaps[session.id%sessionMapNum]
 	smap.Lock()
-	defer smap.Unlock()
 	delete(smap.sessions, session.id)
+	smap.Unlock()
+	lsmap := &manager.loginSessionMaps[userId%sessionMapNum]
+	lsmap.Lock()
+	userSessionMap := lsmap.sessions[userId]
+	if userSessionMap == nil {
+		userSessionMap = make(map[int8]*Session)
+		lsmap.sessions[userId] = userSessionMap
+	}
+	replaced := userSessionMap[session.connType]
+	userSessionMap[session.connType] = session
+	session.userId = userId
+	lsmap.Unlock()
+	session.emit(func(hooks Hooks) { hooks.OnBind(session, userId) })
+	session.bindMutex.Unlock()
+	if replaced != nil && replaced != session {
+		replaced.CloseWithReason(CloseReplaced, nil)
+	}
+	return true
+}
+
+//解除用户绑定，连接回到未登录状态。已关闭的连接由delSession解绑
+func (manager *Manager) Unbind(session *Session) {
+	session.bindMutex.Lock()
+	defer session.bindMutex.Unlock()
+	if !session.IsClosed() {
+		manager.unbind(session)
+	}
+}
+
+func (manager *Manager) unbind(session *Session) {
+	userId := session.userId
+	if userId == 0 {
+		return
+	}
+	manager.delLoginSession(session)
+	session.userId = 0
+	smap := &manager.sessionMaps[session.id%sessionMapNum]
+	smap.Lock()
+	smap.sessions[session.id] = session
+	smap.Unlock()
+	session.emit(func(hooks Hooks) { hooks.OnUnbind(session, userId) })
+}
+
+//只删除该连接，避免删掉顶替它的新连接
+func (manager *Manager) delLoginSession(session *Session) {
 	lsmap := &manager.loginSessionMaps[session.userId%sessionMapNum]
 	lsmap.Lock()
 	defer lsmap.Unlock()
-	if lsmap.sessions[session.userId] == nil {
-		userSessionMap :=  map[int8]*Session{session.connType: session}
-		lsmap.sessions[session.userId] = userSessionMap
-	} else {
-		lsmap.sessions[session.userId][session.connType] = session
+	userSessionMap := lsmap.sessions[session.userId]
+	if userSessionMap[session.connType] == session {
+		delete(userSessionMap, session.connType)
+	}
+	if len(userSessionMap) == 0 {
+		delete(lsmap.sessions, session.userId)
 	}
-	manager.disposeWait.Add(1)
 }
 
+//关闭标识已设置，之后的Bind不再生效
 func (manager *Manager) delSession(session *Session) {
-	if manager.disposeFlag == true {
-		manager.disposeWait.Done()
-		return
-	}
-	if session.userId == 0 {
+	manager.leaveRooms(session)
+	session.bindMutex.Lock()
+	if userId := session.userId; userId != 0 {
+		if atomic.LoadInt32(&manager.disposeFlag) == 0 {
+			manager.delLoginSession(session)
+		}
+		session.emit(func(hooks Hooks) { hooks.OnUnbind(session, userId) })
+	} else if atomic.LoadInt32(&manager.disposeFlag) == 0 {
 		smap := &manager.sessionMaps[session.id%sessionMapNum]
 		smap.Lock()
-		defer smap.Unlock()
 		delete(smap.sessions, session.id)
-	} else {
-		smap := &manager.loginSessionMaps[session.userId%sessionMapNum]
-		smap.Lock()
-		defer smap.Unlock()
-		delete(smap.sessions, session.userId)
+		smap.Unlock()
 	}
+	session.bindMutex.Unlock()
+	manager.countSession(-1)
 	manager.disposeWait.Done()
 }
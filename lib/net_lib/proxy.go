@@ -0,0 +1,180 @@
+package net_lib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	proxyV1Prefix  = "PROXY "
+	proxyV1MaxLen  = 107 //v1头部最大长度(包含\r\n)
+	proxyV2HeadLen = 16
+)
+
+//v2协议的12字节签名
+var proxyV2Sig = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+var ProxyProtoErr = errors.New("[proxy] invalid PROXY protocol header")
+
+//受信任的代理地址段
+type TrustedProxies []*net.IPNet
+
+//解析受信任的代理地址，支持CIDR和单个ip
+func ParseTrustedProxies(cidrs []string) (TrustedProxies, error) {
+	result := make(TrustedProxies, 0, len(cidrs))
+	for _, item := range cidrs {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, errors.New("[proxy] invalid trusted proxy: " + item)
+			}
+			if ip.To4() != nil {
+				item += "/32"
+			} else {
+				item += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ipNet)
+	}
+	return result, nil
+}
+
+//ip是否在受信任的地址段内
+func (t TrustedProxies) Contains(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, ipNet := range t {
+		if ipNet.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+//拆分地址为ip和port，兼容ipv6和unix socket
+func SplitAddr(addr string) (string, string) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, ""
+	}
+	return host, port
+}
+
+//解析PROXY协议头(v1/v2)，没有协议头时ok为false且不消耗数据
+func ReadProxyHeader(r *Reader) (ip, port string, ok bool, err error) {
+	head, _ := r.Peek(len(proxyV2Sig))
+	if bytes.HasPrefix(head, []byte(proxyV1Prefix)) {
+		return readProxyV1(r)
+	}
+	if bytes.Equal(head, proxyV2Sig) {
+		return readProxyV2(r)
+	}
+	return "", "", false, nil
+}
+
+//PROXY TCP4 255.255.255.255 255.255.255.255 65535 65535\r\n
+func readProxyV1(r *Reader) (ip, port string, ok bool, err error) {
+	line, err := r.r.ReadSlice('\n')
+	if err != nil && err != bufio.ErrBufferFull {
+		return "", "", false, err
+	}
+	if len(line) > proxyV1MaxLen || !bytes.HasSuffix(line, []byte("\r\n")) {
+		logger.Error("readProxyV1 header too long or unterminated")
+		return "", "", false, ProxyProtoErr
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return "", "", false, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		logger.Error("readProxyV1 fields error", zap.String("header", string(line)))
+		return "", "", false, ProxyProtoErr
+	}
+	srcIp := net.ParseIP(fields[2])
+	if srcIp == nil || (fields[1] == "TCP4") != (srcIp.To4() != nil) {
+		return "", "", false, ProxyProtoErr
+	}
+	if _, err = strconv.ParseUint(fields[4], 10, 16); err != nil {
+		return "", "", false, ProxyProtoErr
+	}
+	return srcIp.String(), fields[4], true, nil
+}
+
+//12字节签名 + 版本/命令(1) + 地址族/协议(1) + 地址长度(2) + 地址
+func readProxyV2(r *Reader) (ip, port string, ok bool, err error) {
+	head, err := r.ReadN(proxyV2HeadLen)
+	if err != nil {
+		return "", "", false, err
+	}
+	verCmd, family := head[12], head[13]
+	length := int(binary.BigEndian.Uint16(head[14:16]))
+	if verCmd>>4 != 2 {
+		logger.Error("readProxyV2 version error", zap.Uint8("verCmd", verCmd))
+		return "", "", false, ProxyProtoErr
+	}
+	addr, err := r.ReadN(length)
+	if err != nil {
+		return "", "", false, err
+	}
+	//LOCAL命令，例如代理自身的健康检查，使用连接的真实地址
+	if verCmd&0x0f == 0 {
+		return "", "", false, nil
+	}
+	if verCmd&0x0f != 1 {
+		return "", "", false, ProxyProtoErr
+	}
+	switch family >> 4 {
+	case 1: //AF_INET
+		if length < 12 {
+			return "", "", false, ProxyProtoErr
+		}
+		return net.IP(addr[0:4]).String(), strconv.Itoa(int(binary.BigEndian.Uint16(addr[8:10]))), true, nil
+	case 2: //AF_INET6
+		if length < 36 {
+			return "", "", false, ProxyProtoErr
+		}
+		return net.IP(addr[0:16]).String(), strconv.Itoa(int(binary.BigEndian.Uint16(addr[32:34]))), true, nil
+	}
+	//AF_UNSPEC和AF_UNIX不携带ip地址
+	return "", "", false, nil
+}
+
+//从X-Forwarded-For中由右向左取第一个不受信任的地址，没有时取X-Real-IP
+func forwardedIp(trusted TrustedProxies, forwardedFor, realIp string) string {
+	if forwardedFor != "" {
+		hops := strings.Split(forwardedFor, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, _ := SplitAddr(strings.TrimSpace(hops[i]))
+			hop = strings.Trim(hop, "[]")
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if i == 0 || !trusted.Contains(hop) {
+				return hop
+			}
+		}
+	}
+	realIp = strings.TrimSpace(realIp)
+	if net.ParseIP(realIp) != nil {
+		return realIp
+	}
+	return ""
+}
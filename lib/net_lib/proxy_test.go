@@ -0,0 +1,188 @@
+package net_lib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+func proxyReader(data []byte) *Reader {
+	return NewReader(bufio.NewReader(bytes.NewReader(data)))
+}
+
+func readRest(r *Reader) string {
+	rest, _ := ioutil.ReadAll(r.r)
+	return string(rest)
+}
+
+//cmd为0(LOCAL)或1(PROXY)，family为地址族/协议字节
+func proxyV2Header(cmd, family byte, addr []byte) []byte {
+	head := append([]byte(nil), proxyV2Sig...)
+	head = append(head, 0x20|cmd, family, 0, 0)
+	binary.BigEndian.PutUint16(head[14:16], uint16(len(addr)))
+	return append(head, addr...)
+}
+
+func proxyV2Addr(src, dst net.IP, srcPort, dstPort uint16) []byte {
+	addr := append(append([]byte(nil), src...), dst...)
+	ports := make([]byte, 4)
+	binary.BigEndian.PutUint16(ports[0:2], srcPort)
+	binary.BigEndian.PutUint16(ports[2:4], dstPort)
+	return append(addr, ports...)
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	v4 := proxyV2Addr(net.ParseIP("192.0.2.1").To4(), net.ParseIP("192.0.2.2").To4(), 5678, 443)
+	v6 := proxyV2Addr(net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2"), 5678, 443)
+	for _, c := range []struct {
+		name     string
+		data     []byte
+		ip, port string
+		ok       bool
+		err      bool
+		rest     string //协议头之后剩余的数据
+	}{
+		{name: "v1 tcp4", data: []byte("PROXY TCP4 192.0.2.1 192.0.2.2 5678 443\r\nhello"),
+			ip: "192.0.2.1", port: "5678", ok: true, rest: "hello"},
+		{name: "v1 tcp6", data: []byte("PROXY TCP6 2001:db8::1 2001:db8::2 5678 443\r\nhello"),
+			ip: "2001:db8::1", port: "5678", ok: true, rest: "hello"},
+		{name: "v1 unknown", data: []byte("PROXY UNKNOWN\r\nhello"), rest: "hello"},
+		{name: "v1 unknown with addresses", data: []byte("PROXY UNKNOWN ffff::1 ffff::2 1 2\r\nhello"), rest: "hello"},
+		{name: "v1 family mismatch", data: []byte("PROXY TCP4 2001:db8::1 2001:db8::2 5678 443\r\n"), err: true},
+		{name: "v1 bad port", data: []byte("PROXY TCP4 192.0.2.1 192.0.2.2 70000 443\r\n"), err: true},
+		{name: "v1 missing fields", data: []byte("PROXY TCP4 192.0.2.1 192.0.2.2\r\n"), err: true},
+		{name: "v1 unterminated", data: append([]byte("PROXY TCP4 "), bytes.Repeat([]byte("1"), 200)...), err: true},
+		{name: "v2 proxy tcp4", data: append(proxyV2Header(1, 0x11, v4), "hello"...),
+			ip: "192.0.2.1", port: "5678", ok: true, rest: "hello"},
+		{name: "v2 proxy tcp6", data: append(proxyV2Header(1, 0x21, v6), "hello"...),
+			ip: "2001:db8::1", port: "5678", ok: true, rest: "hello"},
+		//LOCAL命令的地址块也要跳过
+		{name: "v2 local", data: append(proxyV2Header(0, 0x11, v4), "hello"...), rest: "hello"},
+		{name: "v2 unspec", data: append(proxyV2Header(1, 0x00, nil), "hello"...), rest: "hello"},
+		{name: "v2 short tcp4 block", data: proxyV2Header(1, 0x11, v4[:8]), err: true},
+		{name: "v2 short tcp6 block", data: proxyV2Header(1, 0x21, v4), err: true},
+		{name: "v2 truncated block", data: proxyV2Header(1, 0x11, v4)[:proxyV2HeadLen+6], err: true},
+		{name: "v2 bad version", data: append(append([]byte(nil), proxyV2Sig...), 0x11, 0x11, 0, 0), err: true},
+		{name: "v2 bad command", data: proxyV2Header(2, 0x11, v4), err: true},
+		{name: "no header", data: []byte("GET / HTTP/1.1\r\n"), rest: "GET / HTTP/1.1\r\n"},
+	} {
+		r := proxyReader(c.data)
+		ip, port, ok, err := ReadProxyHeader(r)
+		if (err != nil) != c.err {
+			t.Fatalf("%s: unexpected error %v", c.name, err)
+		}
+		if c.err {
+			continue
+		}
+		if ip != c.ip || port != c.port || ok != c.ok {
+			t.Fatalf("%s: got %s:%s %v", c.name, ip, port, ok)
+		}
+		if rest := readRest(r); rest != c.rest {
+			t.Fatalf("%s: want rest %q, got %q", c.name, c.rest, rest)
+		}
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{" 10.0.0.0/8 ", "", "192.0.2.1", "2001:db8::/32", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proxies) != 4 {
+		t.Fatalf("want 4 networks, got %d", len(proxies))
+	}
+	for ip, want := range map[string]bool{
+		"10.1.2.3":    true,
+		"192.0.2.1":   true,
+		"192.0.2.2":   false,
+		"2001:db8::5": true,
+		"::1":         true,
+		"::2":         false,
+		"11.0.0.1":    false,
+		"not an ip":   false,
+	} {
+		if got := proxies.Contains(ip); got != want {
+			t.Fatalf("%s: want %v, got %v", ip, want, got)
+		}
+	}
+	for _, item := range []string{"10.0.0.0/33", "example.com", "1.2.3"} {
+		if _, err = ParseTrustedProxies([]string{item}); err == nil {
+			t.Fatalf("%s should fail", item)
+		}
+	}
+}
+
+func TestForwardedIp(t *testing.T) {
+	trusted, _ := ParseTrustedProxies([]string{"10.0.0.0/8", "2001:db8::/32"})
+	for _, c := range []struct {
+		name, forwardedFor, realIp, want string
+	}{
+		{"single hop", "203.0.113.7", "", "203.0.113.7"},
+		//由右向左跳过受信任的代理，第一个不受信任的就是客户端，更左边的可能是伪造的
+		{"trusted tail", "198.51.100.1, 203.0.113.7, 10.0.0.2, 10.0.0.1", "", "203.0.113.7"},
+		{"untrusted in middle", "10.0.0.3, 203.0.113.7, 10.0.0.1", "", "203.0.113.7"},
+		{"all trusted", "10.0.0.3, 10.0.0.2", "", "10.0.0.3"},
+		{"with ports", "203.0.113.7:1234, [2001:db8::1]:443", "", "203.0.113.7"},
+		{"ipv6 client", "2001:db9::7, 2001:db8::1", "", "2001:db9::7"},
+		{"garbage hop stops", "203.0.113.7, unknown, 10.0.0.1", "198.51.100.9", "198.51.100.9"},
+		{"real ip only", "", " 198.51.100.9 ", "198.51.100.9"},
+		{"nothing valid", "unknown", "bad", ""},
+	} {
+		if got := forwardedIp(trusted, c.forwardedFor, c.realIp); got != c.want {
+			t.Fatalf("%s: want %q, got %q", c.name, c.want, got)
+		}
+	}
+}
+
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c addrConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+//只有受信任的代理发来的PROXY头和X-Forwarded-For才会被采用
+func TestSessionProxyTrust(t *testing.T) {
+	trusted, _ := ParseTrustedProxies([]string{"10.0.0.1"})
+	header := []byte("PROXY TCP4 192.0.2.1 192.0.2.2 5678 443\r\nhello")
+	for _, c := range []struct {
+		peer        string
+		ip, port    string
+		rest        string
+		forwardedIp string
+	}{
+		{"10.0.0.1:4000", "192.0.2.1", "5678", "hello", "192.0.2.1"},
+		{"198.51.100.1:4000", "198.51.100.1", "4000", string(header), "198.51.100.1"},
+	} {
+		conn, peer := net.Pipe()
+		addr, _ := net.ResolveTCPAddr("tcp", c.peer)
+		session := newSession(nil, addrConn{Conn: conn, remote: addr}, nil, 0, SessionCfg{})
+		session.SetTrustedProxies(trusted)
+		setInput(session, header)
+		if err := session.readProxyHeader(); err != nil {
+			t.Fatalf("%s: %v", c.peer, err)
+		}
+		if session.RemoteIp != c.ip || session.RemotePort != c.port {
+			t.Fatalf("%s: got %s:%s", c.peer, session.RemoteIp, session.RemotePort)
+		}
+		//不受信任时协议头原样留给后续解析
+		if rest := readRest(session.r); rest != c.rest {
+			t.Fatalf("%s: want rest %q, got %q", c.peer, c.rest, rest)
+		}
+		session.setForwardedIp("203.0.113.7", "")
+		want := c.forwardedIp
+		if session.proxies.Contains(session.peerIp) {
+			want = "203.0.113.7"
+		}
+		if session.RemoteIp != want {
+			t.Fatalf("%s: want forwarded ip %s, got %s", c.peer, want, session.RemoteIp)
+		}
+		conn.Close()
+		peer.Close()
+	}
+}
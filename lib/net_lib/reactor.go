@@ -0,0 +1,426 @@
+package net_lib
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+)
+
+var ReactorUnsupportedErr = errors.New("[reactor] epoll reactor is not supported")
+var ReactorStartedErr = errors.New("[reactor] server already started")
+var pollReadTimeoutErr = errors.New("[reactor] read timeout")
+var pollWriteTimeoutErr = errors.New("[reactor] write timeout")
+
+const (
+	defaultReactorReadBuf   = 64 << 10
+	defaultReactorMaxBuffer = 4 << 20
+)
+
+//epoll模式的配置，零值使用默认值
+type ReactorCfg struct {
+	Loops       int `yaml:"loops"`       //epoll实例数量，默认1
+	Workers     int `yaml:"workers"`     //执行编解码和Handler的协程数，默认CPU数
+	ReadBufSize int `yaml:"readBufSize"` //每次从socket读取的字节数，默认64K
+	MaxBuffer   int `yaml:"maxBuffer"`   //单个连接未处理的读数据和未发出的写数据的上限，默认4M
+}
+
+func (cfg ReactorCfg) withDefault() ReactorCfg {
+	if cfg.Loops <= 0 {
+		cfg.Loops = 1
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.ReadBufSize <= 0 {
+		cfg.ReadBufSize = defaultReactorReadBuf
+	}
+	if cfg.MaxBuffer <= 0 {
+		cfg.MaxBuffer = defaultReactorMaxBuffer
+	}
+	return cfg
+}
+
+//epoll模式下处理收到的消息，在工作协程中执行，同一个连接的消息按顺序处理。
+//工作协程由所有连接共用，Handler阻塞时其他连接的读写也会等待，需要等待下游的处理应交给单独的协程
+type Handler func(session *Session, env *Envelope)
+
+//由工作协程处理的读写事件
+type pollTask struct {
+	session  *Session
+	readable bool
+	writable bool
+}
+
+type reactor struct {
+	cfg       ReactorCfg
+	handler   Handler
+	loops     []*eventLoop
+	next      uint32
+	tasks     chan pollTask
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+func newReactor(cfg ReactorCfg, handler Handler) (*reactor, error) {
+	cfg = cfg.withDefault()
+	r := &reactor{
+		cfg:       cfg,
+		handler:   handler,
+		tasks:     make(chan pollTask, cfg.Workers*64),
+		closeChan: make(chan struct{}),
+	}
+	for i := 0; i < cfg.Loops; i++ {
+		loop, err := newEventLoop(r)
+		if err != nil {
+			r.close()
+			return nil, err
+		}
+		r.loops = append(r.loops, loop)
+		go loop.run()
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		go r.work()
+	}
+	return r, nil
+}
+
+//把握手前的连接加入epoll，无法取得文件描述符(如tls连接)时返回ReactorUnsupportedErr
+func (r *reactor) add(session *Session) error {
+	loop := r.loops[atomic.AddUint32(&r.next, 1)%uint32(len(r.loops))]
+	return loop.add(session)
+}
+
+func (r *reactor) close() {
+	r.closeOnce.Do(func() {
+		close(r.closeChan)
+	})
+}
+
+func (r *reactor) dispatch(task pollTask) {
+	select {
+	case r.tasks <- task:
+	case <-r.closeChan:
+	}
+}
+
+func (r *reactor) work() {
+	buf := make([]byte, r.cfg.ReadBufSize)
+	for {
+		select {
+		case task := <-r.tasks:
+			r.process(task, buf)
+		case <-r.closeChan:
+			return
+		}
+	}
+}
+
+func (r *reactor) process(task pollTask, buf []byte) {
+	session := task.session
+	pc := session.poll
+	if task.writable {
+		if err := pc.flush(); err != nil {
+			session.closeOnError(err, CloseWriteErr)
+			return
+		}
+	}
+	if task.readable {
+		eof, err := pc.readAll(buf, r.cfg.MaxBuffer)
+		if err == nil {
+			err = session.pollDecode(r.handler)
+		}
+		if err == nil && len(pc.inbound) >= r.cfg.MaxBuffer {
+			err = DataLenErr
+		}
+		if err == nil && eof {
+			err = io.EOF
+		}
+		if err != nil {
+			def := CloseReadErr
+			if !session.Handshaked() {
+				def = CloseHandshakeErr
+			}
+			session.closeOnError(err, def)
+			return
+		}
+	}
+	pc.done()
+}
+
+//没有注册到epoll的连接(如tls连接)退回每个连接一个读协程
+func (r *reactor) serveBlocking(session *Session) {
+	if err := session.InitCodec(); err != nil {
+		return
+	}
+	for {
+		env, err := session.Receive()
+		if err != nil {
+			return
+		}
+		r.handler(session, env)
+	}
+}
+
+//按ReadDeadLine和WriteDeadLine关闭空闲读和写阻塞的连接
+func (r *reactor) checkTimeout(session *Session, now int64) {
+	pc := session.poll
+	if session.cfg.ReadDeadLine > 0 && now-atomic.LoadInt64(&pc.lastRead) > int64(session.cfg.ReadDeadLine) {
+		session.closeOnError(pollReadTimeoutErr, CloseTimeout)
+		return
+	}
+	if since := atomic.LoadInt64(&pc.blockedSince); session.cfg.WriteDeadLine > 0 && since > 0 &&
+		now-since > int64(session.cfg.WriteDeadLine) {
+		session.closeOnError(pollWriteTimeoutErr, CloseTimeout)
+	}
+}
+
+//epoll模式下连接的读写状态。
+//读事件到达时数据追加到inbound，编解码器从inbound的副本中解码，
+//数据不完整时回滚到上一个完整的消息(或控制帧)，等待下一次读事件。
+type pollConn struct {
+	loop     *eventLoop
+	raw      syscall.RawConn
+	fd       int
+	inbound  []byte
+	src      *replaySource //正在解码的数据
+	buf      *bufio.Reader
+	mark     int    //已经处理完的字节数，回滚时保留
+	wsMark   WsConn //mark处的websocket读取状态
+	lastRead int64  //最后一次读到数据的时间(秒)
+
+	mutex        sync.Mutex
+	outbound     []byte
+	maxBuffer    int
+	processing   bool  //事件已经交给工作协程，epoll处于未监听状态
+	closed       bool  //已经从epoll中删除
+	blockedSince int64 //outbound非空的开始时间(秒)
+}
+
+func newPollConn(loop *eventLoop, raw syscall.RawConn, fd int, maxBuffer int) *pollConn {
+	return &pollConn{loop: loop, raw: raw, fd: fd, maxBuffer: maxBuffer, lastRead: time.Now().Unix()}
+}
+
+//读取socket中的数据直到没有数据或达到maxBuffer，对端关闭时eof为true。
+//没有读完的数据在重新监听后会再次触发读事件。
+func (pc *pollConn) readAll(buf []byte, maxBuffer int) (eof bool, err error) {
+	for len(pc.inbound) < maxBuffer {
+		n, err := pc.loop.read(pc, buf)
+		if n > 0 {
+			pc.inbound = append(pc.inbound, buf[:n]...)
+			atomic.StoreInt64(&pc.lastRead, time.Now().Unix())
+		}
+		if err == errPollAgain {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if n == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//写入的数据先直接写socket，写不完的部分在可写时由工作协程发送
+func (pc *pollConn) write(buf []byte) error {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	if len(pc.outbound) == 0 {
+		n, err := pc.loop.write(pc, buf)
+		if err != nil && err != errPollAgain {
+			return err
+		}
+		if buf = buf[n:]; len(buf) == 0 {
+			return nil
+		}
+		atomic.StoreInt64(&pc.blockedSince, time.Now().Unix())
+	}
+	if len(pc.outbound)+len(buf) > pc.maxBuffer {
+		return SessionBlockedErr
+	}
+	pc.outbound = append(pc.outbound, buf...)
+	if !pc.processing {
+		return pc.loop.arm(pc, true)
+	}
+	return nil
+}
+
+func (pc *pollConn) flush() error {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	for len(pc.outbound) > 0 {
+		n, err := pc.loop.write(pc, pc.outbound)
+		if err == errPollAgain {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		pc.outbound = pc.outbound[n:]
+	}
+	if len(pc.outbound) == 0 {
+		pc.outbound = nil
+		atomic.StoreInt64(&pc.blockedSince, 0)
+	}
+	return nil
+}
+
+//事件由event loop交给工作协程前调用，返回false表示该连接已经在处理中
+func (pc *pollConn) take() bool {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	if pc.processing {
+		return false
+	}
+	pc.processing = true
+	return true
+}
+
+//处理完成，重新监听读事件，有未发出的数据时同时监听写事件
+func (pc *pollConn) done() {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	pc.processing = false
+	pc.loop.arm(pc, len(pc.outbound) > 0)
+}
+
+//编解码器处理完不产生消息的控制帧后调用，回滚时不再重复处理
+func (session *Session) commitRead() {
+	pc := session.poll
+	if pc == nil || pc.src == nil {
+		return
+	}
+	pc.mark = pc.src.pos - pc.buf.Buffered()
+	if session.wsConn != nil {
+		pc.wsMark = *session.wsConn
+	}
+}
+
+//记录编解码时的读取错误。epoll模式下数据不完整是正常情况，回滚后等待更多数据，只记录调试日志
+func (session *Session) logReadErr(msg string, err error) {
+	if pc := session.poll; pc != nil && pc.src != nil && pc.src.starved {
+		logger.Debug(msg, zap.Uint64("session", session.id), zap.Error(err))
+		return
+	}
+	logger.Error(msg, zap.Error(err))
+}
+
+//从inbound中解码出所有完整的消息
+func (session *Session) pollDecode(handler Handler) error {
+	pc := session.poll
+	if len(pc.inbound) == 0 {
+		return nil
+	}
+	if !session.Handshaked() && httpHeaderPending(pc.inbound, session.cfg.MaxMsgSize) {
+		return nil
+	}
+	pc.src = &replaySource{data: pc.inbound}
+	pc.buf = bufio.NewReaderSize(pc.src, len(pc.inbound))
+	pc.mark = 0
+	session.r = NewReader(pc.buf)
+	defer func() {
+		pc.src, pc.buf, session.r = nil, nil, nil
+	}()
+	if session.wsConn != nil {
+		pc.wsMark = *session.wsConn
+	}
+	//握手时解析PROXY协议会修改地址，回滚时需要恢复
+	peerIp, remoteIp, remotePort := session.peerIp, session.RemoteIp, session.RemotePort
+	for pc.mark < len(pc.inbound) && !session.IsClosed() {
+		var env *Envelope
+		var err error
+		if session.Handshaked() {
+			env, err = session.codec.UnPack(session)
+		} else {
+			err = session.initCodec()
+		}
+		if pc.src.starved && (err != nil || !session.Handshaked()) {
+			//数据不完整，回滚到最后处理完的位置
+			if session.wsConn != nil {
+				*session.wsConn = pc.wsMark
+			}
+			if !session.Handshaked() {
+				session.peerIp, session.RemoteIp, session.RemotePort = peerIp, remoteIp, remotePort
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		session.commitRead()
+		if !session.Handshaked() {
+			if err = session.allowClient(); err != nil {
+				return err
+			}
+			session.handshakeDone()
+			continue
+		}
+		handler(session, env)
+	}
+	if pc.mark >= len(pc.inbound) {
+		pc.inbound = nil
+	} else if pc.mark > 0 {
+		pc.inbound = append([]byte(nil), pc.inbound[pc.mark:]...)
+	}
+	return nil
+}
+
+//http请求头还没有接收完整，websocket握手需要完整的请求头
+func httpHeaderPending(data []byte, maxMsgSize uint32) bool {
+	if maxMsgSize > 0 && len(data) > int(maxMsgSize) {
+		return false
+	}
+	return bytes.Contains(data, []byte(" HTTP/1.")) && !bytes.Contains(data, []byte("\r\n\r\n"))
+}
+
+//可以重复读取的数据源，读到末尾时记录数据不足
+type replaySource struct {
+	data    []byte
+	pos     int
+	starved bool
+}
+
+func (s *replaySource) Read(p []byte) (int, error) {
+	if s.pos >= len(s.data) {
+		s.starved = true
+		return 0, io.EOF
+	}
+	n := copy(p, s.data[s.pos:])
+	s.pos += n
+	return n, nil
+}
+
+//使用epoll处理所有监听的连接，阻塞到Stop。连接没有读协程，
+//收到的消息交给handler处理，Accept不再返回连接。
+func (server *Server) ServeReactor(cfg ReactorCfg, handler Handler) error {
+	r, err := newReactor(cfg, handler)
+	if err != nil {
+		logger.Error("ServeReactor", zap.Error(err))
+		return err
+	}
+	server.listenerMutex.Lock()
+	if server.started {
+		server.listenerMutex.Unlock()
+		r.close()
+		return ReactorStartedErr
+	}
+	server.started = true
+	server.reactor = r
+	for _, item := range server.listeners {
+		go server.acceptLoop(item)
+	}
+	server.listenerMutex.Unlock()
+	<-server.stopChan
+	r.close()
+	return nil
+}
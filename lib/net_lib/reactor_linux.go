@@ -0,0 +1,167 @@
+//go:build linux
+// +build linux
+
+package net_lib
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+)
+
+var errPollAgain = errors.New("[reactor] would block")
+
+const (
+	pollReadEvents  = syscall.EPOLLIN | syscall.EPOLLRDHUP | syscall.EPOLLONESHOT
+	pollWriteEvents = syscall.EPOLLOUT
+	pollWaitEvents  = 256
+	pollWaitTimeout = 1000 //毫秒，也是检查超时的间隔
+)
+
+//一个epoll实例，事件采用EPOLLONESHOT，处理完后由工作协程重新监听，
+//保证同一个连接同时只在一个工作协程中处理
+type eventLoop struct {
+	reactor  *reactor
+	epfd     int
+	sessions map[int]*Session
+	mutex    sync.RWMutex
+}
+
+func newEventLoop(r *reactor) (*eventLoop, error) {
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLoop{reactor: r, epfd: epfd, sessions: make(map[int]*Session)}, nil
+}
+
+//取得连接的文件描述符并监听读事件，连接仍由net.Conn负责关闭
+func (loop *eventLoop) add(session *Session) error {
+	sc, ok := session.conn.(syscall.Conn)
+	if !ok {
+		return ReactorUnsupportedErr
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	fd := -1
+	if err = raw.Control(func(s uintptr) { fd = int(s) }); err != nil {
+		return err
+	}
+	session.poll = newPollConn(loop, raw, fd, loop.reactor.cfg.MaxBuffer)
+	loop.mutex.Lock()
+	loop.sessions[fd] = session
+	loop.mutex.Unlock()
+	event := &syscall.EpollEvent{Events: pollReadEvents, Fd: int32(fd)}
+	if err = syscall.EpollCtl(loop.epfd, syscall.EPOLL_CTL_ADD, fd, event); err != nil {
+		loop.mutex.Lock()
+		delete(loop.sessions, fd)
+		loop.mutex.Unlock()
+		session.poll = nil
+		return err
+	}
+	return nil
+}
+
+//在关闭连接之前调用，关闭后文件描述符可能被新连接复用，之后不能再修改监听
+func (loop *eventLoop) remove(pc *pollConn) {
+	pc.mutex.Lock()
+	pc.closed = true
+	syscall.EpollCtl(loop.epfd, syscall.EPOLL_CTL_DEL, pc.fd, nil)
+	pc.mutex.Unlock()
+	loop.mutex.Lock()
+	delete(loop.sessions, pc.fd)
+	loop.mutex.Unlock()
+}
+
+//调用时需要持有pc.mutex
+func (loop *eventLoop) arm(pc *pollConn, writable bool) error {
+	if pc.closed {
+		return SessionClosedErr
+	}
+	events := uint32(pollReadEvents)
+	if writable {
+		events |= pollWriteEvents
+	}
+	return syscall.EpollCtl(loop.epfd, syscall.EPOLL_CTL_MOD, pc.fd, &syscall.EpollEvent{Events: events, Fd: int32(pc.fd)})
+}
+
+//通过RawConn.Control持有文件描述符，避免和关闭连接并发时读写到复用的描述符
+func (loop *eventLoop) read(pc *pollConn, buf []byte) (n int, err error) {
+	if cerr := pc.raw.Control(func(fd uintptr) { n, err = syscall.Read(int(fd), buf) }); cerr != nil {
+		return 0, cerr
+	}
+	if err == syscall.EAGAIN || err == syscall.EINTR {
+		return 0, errPollAgain
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n, err
+}
+
+func (loop *eventLoop) write(pc *pollConn, buf []byte) (n int, err error) {
+	if cerr := pc.raw.Control(func(fd uintptr) { n, err = syscall.Write(int(fd), buf) }); cerr != nil {
+		return 0, cerr
+	}
+	if err == syscall.EAGAIN || err == syscall.EINTR {
+		return 0, errPollAgain
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n, err
+}
+
+func (loop *eventLoop) run() {
+	defer syscall.Close(loop.epfd)
+	events := make([]syscall.EpollEvent, pollWaitEvents)
+	lastCheck := time.Now().Unix()
+	for {
+		select {
+		case <-loop.reactor.closeChan:
+			return
+		default:
+		}
+		n, err := syscall.EpollWait(loop.epfd, events, pollWaitTimeout)
+		if err != nil && err != syscall.EINTR {
+			logger.Error("eventLoop EpollWait", zap.Error(err))
+			return
+		}
+		for i := 0; i < n; i++ {
+			loop.mutex.RLock()
+			session := loop.sessions[int(events[i].Fd)]
+			loop.mutex.RUnlock()
+			if session == nil || !session.poll.take() {
+				continue
+			}
+			ev := events[i].Events
+			loop.reactor.dispatch(pollTask{
+				session:  session,
+				readable: ev&(syscall.EPOLLIN|syscall.EPOLLRDHUP|syscall.EPOLLHUP|syscall.EPOLLERR) != 0,
+				writable: ev&syscall.EPOLLOUT != 0,
+			})
+		}
+		if now := time.Now().Unix(); now != lastCheck {
+			lastCheck = now
+			loop.checkTimeout(now)
+		}
+	}
+}
+
+func (loop *eventLoop) checkTimeout(now int64) {
+	loop.mutex.RLock()
+	sessions := make([]*Session, 0, len(loop.sessions))
+	for _, session := range loop.sessions {
+		sessions = append(sessions, session)
+	}
+	loop.mutex.RUnlock()
+	for _, session := range sessions {
+		loop.reactor.checkTimeout(session, now)
+	}
+}
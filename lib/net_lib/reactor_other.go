@@ -0,0 +1,35 @@
+//go:build !linux
+// +build !linux
+
+package net_lib
+
+import "errors"
+
+var errPollAgain = errors.New("[reactor] would block")
+
+//非linux平台不支持epoll，ServeReactor返回ReactorUnsupportedErr
+type eventLoop struct{}
+
+func newEventLoop(r *reactor) (*eventLoop, error) {
+	return nil, ReactorUnsupportedErr
+}
+
+func (loop *eventLoop) run() {}
+
+func (loop *eventLoop) add(session *Session) error {
+	return ReactorUnsupportedErr
+}
+
+func (loop *eventLoop) remove(pc *pollConn) {}
+
+func (loop *eventLoop) arm(pc *pollConn, writable bool) error {
+	return ReactorUnsupportedErr
+}
+
+func (loop *eventLoop) read(pc *pollConn, buf []byte) (int, error) {
+	return 0, ReactorUnsupportedErr
+}
+
+func (loop *eventLoop) write(pc *pollConn, buf []byte) (int, error) {
+	return 0, ReactorUnsupportedErr
+}
@@ -0,0 +1,333 @@
+//go:build linux
+// +build linux
+
+package net_lib
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func echoHandler(session *Session, env *Envelope) {
+	env.AckId = env.MsgId
+	env.Flags |= EnvelopeResponse
+	session.Send(env)
+}
+
+func serveReactor(t testing.TB, cfg *SessionCfg, handler Handler) *Server {
+	server, err := Serve("tcp", "127.0.0.1:0", cfg, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.ServeReactor(ReactorCfg{Workers: 2}, handler)
+	return server
+}
+
+func tcpFrame(flags uint8, payload []byte) []byte {
+	w := new(Writer)
+	w.WriteFrameHeader(flags, 8+len(payload))
+	w.Write(make([]byte, 8), payload)
+	return w.Bytes()
+}
+
+//分多次写入，每次写入后等待服务端处理，验证不完整的数据会等待后续的读事件
+func writeChunks(t *testing.T, conn net.Conn, data []byte, size int) {
+	t.Helper()
+	for len(data) > 0 {
+		n := size
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := conn.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func readTcpFrame(t *testing.T, r *Reader) (*FrameHeader, []byte) {
+	t.Helper()
+	header, err := r.ReadFrameHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := r.ReadN(int(header.Length))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header, data[8:]
+}
+
+func checkEcho(t *testing.T, data []byte, msgId uint64) {
+	t.Helper()
+	env, err := UnmarshalEnvelope(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testEnvelope()
+	want.MsgId, want.AckId, want.Flags = msgId, msgId, want.Flags|EnvelopeResponse
+	checkEnvelope(t, want, env)
+}
+
+func TestReactorTcp(t *testing.T) {
+	server := serveReactor(t, &SessionCfg{MaxMsgSize: 64 << 10}, echoHandler)
+	defer server.Stop()
+	conn, err := net.Dial("tcp", server.Listener().Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := NewReader(bufio.NewReader(conn))
+
+	//控制帧之后紧跟不完整的消息，控制帧只能处理一次
+	var data []byte
+	data = append(data, tcpFrame(FlagControl, FormatControl(map[string]string{"compress": "gzip"}))...)
+	env := testEnvelope()
+	env.MsgId = 1
+	data = append(data, tcpFrame(0, env.Marshal())...)
+	writeChunks(t, conn, data, 7)
+	header, reply := readTcpFrame(t, r)
+	if !header.Control() || ParseControl(reply)["compress"] != "" {
+		t.Fatalf("unexpected control reply %v %q", header, reply)
+	}
+	_, reply = readTcpFrame(t, r)
+	checkEcho(t, reply, 1)
+
+	//一次写入多条消息
+	data = data[:0]
+	for i := 2; i < 22; i++ {
+		env.MsgId = uint64(i)
+		data = append(data, tcpFrame(0, env.Marshal())...)
+	}
+	if _, err = conn.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	for i := 2; i < 22; i++ {
+		_, reply = readTcpFrame(t, r)
+		checkEcho(t, reply, uint64(i))
+	}
+}
+
+func wsClientFrame(opcode byte, final bool, payload []byte) []byte {
+	b0 := opcode
+	if final {
+		b0 |= finalBit
+	}
+	frame := []byte{b0}
+	switch {
+	case len(payload) > 65535:
+		frame = append(frame, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	case len(payload) > 125:
+		frame = append(frame, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame = append(frame, maskBit|byte(len(payload)))
+	}
+	key := [4]byte{1, 2, 3, 4}
+	frame = append(frame, key[:]...)
+	masked := append([]byte(nil), payload...)
+	maskBytes(key, 0, masked)
+	return append(frame, masked...)
+}
+
+func readWsFrame(t *testing.T, r *Reader) []byte {
+	t.Helper()
+	head, err := r.ReadN(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	length := int(head[1] & 0x7f)
+	switch length {
+	case 126:
+		ext, _ := r.ReadN(2)
+		length = int(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext, _ := r.ReadN(8)
+		length = int(binary.BigEndian.Uint64(ext))
+	}
+	data, err := r.ReadN(length)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReactorWs(t *testing.T) {
+	server := serveReactor(t, &SessionCfg{MaxMsgSize: 64 << 10}, echoHandler)
+	defer server.Stop()
+	conn, err := net.Dial("tcp", server.Listener().Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := NewReader(bufio.NewReader(conn))
+
+	handshake := "GET / HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	writeChunks(t, conn, []byte(handshake), 20)
+	resp, err := r.r.ReadString('\n')
+	if err != nil || !strings.Contains(resp, "101") {
+		t.Fatalf("unexpected response %q %v", resp, err)
+	}
+	for line := ""; line != "\r\n"; {
+		if line, err = r.r.ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env := testEnvelope()
+	env.MsgId = 1
+	body := append(make([]byte, 24), env.Marshal()...)
+	writeChunks(t, conn, wsClientFrame(BinaryMessage, true, body), 11)
+	checkEcho(t, readWsFrame(t, r)[24:], 1)
+
+	//分片消息分多次写入
+	env.MsgId = 2
+	body = append(make([]byte, 24), env.Marshal()...)
+	var data []byte
+	data = append(data, wsClientFrame(BinaryMessage, false, body[:100])...)
+	data = append(data, wsClientFrame(continuationFrame, true, body[100:])...)
+	writeChunks(t, conn, data, 50)
+	checkEcho(t, readWsFrame(t, r)[24:], 2)
+}
+
+func TestReactorReadTimeout(t *testing.T) {
+	server := serveReactor(t, &SessionCfg{MaxMsgSize: 64 << 10, ReadDeadLine: 1}, echoHandler)
+	defer server.Stop()
+	conn, err := net.Dial("tcp", server.Listener().Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err = conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("want idle connection closed")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("idle connection not closed")
+	}
+}
+
+const idleConns = 1000
+
+//不经过Go的netpoll建立客户端连接，避免客户端的内存计入结果
+func dialRaw(b *testing.B, addr *net.TCPAddr) int {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	sa := &syscall.SockaddrInet4{Port: addr.Port}
+	copy(sa.Addr[:], addr.IP.To4())
+	if err = syscall.Connect(fd, sa); err != nil {
+		b.Fatal(err)
+	}
+	return fd
+}
+
+func heapAndStack() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse + stats.StackInuse
+}
+
+//建立idleConns个完成握手并收到一条消息后空闲的连接，返回每个连接占用的内存
+func measureIdleConns(b *testing.B, poll bool) float64 {
+	var received int64
+	handler := func(session *Session, env *Envelope) { atomic.AddInt64(&received, 1) }
+	//与接入服务相同，阻塞模式每个连接有读协程和发送协程
+	server, err := Serve("tcp", "127.0.0.1:0", &SessionCfg{MaxMsgSize: 64 << 10}, 1)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer server.Stop()
+	if poll {
+		go server.ServeReactor(ReactorCfg{Workers: 4}, handler)
+	} else {
+		go func() {
+			for {
+				session, err := server.Accept()
+				if err != nil {
+					return
+				}
+				go (&reactor{handler: handler}).serveBlocking(session)
+			}
+		}()
+	}
+	addr := server.Listener().Addr().(*net.TCPAddr)
+	frame := tcpFrame(0, testEnvelope().Marshal())
+	before := heapAndStack()
+	fds := make([]int, 0, idleConns)
+	defer func() {
+		for _, fd := range fds {
+			syscall.Close(fd)
+		}
+	}()
+	for i := 0; i < idleConns; i++ {
+		fd := dialRaw(b, addr)
+		fds = append(fds, fd)
+		if _, err = syscall.Write(fd, frame); err != nil {
+			b.Fatal(err)
+		}
+	}
+	for deadline := time.Now().Add(10 * time.Second); atomic.LoadInt64(&received) < idleConns; {
+		if time.Now().After(deadline) {
+			b.Fatalf("received %d of %d", atomic.LoadInt64(&received), idleConns)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	after := heapAndStack()
+	return float64(after-before) / idleConns
+}
+
+//go test -run none -bench IdleConn -benchtime 3x ./lib/net_lib
+func BenchmarkIdleConn(b *testing.B) {
+	for _, mode := range []struct {
+		name    string
+		reactor bool
+	}{{"goroutine", false}, {"reactor", true}} {
+		b.Run(mode.name, func(b *testing.B) {
+			var total float64
+			for i := 0; i < b.N; i++ {
+				total += measureIdleConns(b, mode.reactor)
+			}
+			b.ReportMetric(total/float64(b.N), "B/conn")
+		})
+	}
+}
+
+func BenchmarkReactorEcho(b *testing.B) {
+	server := serveReactor(b, &SessionCfg{MaxMsgSize: 64 << 10}, echoHandler)
+	defer server.Stop()
+	conn, err := net.Dial("tcp", server.Listener().Addr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer conn.Close()
+	frame := tcpFrame(0, testEnvelope().Marshal())
+	r := bufio.NewReader(conn)
+	reply := make([]byte, 0, len(frame)+16)
+	b.SetBytes(int64(len(frame)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = conn.Write(frame); err != nil {
+			b.Fatal(err)
+		}
+		//回复只比请求多了AckId和响应标识，帧头长度相同
+		reply = reply[:len(frame)]
+		if _, err = io.ReadFull(r, reply); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
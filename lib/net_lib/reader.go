@@ -36,6 +36,9 @@ func (r *Reader) Read(data []byte) (int, error) {
 		tempNum, err = r.r.Read(data[readLen:total])
 		readLen += tempNum
 	}
+	if readLen < total {
+		return readLen, err
+	}
 	return readLen, nil
 }
 
@@ -58,3 +61,7 @@ func (r *Reader) ReadUint32() (uint32, error) {
 	v := binary.LittleEndian.Uint32(buf[0:4])
 	return v, nil
 }
+
+func (r *Reader) ReadUvarint() (uint64, error) {
+	return binary.ReadUvarint(r.r)
+}
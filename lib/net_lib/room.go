@@ -0,0 +1,88 @@
+package net_lib
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+//房间内的连接，用于聊天室、直播间等按房间广播的场景
+type roomMap struct {
+	rooms map[string]map[uint64]*Session
+	sync.RWMutex
+}
+
+func (manager *Manager) roomMap(roomId string) *roomMap {
+	h := fnv.New32a()
+	h.Write([]byte(roomId))
+	return &manager.roomMaps[h.Sum32()%sessionMapNum]
+}
+
+//连接加入房间，连接关闭时自动退出所有房间
+func (manager *Manager) JoinRoom(session *Session, roomId string) {
+	session.roomMutex.Lock()
+	if session.rooms == nil {
+		session.rooms = make(map[string]struct{})
+	}
+	session.rooms[roomId] = struct{}{}
+	session.roomMutex.Unlock()
+	rmap := manager.roomMap(roomId)
+	rmap.Lock()
+	sessions := rmap.rooms[roomId]
+	if sessions == nil {
+		sessions = make(map[uint64]*Session)
+		rmap.rooms[roomId] = sessions
+	}
+	sessions[session.id] = session
+	rmap.Unlock()
+	//与关闭并发时，关闭可能已经清理过房间
+	if session.IsClosed() {
+		manager.LeaveRoom(session, roomId)
+	}
+}
+
+func (manager *Manager) LeaveRoom(session *Session, roomId string) {
+	session.roomMutex.Lock()
+	delete(session.rooms, roomId)
+	session.roomMutex.Unlock()
+	manager.removeFromRoom(session, roomId)
+}
+
+func (manager *Manager) removeFromRoom(session *Session, roomId string) {
+	rmap := manager.roomMap(roomId)
+	rmap.Lock()
+	defer rmap.Unlock()
+	sessions := rmap.rooms[roomId]
+	delete(sessions, session.id)
+	if len(sessions) == 0 {
+		delete(rmap.rooms, roomId)
+	}
+}
+
+//房间内连接的快照
+func (manager *Manager) RoomSessions(roomId string) []*Session {
+	rmap := manager.roomMap(roomId)
+	rmap.RLock()
+	defer rmap.RUnlock()
+	sessions := make([]*Session, 0, len(rmap.rooms[roomId]))
+	for _, session := range rmap.rooms[roomId] {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+func (manager *Manager) RoomCount(roomId string) int {
+	rmap := manager.roomMap(roomId)
+	rmap.RLock()
+	defer rmap.RUnlock()
+	return len(rmap.rooms[roomId])
+}
+
+func (manager *Manager) leaveRooms(session *Session) {
+	session.roomMutex.Lock()
+	rooms := session.rooms
+	session.rooms = nil
+	session.roomMutex.Unlock()
+	for roomId := range rooms {
+		manager.removeFromRoom(session, roomId)
+	}
+}
@@ -0,0 +1,39 @@
+package net_lib
+
+import (
+	"net"
+	"testing"
+)
+
+func TestRoom(t *testing.T) {
+	manager := NewManager()
+	var sessions []*Session
+	for i := 0; i < 3; i++ {
+		conn, peer := net.Pipe()
+		defer peer.Close()
+		session := manager.NewSession(conn, nil, 0, SessionCfg{})
+		manager.JoinRoom(session, "a")
+		sessions = append(sessions, session)
+	}
+	manager.JoinRoom(sessions[0], "b")
+	if n := manager.RoomCount("a"); n != 3 {
+		t.Fatalf("room a should have 3 sessions, got %d", n)
+	}
+	manager.LeaveRoom(sessions[1], "a")
+	if n := len(manager.RoomSessions("a")); n != 2 {
+		t.Fatalf("room a should have 2 sessions, got %d", n)
+	}
+	//关闭时退出所有房间，空房间被删除
+	sessions[0].Close()
+	if manager.RoomCount("a") != 1 || manager.RoomCount("b") != 0 {
+		t.Fatalf("closed session should leave rooms, a=%d b=%d", manager.RoomCount("a"), manager.RoomCount("b"))
+	}
+	if _, ok := manager.roomMap("b").rooms["b"]; ok {
+		t.Fatal("empty room should be removed")
+	}
+	//已关闭的连接不能加入房间
+	manager.JoinRoom(sessions[0], "c")
+	if manager.RoomCount("c") != 0 {
+		t.Fatal("closed session should not join room")
+	}
+}
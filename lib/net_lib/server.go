@@ -1,40 +1,205 @@
 package net_lib
 
 import (
+	"bufio"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
 	"io"
 	"net"
 	"strings"
+	"sync"
 	"time"
-	"github.com/imkuqin-zw/ZWChat/common/logger"
-	"go.uber.org/zap"
 )
 
+//一个Server可以有多个监听，所有监听接受的连接都从Accept返回
 type Server struct {
 	manager         *Manager
-	listener        net.Listener
+	listeners       []*listener
+	listenerMutex   sync.Mutex
+	started         bool
+	sessionChan     chan *Session
+	stopChan        chan struct{}
+	stopOnce        sync.Once
 	defaultCode     Codec
 	sendChannelSize int
 	sessionCfg      *SessionCfg
+	trustedProxies  TrustedProxies
+	limiter         *Limiter
+	reactor         *reactor //ServeReactor启动后不为nil
 }
 
 func NewServer(l net.Listener, sendChannelSize int, cfg *SessionCfg) *Server {
+	server := newServer(sendChannelSize, cfg)
+	server.AddListener(l, &ListenerCfg{})
+	return server
+}
+
+func newServer(sendChannelSize int, cfg *SessionCfg) *Server {
 	return &Server{
-		listener:        l,
 		manager:         NewManager(),
+		sessionChan:     make(chan *Session),
+		stopChan:        make(chan struct{}),
 		defaultCode:     ProtoTcp,
 		sendChannelSize: sendChannelSize,
 		sessionCfg:      cfg,
 	}
 }
 
+func (server *Server) Manager() *Manager {
+	return server.manager
+}
+
+//第一个监听
 func (server *Server) Listener() net.Listener {
-	return server.listener
+	server.listenerMutex.Lock()
+	defer server.listenerMutex.Unlock()
+	if len(server.listeners) == 0 {
+		return nil
+	}
+	return server.listeners[0].listener
+}
+
+//所有的监听
+func (server *Server) Listeners() []net.Listener {
+	server.listenerMutex.Lock()
+	defer server.listenerMutex.Unlock()
+	result := make([]net.Listener, len(server.listeners))
+	for i, l := range server.listeners {
+		result[i] = l.listener
+	}
+	return result
+}
+
+//添加监听，cfg中的TLS不会被使用；Network和Addr用于平滑升级时匹配继承的监听，为空时使用l.Addr()
+func (server *Server) AddListener(l net.Listener, cfg *ListenerCfg) error {
+	return server.addListener(l, l, cfg)
+}
+
+func (server *Server) addListener(l, raw net.Listener, cfg *ListenerCfg) error {
+	protos, err := parseProtos(cfg.Protos)
+	if err != nil {
+		return err
+	}
+	item := &listener{
+		name:     cfg.Name,
+		network:  cfg.Network,
+		addr:     cfg.Addr,
+		listener: l,
+		raw:      raw,
+		protos:   protos,
+		override: cfg.SessionCfg,
+	}
+	if item.network == "" || item.addr == "" {
+		item.network, item.addr = l.Addr().Network(), l.Addr().String()
+	}
+	server.listenerMutex.Lock()
+	defer server.listenerMutex.Unlock()
+	item.sessionCfg = server.baseSessionCfg().Override(item.override)
+	server.listeners = append(server.listeners, item)
+	if server.started {
+		go server.acceptLoop(item)
+	}
+	return nil
+}
+
+//调用方持有listenerMutex
+func (server *Server) baseSessionCfg() SessionCfg {
+	if server.sessionCfg == nil {
+		return SessionCfg{}
+	}
+	return *server.sessionCfg
+}
+
+//修改会话配置，只对之后建立的连接生效，监听配置中的非零值仍然覆盖cfg
+func (server *Server) SetSessionCfg(cfg SessionCfg) {
+	server.listenerMutex.Lock()
+	defer server.listenerMutex.Unlock()
+	server.sessionCfg = &cfg
+	for _, item := range server.listeners {
+		item.sessionCfg = cfg.Override(item.override)
+	}
+}
+
+//当前的会话配置
+func (server *Server) SessionCfg() SessionCfg {
+	server.listenerMutex.Lock()
+	defer server.listenerMutex.Unlock()
+	return server.baseSessionCfg()
+}
+
+func (server *Server) listenerSessionCfg(item *listener) SessionCfg {
+	server.listenerMutex.Lock()
+	defer server.listenerMutex.Unlock()
+	return item.sessionCfg
+}
+
+//按配置创建监听并添加
+func (server *Server) Listen(cfg *ListenerCfg) error {
+	l, raw, err := cfg.listen()
+	if err != nil {
+		return err
+	}
+	item := *cfg
+	item.Network = cfg.network()
+	if err = server.addListener(l, raw, &item); err != nil {
+		l.Close()
+		return err
+	}
+	return nil
 }
 
+//设置受信任的代理地址(CIDR或ip)，只有来自这些地址的连接才会解析PROXY协议和X-Forwarded-For
+func (server *Server) SetTrustedProxies(cidrs []string) error {
+	proxies, err := ParseTrustedProxies(cidrs)
+	if err != nil {
+		return err
+	}
+	server.trustedProxies = proxies
+	return nil
+}
+
+//设置连接数限制和封禁列表，需要在Accept之前设置
+func (server *Server) SetLimit(cfg LimitCfg) error {
+	limiter, err := NewLimiter(cfg)
+	if err != nil {
+		return err
+	}
+	server.limiter = limiter
+	return nil
+}
+
+//没有设置限制时返回nil
+func (server *Server) Limiter() *Limiter {
+	return server.limiter
+}
+
+//注册连接生命周期回调，需要在Accept之前设置
+func (server *Server) SetHooks(hooks Hooks) {
+	server.manager.SetHooks(hooks)
+}
+
+//返回下一个连接，服务关闭后返回io.EOF
 func (server *Server) Accept() (*Session, error) {
+	server.listenerMutex.Lock()
+	if !server.started {
+		server.started = true
+		for _, item := range server.listeners {
+			go server.acceptLoop(item)
+		}
+	}
+	server.listenerMutex.Unlock()
+	select {
+	case session := <-server.sessionChan:
+		return session, nil
+	case <-server.stopChan:
+		return nil, io.EOF
+	}
+}
+
+func (server *Server) acceptLoop(item *listener) {
 	var tempDelay time.Duration
 	for {
-		conn, err := server.listener.Accept()
+		conn, err := item.listener.Accept()
 		if err != nil {
 			if ne, ok := err.(net.Error); ok && ne.Temporary() {
 				if tempDelay == 0 {
@@ -50,25 +215,100 @@ func (server *Server) Accept() (*Session, error) {
 				continue
 			}
 			// TODO 可能需要优化一下，但现在技术有限
-			if strings.Contains(err.Error(), "use of closed network connection") {
-				return nil, io.EOF
+			if !strings.Contains(err.Error(), "use of closed network connection") {
+				logger.Error("Server acceptLoop", zap.String("listener", item.name), zap.Error(err))
 			}
-			return nil, err
+			return
 		}
-		return server.manager.NewSession(conn, server.defaultCode, server.sendChannelSize, *server.sessionCfg), nil
+		tempDelay = 0
+		//在解析任何协议之前拒绝超过限制的连接
+		var limitIp string
+		if server.limiter != nil {
+			var ok bool
+			if limitIp, ok = server.allow(conn); !ok {
+				conn.Close()
+				continue
+			}
+		}
+		if server.reactor != nil {
+			server.acceptPoll(conn, item, limitIp)
+			continue
+		}
+		session := server.manager.NewSession(conn, server.defaultCode, server.sendChannelSize,
+			server.listenerSessionCfg(item))
+		session.SetTrustedProxies(server.trustedProxies)
+		session.limiter, session.limitIp = server.limiter, limitIp
+		session.allowProtos = item.protos
+		session.emit(func(hooks Hooks) { hooks.OnAccept(session) })
+		select {
+		case server.sessionChan <- session:
+		case <-server.stopChan:
+			session.CloseWithReason(CloseServerStop, nil)
+			return
+		}
+	}
+}
+
+//受信任代理的连接只受封禁和全局连接数限制，返回的limitIp为空，握手后由allowClient按客户端地址检查。
+//其他连接为对端ip
+func (server *Server) allow(conn net.Conn) (limitIp string, ok bool) {
+	ip, _ := SplitAddr(conn.RemoteAddr().String())
+	if server.trustedProxies.Contains(ip) {
+		return "", server.limiter.allowShared(ip)
+	}
+	return ip, server.limiter.allow(ip)
+}
+
+//把连接交给epoll，不支持的连接退回读协程
+func (server *Server) acceptPoll(conn net.Conn, item *listener, limitIp string) {
+	session := server.manager.newPollSession(conn, server.defaultCode, server.listenerSessionCfg(item))
+	session.SetTrustedProxies(server.trustedProxies)
+	session.limiter, session.limitIp = server.limiter, limitIp
+	session.allowProtos = item.protos
+	session.emit(func(hooks Hooks) { hooks.OnAccept(session) })
+	if err := server.reactor.add(session); err != nil {
+		if err != ReactorUnsupportedErr {
+			logger.Error("Server acceptPoll", zap.String("listener", item.name), zap.Error(err))
+		}
+		session.r = NewReader(bufio.NewReader(conn))
+		go server.reactor.serveBlocking(session)
 	}
 }
 
 func (server *Server) Stop() {
-	server.listener.Close()
-	server.manager.Dispose()
+	server.stopOnce.Do(func() {
+		close(server.stopChan)
+		server.listenerMutex.Lock()
+		for _, item := range server.listeners {
+			item.listener.Close()
+		}
+		server.listenerMutex.Unlock()
+		server.manager.Dispose()
+	})
 }
 
+//由Upgrade启动的进程会使用继承的监听
 func Serve(network, address string, cfg *SessionCfg, sendChanSize int) (*Server, error) {
-	listener, err := net.Listen(network, address)
+	listener, err := listen(network, address)
 	if err != nil {
 		logger.Fatal("Serve", zap.Error(err))
 		return nil, err
 	}
-	return NewServer(listener, sendChanSize, cfg), nil
+	server := newServer(sendChanSize, cfg)
+	server.AddListener(listener, &ListenerCfg{Network: network, Addr: address})
+	return server, nil
+}
+
+//按配置创建所有监听，任何一个失败时关闭已创建的监听
+func ServeListeners(cfgs []*ListenerCfg, cfg *SessionCfg, sendChanSize int) (*Server, error) {
+	server := newServer(sendChanSize, cfg)
+	for _, item := range cfgs {
+		if err := server.Listen(item); err != nil {
+			logger.Error("ServeListeners", zap.String("listener", item.Name), zap.String("addr", item.Addr),
+				zap.Error(err))
+			server.Stop()
+			return nil, err
+		}
+	}
+	return server, nil
 }
@@ -3,10 +3,10 @@ package net_lib
 import (
 	"bufio"
 	"fmt"
+	"github.com/golang/protobuf/proto"
 	"github.com/imkuqin-zw/ZWChat/common/logger"
 	"go.uber.org/zap"
 	"net"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -32,48 +32,72 @@ type SessionCfg struct {
 	Duration      int64  `yaml:"duration"`      //时间周期
 	Interval      int64  `yaml:"interval"`      //窗口时间间隔(s)
 	Count         int64  `yaml:"count"`         //窗口数量
+	//允许的压缩算法，按优先级排列，为空时不压缩
+	Compress          []string `yaml:"compress"`
+	CompressThreshold int      `yaml:"compressThreshold"` //小于该字节数的消息不压缩
 }
 
 type Session struct {
-	id         uint64 //会话唯一标识
-	manager    *Manager
-	conn       net.Conn
-	r          *Reader        //读取数据的bufer
-	codec      Codec          //打包和解包接口
-	waitFlag   int32          //等待关闭的状态（不接受消息）
-	closeWait  sync.WaitGroup //等待关闭
-	closeFlag  int32          //连接是否关闭标识, 用int型是为了线程安全的改值
-	closeChan  chan int
-	sendChan   chan interface{}
-	userId     uint64 //用户唯一标识
-	msgId      uint64 //消息的唯一标识
-	shareKeyId []byte
-	shareKey   []byte
-	cfg        SessionCfg
-	connType   int8 //连接类型
-	wsConn     *WsConn
-	RemoteIp   string //对端ip
-	RemotePort string //对端port
+	id          uint64 //会话唯一标识
+	manager     *Manager
+	conn        net.Conn
+	r           *Reader        //读取数据的bufer
+	codec       Codec          //打包和解包接口
+	waitFlag    int32          //等待关闭的状态（不接受消息）
+	handshaked  int32          //InitCodec成功后为1
+	closeWait   sync.WaitGroup //等待关闭
+	closeFlag   int32          //连接是否关闭标识, 用int型是为了线程安全的改值
+	closeChan   chan int
+	sendChan    chan *Envelope
+	userId      uint64     //用户唯一标识
+	bindMutex   sync.Mutex //绑定、解绑与关闭时解绑互斥
+	msgId       uint64     //消息的唯一标识
+	shareKeyId  []byte
+	shareKey    []byte
+	cfg         SessionCfg
+	connType    int8       //连接类型
+	legacyFrame bool       //tcp连接是否使用旧版本的帧格式
+	recvFlags   uint8      //最近收到的帧的标识
+	compressor  Compressor //读协程协商后修改，发送协程读取
+	compMutex   sync.RWMutex
+	format      WireFormat //消息体的编码格式
+	writeMutex  sync.Mutex
+	wsConn      *WsConn
+	proxies     TrustedProxies //受信任的代理地址
+	limiter     *Limiter       //关闭时释放连接数
+	limitIp     string         //计入单个ip限制的地址，受信任代理转发的连接握手后为客户端地址
+	allowProtos uint8          //监听允许的连接类型(1<<TCP|1<<HTTP|1<<WS)，0表示全部允许
+	poll        *pollConn      //epoll模式下的读写状态，为nil时由读协程阻塞读取
+	peerIp      string         //直连对端的ip(解析PROXY协议后)
+	RemoteIp    string         //客户端真实ip
+	RemotePort  string         //客户端真实port
+	attrs       sync.Map       //业务层保存的连接属性，如握手时上报的设备信息
+	rooms       map[string]struct{}
+	roomMutex   sync.Mutex
 }
 
 func newSession(manager *Manager, conn net.Conn, defaultCode Codec, sendChanSize int, cfg SessionCfg) *Session {
+	session := newBaseSession(manager, conn, defaultCode, cfg)
+	session.r = NewReader(bufio.NewReader(conn))
+	if sendChanSize > 0 {
+		session.sendChan = make(chan *Envelope, sendChanSize)
+		go session.sendLoop()
+	}
+	return session
+}
+
+//不带读缓冲和发送协程的会话，epoll模式在解码时才设置Reader
+func newBaseSession(manager *Manager, conn net.Conn, defaultCode Codec, cfg SessionCfg) *Session {
 	session := &Session{
 		id:        atomic.AddUint64(&globalSessionId, 1),
 		manager:   manager,
 		closeChan: make(chan int),
 		conn:      conn,
-		r:         NewReader(bufio.NewReader(conn)),
 		codec:     defaultCode,
 		cfg:       cfg,
 	}
-
-	remoteAddr := strings.Split(conn.RemoteAddr().String(), ":")
-	session.RemoteIp = remoteAddr[0]
-	session.RemotePort = remoteAddr[1]
-	if sendChanSize > 0 {
-		session.sendChan = make(chan interface{}, sendChanSize)
-		go session.sendLoop()
-	}
+	session.RemoteIp, session.RemotePort = SplitAddr(conn.RemoteAddr().String())
+	session.peerIp = session.RemoteIp
 	return session
 }
 
@@ -95,6 +119,10 @@ func (session *Session) SetConnType(connType int8) {
 	session.connType = connType
 }
 
+func (session *Session) Id() uint64 {
+	return session.id
+}
+
 func (session *Session) GetConnType() int8 {
 	return session.connType
 }
@@ -108,28 +136,27 @@ func (session *Session) GetShareKeyId() []byte {
 }
 
 func (session *Session) SetShareKey(shareKey []byte) {
-	session.shareKeyId = shareKey
+	session.shareKey = shareKey
 }
 
 func (session *Session) GetShareKey(shareKeyId []byte) []byte {
 	var shareKey []byte
 	if !IsBytesAllZero(session.shareKey) {
 		shareKey = session.shareKey
-	} else {
-
+	} else if len(shareKeyId) != 0 && session.manager != nil && session.manager.shareKeyFunc != nil {
+		shareKey = session.manager.shareKeyFunc(shareKeyId)
 	}
 	return shareKey
 }
 
 func (session *Session) sendLoop() {
-	defer session.Close()
 	for {
 		select {
-		case msg := <-session.sendChan:
-			//TODO 解析这个msg
-			buf, err := session.codec.Packet(msg, session)
+		case env := <-session.sendChan:
+			buf, err := session.codec.Packet(env, session)
 			if err != nil {
 				logger.Debug("sendLoop", zap.Error(err))
+				session.closeOnError(err, CloseWriteErr)
 				return
 			}
 			if session.cfg.WriteDeadLine > 0 {
@@ -138,6 +165,7 @@ func (session *Session) sendLoop() {
 			}
 			if err = session.Write(buf); err != nil {
 				logger.Error("session.Write error: ", zap.Error(err))
+				session.closeOnError(err, CloseWriteErr)
 				return
 			}
 			if session.cfg.WriteDeadLine > 0 {
@@ -150,18 +178,66 @@ func (session *Session) sendLoop() {
 }
 
 func (session *Session) Close() error {
+	return session.CloseWithReason(CloseNormal, nil)
+}
+
+//关闭连接并把原因和导致关闭的错误传给OnClose回调
+func (session *Session) CloseWithReason(reason CloseReason, cause error) error {
 	if atomic.CompareAndSwapInt32(&session.closeFlag, 0, 1) {
 		session.closeWait.Wait()
+		if session.poll != nil {
+			session.poll.loop.remove(session.poll)
+		}
 		err := session.conn.Close()
 		close(session.closeChan)
+		if session.limiter != nil {
+			session.limiter.release(session.limitIp)
+		}
 		if session.manager != nil {
 			session.manager.delSession(session)
 		}
+		session.emit(func(hooks Hooks) { hooks.OnClose(session, reason, cause) })
 		return err
 	}
 	return SessionClosedErr
 }
 
+//读写出错时关闭连接，对端正常关闭不回调OnError
+func (session *Session) closeOnError(err error, def CloseReason) {
+	if session.IsClosed() {
+		return
+	}
+	reason := closeReasonOf(err, def)
+	if reason != ClosePeer {
+		session.emit(func(hooks Hooks) { hooks.OnError(session, err) })
+	}
+	session.CloseWithReason(reason, err)
+}
+
+//投递生命周期回调，没有注册回调时忽略
+func (session *Session) emit(f func(hooks Hooks)) {
+	if session.manager != nil && session.manager.hooks != nil {
+		session.manager.hooks.post(session, f)
+	}
+}
+
+//绑定用户，见Manager.Bind
+func (session *Session) Bind(userId uint64) bool {
+	if session.manager != nil {
+		return session.manager.Bind(session, userId)
+	}
+	session.userId = userId
+	return true
+}
+
+func (session *Session) Unbind() {
+	if session.manager != nil {
+		session.manager.Unbind(session)
+		return
+	}
+	session.userId = 0
+}
+
 func (session *Session) IsHttp() bool {
 	if session.cfg.ReadDeadLine > 0 {
 		deadTime := time.Now().Add(time.Second * time.Duration(session.cfg.ReadDeadLine))
@@ -174,10 +250,92 @@ func (session *Session) IsHttp() bool {
 	return b
 }
 
+//设置受信任的代理，来自这些地址的连接会解析PROXY协议和X-Forwarded-For
+func (session *Session) SetTrustedProxies(proxies TrustedProxies) {
+	session.proxies = proxies
+}
+
+func (session *Session) readProxyHeader() error {
+	if !session.proxies.Contains(session.peerIp) {
+		return nil
+	}
+	if session.cfg.ReadDeadLine > 0 {
+		deadTime := time.Now().Add(time.Second * time.Duration(session.cfg.ReadDeadLine))
+		session.conn.SetReadDeadline(deadTime)
+	}
+	ip, port, ok, err := ReadProxyHeader(session.r)
+	if session.cfg.ReadDeadLine > 0 {
+		session.conn.SetReadDeadline(time.Time{})
+	}
+	if err != nil {
+		logger.Debug("readProxyHeader", zap.String("peer", session.peerIp), zap.Error(err))
+		return err
+	}
+	if ok {
+		session.peerIp = ip
+		session.RemoteIp, session.RemotePort = ip, port
+	}
+	return nil
+}
+
+//对端为受信任的代理时，使用X-Forwarded-For/X-Real-IP中的客户端地址
+func (session *Session) setForwardedIp(forwardedFor, realIp string) {
+	if !session.proxies.Contains(session.peerIp) {
+		return
+	}
+	if ip := forwardedIp(session.proxies, forwardedFor, realIp); ip != "" {
+		session.RemoteIp, session.RemotePort = ip, ""
+	}
+}
+
+//判断连接类型并选择编解码器，失败时关闭连接
 func (session *Session) InitCodec() error {
+	err := session.initCodec()
+	if err == nil {
+		err = session.allowClient()
+	}
+	if err != nil {
+		session.closeOnError(err, CloseHandshakeErr)
+		return err
+	}
+	session.handshakeDone()
+	return nil
+}
+
+//受信任代理转发的连接在握手解析出客户端地址后，按客户端地址检查封禁和单个ip的限制，
+//通过后关闭时按客户端地址释放。没有解析出客户端地址时仍只受accept时的限制
+func (session *Session) allowClient() error {
+	ip := session.RemoteIp
+	if session.limiter == nil || session.limitIp != "" || session.proxies.Contains(ip) {
+		return nil
+	}
+	if !session.limiter.allowClient(ip) {
+		return ClientRejectedErr
+	}
+	session.limitIp = ip
+	return nil
+}
+
+func (session *Session) handshakeDone() {
+	atomic.StoreInt32(&session.handshaked, 1)
+	session.emit(func(hooks Hooks) { hooks.OnHandshake(session) })
+}
+
+//是否已经确定连接类型和编解码器
+func (session *Session) Handshaked() bool {
+	return atomic.LoadInt32(&session.handshaked) == 1
+}
+
+func (session *Session) initCodec() error {
+	if err := session.readProxyHeader(); err != nil {
+		return err
+	}
 	if session.IsHttp() {
 		length, headers := GetHeader(session.r, session.cfg.MaxMsgSize)
 		if IsWsHandshake(headers) {
+			if !session.protoAllowed(WS) {
+				return ProtoNotAllowedErr
+			}
 			if _, err := session.r.Discard(length); err != nil {
 				return err
 			}
@@ -185,8 +343,15 @@ func (session *Session) InitCodec() error {
 				logger.Debug("InitCodec", zap.Error(err))
 				return err
 			}
+			subprotocol, format, err := NegotiateSubprotocol(GetHeaderValue(headers, "Sec-WebSocket-Protocol"))
+			if err != nil {
+				logger.Debug("InitCodec", zap.Error(err))
+				session.Write([]byte(http400Subprotocol))
+				return err
+			}
+			session.setForwardedIp(GetHeaderValue(headers, "X-Forwarded-For"), GetHeaderValue(headers, "X-Real-IP"))
 			acceptKey := ComputeAcceptedKey(headers["Sec-WebSocket-Key"])
-			resp := CreateUpgradeResp(acceptKey)
+			resp := CreateUpgradeResp(acceptKey, subprotocol)
 			if err := session.Write([]byte(resp)); err != nil {
 				return err
 			}
@@ -198,23 +363,52 @@ func (session *Session) InitCodec() error {
 				handleClose: nil,
 			}
 			session.SetConnType(WS)
-			session.SetCodec(ProtoWs)
+			session.format = format
+			if format == FormatJSON {
+				session.SetCodec(JsonWs)
+			} else {
+				session.SetCodec(ProtoWs)
+			}
 		} else {
+			if !session.protoAllowed(HTTP) {
+				return ProtoNotAllowedErr
+			}
 			session.SetConnType(HTTP)
 			session.SetCodec(ProtoHttp)
 		}
 	} else {
+		if !session.protoAllowed(TCP) {
+			return ProtoNotAllowedErr
+		}
 		session.SetConnType(TCP)
 		session.SetCodec(ProtoTcp)
+		if err := ProtoTcp.DetectVersion(session); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (session *Session) Receive() (buf []byte, err error) {
-	return session.codec.UnPack(session)
+func (session *Session) protoAllowed(connType int8) bool {
+	return session.allowProtos == 0 || session.allowProtos&(1<<uint8(connType)) != 0
+}
+
+//返回错误时连接已关闭
+func (session *Session) Receive() (*Envelope, error) {
+	env, err := session.codec.UnPack(session)
+	if err != nil {
+		session.closeOnError(err, CloseReadErr)
+		return nil, err
+	}
+	return env, nil
 }
 
 func (session *Session) Write(buf []byte) (err error) {
+	session.writeMutex.Lock()
+	defer session.writeMutex.Unlock()
+	if session.poll != nil {
+		return session.poll.write(buf)
+	}
 	if session.cfg.WriteDeadLine > 0 {
 		deadTime := time.Now().Add(time.Second * time.Duration(session.cfg.WriteDeadLine))
 		session.conn.SetWriteDeadline(deadTime)
@@ -234,34 +428,137 @@ func (session *Session) Write(buf []byte) (err error) {
 	return nil
 }
 
-func (session *Session) Send(msg interface{}) error {
+func (session *Session) Send(env *Envelope) error {
 	if session.IsClosed() {
 		return SessionClosedErr
 	}
-	//if session.sendChan == nil {
-	//	//TODO 解析这个msg
-	//
-	//	buf, err := session.codec.Packet(msg, session)
-	//	if err != nil {
-	//		return err
-	//	}
-	//	if err = session.Write(buf); err != nil {
-	//		logger.Error("session.Write error: ", zap.Error(err))
-	//		return err
-	//	}
-	//}
+	if session.sendChan == nil {
+		buf, err := session.codec.Packet(env, session)
+		if err != nil {
+			session.emit(func(hooks Hooks) { hooks.OnError(session, err) })
+			return err
+		}
+		if err = session.Write(buf); err != nil {
+			logger.Error("session.Write error: ", zap.Error(err))
+			session.closeOnError(err, CloseWriteErr)
+			return err
+		}
+		return nil
+	}
 	select {
-	case session.sendChan <- msg:
+	case session.sendChan <- env:
 		return nil
 	default:
 		return SessionClosedErr
 	}
 }
 
+//不经过发送队列直接写出最后一条消息后关闭连接，用于踢下线等需要客户端收到原因的场景
+func (session *Session) SendAndClose(env *Envelope, reason CloseReason) error {
+	if session.IsClosed() {
+		return SessionClosedErr
+	}
+	session.SetWaite()
+	buf, err := session.codec.Packet(env, session)
+	if err == nil {
+		err = session.Write(buf)
+	}
+	session.CloseWithReason(reason, err)
+	return err
+}
+
+//连接使用的消息格式，收发两个方向相同
+func (session *Session) Format() WireFormat {
+	return session.format
+}
+
+//按连接的消息格式生成信封
+func (session *Session) NewEnvelope(cmd uint32, msg proto.Message) (*Envelope, error) {
+	return NewFormatEnvelope(session.format, cmd, msg)
+}
+
+//可以在发送时调用，之后打包的消息使用新的压缩算法
+func (session *Session) SetCompressor(c Compressor) {
+	session.compMutex.Lock()
+	session.compressor = c
+	session.compMutex.Unlock()
+}
+
+func (session *Session) GetCompressor() Compressor {
+	session.compMutex.RLock()
+	defer session.compMutex.RUnlock()
+	return session.compressor
+}
+
+//协商压缩算法后，达到阈值的数据在加密前压缩，返回压缩使用的算法，没有压缩时为nil
+func (session *Session) compress(data []byte) ([]byte, Compressor, error) {
+	threshold := session.cfg.CompressThreshold
+	if threshold <= 0 {
+		threshold = defaultCompressThreshold
+	}
+	c := session.GetCompressor()
+	if c == nil || len(data) < threshold {
+		return data, nil, nil
+	}
+	result, err := c.Compress(data)
+	if err != nil {
+		logger.Error("session compress", zap.String("compressor", c.Name()), zap.Error(err))
+		return nil, nil, err
+	}
+	if len(result) >= len(data) {
+		return data, nil, nil
+	}
+	return result, c, nil
+}
+
+//解压后的数据加上已收到的received字节不能超过maxMsgSize
+func (session *Session) decompress(c Compressor, data []byte, received uint32) ([]byte, error) {
+	if c == nil {
+		logger.Error("session decompress", zap.Error(CompressorErr))
+		return nil, CompressorErr
+	}
+	var maxSize uint32
+	if session.cfg.MaxMsgSize > 0 {
+		if received >= session.cfg.MaxMsgSize {
+			return nil, DecompressLenErr
+		}
+		maxSize = session.cfg.MaxMsgSize - received
+	}
+	result, err := c.Decompress(data, maxSize)
+	if err != nil {
+		logger.Error("session decompress", zap.String("compressor", c.Name()), zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+//最近收到的帧的标识，可以通过FrameHeader获取优先级等信息
+func (session *Session) RecvFlags() uint8 {
+	return session.recvFlags
+}
+
+func (session *Session) IsLegacyFrame() bool {
+	return session.legacyFrame
+}
+
 func (session *Session) IsClosed() bool {
 	return atomic.LoadInt32(&session.closeFlag) == 1
 }
 
+func (session *Session) Config() SessionCfg {
+	return session.cfg
+}
+
+//设置连接属性，可以并发调用
+func (session *Session) SetAttr(key string, value interface{}) {
+	session.attrs.Store(key, value)
+}
+
+func (session *Session) Attr(key string) interface{} {
+	value, _ := session.attrs.Load(key)
+	return value
+}
+
 func (session *Session) SetUserId(uid uint64) {
 	session.userId = uid
 }
@@ -0,0 +1,205 @@
+package net_lib
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+)
+
+var UpgradeFdErr = errors.New("[upgrade] listener does not support fd handoff")
+var UpgradeReadyErr = errors.New("[upgrade] new process exited before ready")
+
+//新进程通过该环境变量得到继承的监听和通知旧进程的管道
+const upgradeEnv = "ZWCHAT_UPGRADE"
+
+//子进程的文件描述符0-2为标准输入输出，继承的文件从3开始
+const upgradeFdStart = 3
+
+type upgradeListener struct {
+	Network string  `json:"network"`
+	Addr    string  `json:"addr"`
+	Fd      uintptr `json:"fd"`
+}
+
+type upgradeState struct {
+	Listeners []upgradeListener `json:"listeners"`
+	ReadyFd   uintptr           `json:"readyFd"`
+}
+
+var inherited struct {
+	sync.Mutex
+	once      sync.Once
+	listeners map[string]net.Listener
+	ready     *os.File
+}
+
+func inheritKey(network, addr string) string {
+	return network + "|" + addr
+}
+
+//解析旧进程传递的监听，只在第一次使用时执行
+func loadInherited() {
+	inherited.once.Do(func() {
+		inherited.listeners = make(map[string]net.Listener)
+		value := os.Getenv(upgradeEnv)
+		if value == "" {
+			return
+		}
+		os.Unsetenv(upgradeEnv)
+		var state upgradeState
+		if err := json.Unmarshal([]byte(value), &state); err != nil {
+			logger.Error("loadInherited", zap.String("env", value), zap.Error(err))
+			return
+		}
+		for _, item := range state.Listeners {
+			f := os.NewFile(item.Fd, item.Addr)
+			l, err := net.FileListener(f)
+			f.Close()
+			if err != nil {
+				logger.Error("loadInherited", zap.String("addr", item.Addr), zap.Error(err))
+				continue
+			}
+			inherited.listeners[inheritKey(item.Network, item.Addr)] = l
+		}
+		if state.ReadyFd != 0 {
+			inherited.ready = os.NewFile(state.ReadyFd, "upgrade-ready")
+		}
+		logger.Info("inherit listeners", zap.Int("count", len(inherited.listeners)))
+	})
+}
+
+//取出继承的监听，先按配置的地址匹配，再按实际监听的地址匹配
+func inheritListener(network, addr string) net.Listener {
+	loadInherited()
+	inherited.Lock()
+	defer inherited.Unlock()
+	key := inheritKey(network, addr)
+	l, ok := inherited.listeners[key]
+	if !ok {
+		for k, item := range inherited.listeners {
+			if item.Addr().Network() == network && item.Addr().String() == addr {
+				key, l, ok = k, item, true
+				break
+			}
+		}
+	}
+	if ok {
+		delete(inherited.listeners, key)
+	}
+	return l
+}
+
+//新进程开始接受连接后调用，通知旧进程关闭，并关闭没有使用的继承监听。
+//不是由Upgrade启动的进程调用时什么都不做
+func UpgradeReady() error {
+	loadInherited()
+	inherited.Lock()
+	defer inherited.Unlock()
+	for key, l := range inherited.listeners {
+		logger.Info("close unused inherited listener", zap.String("addr", l.Addr().String()))
+		l.Close()
+		delete(inherited.listeners, key)
+	}
+	if inherited.ready == nil {
+		return nil
+	}
+	_, err := inherited.ready.Write([]byte{1})
+	inherited.ready.Close()
+	inherited.ready = nil
+	return err
+}
+
+type fileListener interface {
+	File() (*os.File, error)
+}
+
+//用相同的参数启动新的进程并把所有监听传给它，等待新进程调用UpgradeReady。
+//extra为其它需要传递的监听(如管理接口)，新进程通过Listen取得。
+//返回成功后旧进程应当调用Drain关闭已有的连接；失败时旧进程继续服务
+func (server *Server) Upgrade(timeout time.Duration, extra ...net.Listener) (*os.Process, error) {
+	server.listenerMutex.Lock()
+	items := append([]*listener(nil), server.listeners...)
+	server.listenerMutex.Unlock()
+	for _, l := range extra {
+		items = append(items, &listener{network: l.Addr().Network(), addr: l.Addr().String(), raw: l})
+	}
+
+	var state upgradeState
+	files := []*os.File{os.Stdin, os.Stdout, os.Stderr}
+	defer func() {
+		for _, f := range files[upgradeFdStart:] {
+			f.Close()
+		}
+	}()
+	for _, item := range items {
+		fl, ok := item.raw.(fileListener)
+		if !ok {
+			return nil, UpgradeFdErr
+		}
+		f, err := fl.File()
+		if err != nil {
+			return nil, err
+		}
+		state.Listeners = append(state.Listeners, upgradeListener{
+			Network: item.network,
+			Addr:    item.addr,
+			Fd:      uintptr(len(files)),
+		})
+		files = append(files, f)
+	}
+	ready, notify, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer ready.Close()
+	state.ReadyFd = uintptr(len(files))
+	files = append(files, notify)
+
+	value, err := json.Marshal(&state)
+	if err != nil {
+		return nil, err
+	}
+	path, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	env := []string{upgradeEnv + "=" + string(value)}
+	for _, item := range os.Environ() {
+		if !strings.HasPrefix(item, upgradeEnv+"=") {
+			env = append(env, item)
+		}
+	}
+	process, err := os.StartProcess(path, os.Args, &os.ProcAttr{Env: env, Files: files})
+	if err != nil {
+		return nil, err
+	}
+	//关闭父进程的写端，新进程退出时读取会返回EOF
+	notify.Close()
+	files = files[:len(files)-1]
+
+	ready.SetReadDeadline(time.Now().Add(timeout))
+	if _, err = ready.Read(make([]byte, 1)); err != nil {
+		logger.Error("Upgrade wait ready", zap.Int("pid", process.Pid), zap.Error(err))
+		process.Kill()
+		process.Wait()
+		if os.IsTimeout(err) {
+			return nil, err
+		}
+		return nil, UpgradeReadyErr
+	}
+	//unix socket的文件已经由新进程使用，旧进程关闭监听时不能删除
+	for _, item := range items {
+		if l, ok := item.raw.(*net.UnixListener); ok {
+			l.SetUnlinkOnClose(false)
+		}
+	}
+	logger.Info("Upgrade new process ready", zap.Int("pid", process.Pid))
+	return process, nil
+}
@@ -0,0 +1,158 @@
+// +build !windows
+
+package net_lib
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+//服务端持续读取，客户端断开后关闭连接
+func receiveLoop(session *Session) {
+	for {
+		if _, err := session.Receive(); err != nil {
+			session.Close()
+			return
+		}
+	}
+}
+
+//读取GOAWAY控制帧，返回原因
+func readGoAway(t *testing.T, conn net.Conn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	r := NewReader(bufio.NewReader(conn))
+	header, err := r.ReadFrameHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := r.ReadN(int(header.Length))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !header.Control() {
+		t.Fatalf("want control frame, got %+v", header)
+	}
+	return ParseControl(data[8:])[ControlGoAway]
+}
+
+func TestGoAwayTcp(t *testing.T) {
+	server, err := Serve("tcp", "127.0.0.1:0", &SessionCfg{MaxMsgSize: 64 << 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	session, conn := acceptTcp(t, server)
+	defer conn.Close()
+	if err = session.GoAway(GoAwayRestart); err != nil {
+		t.Fatal(err)
+	}
+	if reason := readGoAway(t, conn); reason != GoAwayRestart {
+		t.Fatalf("want goaway %q, got %q", GoAwayRestart, reason)
+	}
+	if !session.IsWaiting() || session.IsClosed() {
+		t.Fatal("session should be waiting and still open after goaway")
+	}
+}
+
+func TestDrain(t *testing.T) {
+	server, err := Serve("tcp", "127.0.0.1:0", &SessionCfg{MaxMsgSize: 64 << 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := server.Listener().Addr().String()
+	//第一个客户端收到GOAWAY后断开，第二个不处理
+	polite, politeConn := acceptTcp(t, server)
+	defer politeConn.Close()
+	go receiveLoop(polite)
+	stubborn, stubbornConn := acceptTcp(t, server)
+	defer stubbornConn.Close()
+	go receiveLoop(stubborn)
+	go func() {
+		conn := politeConn
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		r := NewReader(bufio.NewReader(conn))
+		if header, err := r.ReadFrameHeader(); err == nil && header.Control() {
+			conn.Close()
+		}
+	}()
+	if count := server.Manager().Count(); count != 2 {
+		t.Fatalf("want 2 sessions, got %d", count)
+	}
+	if remain := server.Drain(GoAwayShutdown, 500*time.Millisecond); remain != 1 {
+		t.Fatalf("want 1 session left after drain, got %d", remain)
+	}
+	if !polite.IsClosed() || !stubborn.IsClosed() {
+		t.Fatal("all sessions should be closed after drain")
+	}
+	if _, err = net.DialTimeout("tcp", addr, time.Second); err == nil {
+		t.Fatal("listener should be closed after drain")
+	}
+}
+
+//模拟旧进程通过环境变量传递监听和通知管道
+func TestInheritListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//继承的描述符由loadInherited负责关闭，复制一份避免重复关闭
+	fd, err := syscall.Dup(int(f.Fd()))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready, notify, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ready.Close()
+	notifyFd, err := syscall.Dup(int(notify.Fd()))
+	notify.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, _ := json.Marshal(&upgradeState{
+		Listeners: []upgradeListener{{Network: "tcp", Addr: addr, Fd: uintptr(fd)}},
+		ReadyFd:   uintptr(notifyFd),
+	})
+	os.Setenv(upgradeEnv, string(value))
+	inherited.once = sync.Once{}
+	defer func() { inherited.once = sync.Once{} }()
+
+	server, err := Serve("tcp", addr, &SessionCfg{MaxMsgSize: 64 << 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	if os.Getenv(upgradeEnv) != "" {
+		t.Fatal("upgrade env should be cleared after loading")
+	}
+	//旧进程关闭监听后，新进程仍然可以接受连接
+	l.Close()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	acceptSession(t, server)
+
+	if err = UpgradeReady(); err != nil {
+		t.Fatal(err)
+	}
+	ready.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err = ready.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("ready not notified: %v", err)
+	}
+}
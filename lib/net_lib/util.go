@@ -32,7 +32,7 @@ func DeriveMsgKey(shareKey []byte, buf []byte) []byte {
 	return result
 }
 
-func DeriveAESKey(shareKey, msgKey []byte, key, iv []byte) {
+func DeriveAESKey(shareKey, msgKey []byte) (key, iv []byte) {
 	if len(shareKey) != 32 {
 		panic("invalid auth key len")
 	}
@@ -59,6 +59,7 @@ func DeriveAESKey(shareKey, msgKey []byte, key, iv []byte) {
 	copy(iv, b[:8])
 	copy(iv[8:24], a[8:24])
 	copy(iv[24:32], b[24:32])
+	return
 }
 
 //AES CBC 模式加密
@@ -15,11 +15,29 @@ const http200Upgrade = "HTTP/1.1 101 Switching Protocols\r\n" +
 	"Upgrade: websocket\r\n" +
 	"Connection: Upgrade\r\n" +
 	"Sec-WebSocket-Accept: %s\r\n" +
+	"%s" +
 	"Date:%s\r\n\r\n"
 
+const http400Subprotocol = "HTTP/1.1 400 Bad Request\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"Connection: close\r\n" +
+	"Content-Length: 0\r\n\r\n"
+
+//websocket子协议，决定连接使用的消息格式
+const (
+	SubprotocolProto = "zwchat.proto.v1"
+	SubprotocolJSON  = "zwchat.json.v1"
+)
+
+var subprotocols = map[string]WireFormat{
+	SubprotocolProto: FormatProto,
+	SubprotocolJSON:  FormatJSON,
+}
+
 var keyGUID = []byte("258EAFA5-E914-47DA-95CA-C5AB0DC85B11")
 var WsSWKErr = errors.New("Sec-WebSocket-Key header is error")
 var WsUpgErr = errors.New("Upgrade header is error")
+var WsSubprotocolErr = errors.New("Sec-WebSocket-Protocol not supported")
 
 // 检查握手协议是否正确
 func CheckUpgrade(header map[string]string) error {
@@ -40,10 +58,29 @@ func ComputeAcceptedKey(secWsKey string) string {
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
 
-func CreateUpgradeResp(secWsKey string) string {
+//subprotocol为空时不返回Sec-WebSocket-Protocol
+func CreateUpgradeResp(secWsKey, subprotocol string) string {
 	TimeFormat := "Mon, 02 Jan 2006 15:04:05 GMT"
 	modtimeStr := time.Now().UTC().Format(TimeFormat)
-	return fmt.Sprintf(http200Upgrade, secWsKey, modtimeStr)
+	var protocolHeader string
+	if subprotocol != "" {
+		protocolHeader = fmt.Sprintf("Sec-WebSocket-Protocol: %s\r\n", subprotocol)
+	}
+	return fmt.Sprintf(http200Upgrade, secWsKey, protocolHeader, modtimeStr)
+}
+
+//按客户端的顺序选择第一个支持的子协议，客户端没有提供子协议时使用protobuf格式
+func NegotiateSubprotocol(offered string) (string, WireFormat, error) {
+	if strings.TrimSpace(offered) == "" {
+		return "", FormatProto, nil
+	}
+	for _, item := range strings.Split(offered, ",") {
+		name := strings.TrimSpace(item)
+		if format, ok := subprotocols[name]; ok {
+			return name, format, nil
+		}
+	}
+	return "", FormatProto, WsSubprotocolErr
 }
 
 func GetHeader(r *Reader, maxSize uint32) (int, map[string]string) {
@@ -53,12 +90,28 @@ func GetHeader(r *Reader, maxSize uint32) (int, map[string]string) {
 	headerBytes := bytes.Split(data, []byte("\r\n"))[1:]
 	headers := make(map[string]string, len(headerBytes))
 	for _, item := range headerBytes {
-		header := strings.Split(string(item), ":")
+		header := strings.SplitN(string(item), ":", 2)
+		if len(header) != 2 {
+			continue
+		}
 		headers[header[0]] = strings.TrimSpace(header[1])
 	}
 	return length, headers
 }
 
+//不区分大小写的获取头部字段
+func GetHeaderValue(header map[string]string, key string) string {
+	if value, ok := header[key]; ok {
+		return value
+	}
+	for k, value := range header {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return ""
+}
+
 func IsWsHandshake(header map[string]string) bool {
 	if upgrade, ok := header["Upgrade"]; ok {
 		if upgrade == "websocket" {
@@ -47,6 +47,12 @@ func (w *Writer) WriteUint64(v uint64) {
 	w.buf.Write(b[:])
 }
 
+func (w *Writer) WriteUvarint(v uint64) {
+	var b = make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(b, v)
+	w.buf.Write(b[:n])
+}
+
 func (w *Writer) WriteCmd(v uint32) {
 	w.WriteUint32(v)
 }
@@ -57,7 +63,6 @@ func (w *Writer) Write(v ...[]byte) {
 	}
 }
 
-
 func (w *Writer) WriteStrings(v ...string) {
 	for _, item := range v {
 		w.buf.WriteString(item)
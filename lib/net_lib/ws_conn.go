@@ -133,26 +133,33 @@ func (c *Session) handleProtocolError(message string) error {
 // WriteControl writes a control message with the given deadline. The allowed
 // message types are CloseMessage, PingMessage and PongMessage.
 func (c *Session) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	buf, err := wsControlFrame(messageType, data)
+	if err != nil {
+		return err
+	}
+	//控制帧直接写入连接，不经过codec
+	err = c.Write(buf)
+	if messageType == CloseMessage {
+		c.SetWaite()
+		c.Close()
+	}
+	return err
+}
+
+func wsControlFrame(messageType int, data []byte) ([]byte, error) {
 	if !isControl(messageType) {
-		return errBadWriteOpCode
+		return nil, errBadWriteOpCode
 	}
 	length := len(data)
 	if length > maxControlFramePayloadSize {
-		return errInvalidControlFrame
+		return nil, errInvalidControlFrame
 	}
 
 	buf := make([]byte, 2+length)
 	buf[0] = byte(messageType) | finalBit
 	buf[1] = byte(length)
 	copy(buf[2:], data)
-	c.Send(buf)
-	if messageType == CloseMessage {
-		c.closeWait.Add(1)
-		c.SetWaite()
-		c.Close()
-	}
-
-	return nil
+	return buf, nil
 }
 
 func (c *Session) flushFrame(extra []byte) []byte {
@@ -280,7 +287,7 @@ func (c *Session) advanceFrame() (int, error) {
 		payload, err = c.r.ReadN(int(c.wsConn.readRemaining))
 		c.wsConn.readRemaining = 0
 		if err != nil {
-			logger.Error("advanceFrame readRemaining error:", zap.Error(err))
+			c.logReadErr("advanceFrame readRemaining error:", err)
 			return noFrame, err
 		}
 		//解码
@@ -313,5 +320,9 @@ func (c *Session) advanceFrame() (int, error) {
 		return noFrame, errClientClose
 	}
 
+	//分片消息之间的控制帧不能提交，回滚时需要重新读取前面的分片
+	if c.wsConn.readFinal {
+		c.commitRead()
+	}
 	return frameType, nil
 }
@@ -0,0 +1,102 @@
+package route
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	etcdv3 "github.com/coreos/etcd/clientv3"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/lib/etcd_lib"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultTTL = 15 * time.Second
+	maxTxnOps  = 128 //etcd默认的单个事务最大操作数
+)
+
+//路由写在本进程的租约下，进程崩溃后在ttl内过期。
+//租约失效(如与etcd长时间断开)后重新申请，并把本进程写入的路由写回。
+//key为/{prefix}/route/{uid}/{nodeId}/{sessionId}，value为json
+type EtcdStore struct {
+	client *etcdv3.Client
+	prefix string
+	keeper *etcd_lib.LeaseKeeper
+	shared bool //client和租约由外部创建，Close时不关闭
+}
+
+//Close时关闭client
+func NewEtcdStore(client *etcdv3.Client, prefix string, ttl time.Duration) *EtcdStore {
+	if ttl < time.Second {
+		ttl = defaultTTL
+	}
+	return &EtcdStore{client: client, prefix: prefix, keeper: etcd_lib.NewLeaseKeeper(client, ttl, "route")}
+}
+
+//与进程中其他使用方共用租约和它的client，路由在租约的所有者Close时删除
+func NewSharedEtcdStore(keeper *etcd_lib.LeaseKeeper, prefix string) *EtcdStore {
+	return &EtcdStore{client: keeper.Client(), prefix: prefix, keeper: keeper, shared: true}
+}
+
+//uid后带/，避免前缀匹配到其他uid
+func (s *EtcdStore) userPrefix(uid uint64) string {
+	return fmt.Sprintf("/%s/route/%d/", s.prefix, uid)
+}
+
+func (s *EtcdStore) key(route *Route) string {
+	return fmt.Sprintf("%s%s/%d", s.userPrefix(route.Uid), route.NodeId, route.SessionId)
+}
+
+//写入失败时在后台写回
+func (s *EtcdStore) Put(ctx context.Context, route *Route) error {
+	value, err := json.Marshal(route)
+	if err != nil {
+		return err
+	}
+	return s.keeper.Put(ctx, s.key(route), string(value))
+}
+
+func (s *EtcdStore) Delete(ctx context.Context, route *Route) error {
+	return s.keeper.Delete(ctx, s.key(route))
+}
+
+//每个事务最多查询maxTxnOps个用户，结果在同一个版本上
+func (s *EtcdStore) Lookup(ctx context.Context, uids ...uint64) (map[uint64][]*Route, error) {
+	result := make(map[uint64][]*Route)
+	for start := 0; start < len(uids); start += maxTxnOps {
+		end := start + maxTxnOps
+		if end > len(uids) {
+			end = len(uids)
+		}
+		ops := make([]etcdv3.Op, 0, end-start)
+		for _, uid := range uids[start:end] {
+			ops = append(ops, etcdv3.OpGet(s.userPrefix(uid), etcdv3.WithPrefix()))
+		}
+		resp, err := s.client.Txn(ctx).Then(ops...).Commit()
+		if err != nil {
+			return nil, err
+		}
+		for _, op := range resp.Responses {
+			for _, kv := range op.GetResponseRange().Kvs {
+				route := &Route{}
+				if err = json.Unmarshal(kv.Value, route); err != nil {
+					logger.Error("route unmarshal", zap.String("key", string(kv.Key)), zap.Error(err))
+					continue
+				}
+				result[route.Uid] = append(result[route.Uid], route)
+			}
+		}
+	}
+	return result, nil
+}
+
+//撤销租约，本进程写入的路由立即删除
+func (s *EtcdStore) Close() error {
+	if s.shared {
+		return nil
+	}
+	s.keeper.Close()
+	return s.client.Close()
+}
@@ -0,0 +1,63 @@
+package route
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+type routeKey struct {
+	nodeId    string
+	sessionId uint64
+}
+
+//进程内的路由表，保存和返回的都是副本
+type MemoryStore struct {
+	mutex  sync.RWMutex
+	routes map[uint64]map[routeKey]*Route
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{routes: make(map[uint64]map[routeKey]*Route)}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, route *Route) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	userRoutes := s.routes[route.Uid]
+	if userRoutes == nil {
+		userRoutes = make(map[routeKey]*Route)
+		s.routes[route.Uid] = userRoutes
+	}
+	r := *route
+	userRoutes[routeKey{route.NodeId, route.SessionId}] = &r
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, route *Route) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	userRoutes := s.routes[route.Uid]
+	delete(userRoutes, routeKey{route.NodeId, route.SessionId})
+	if len(userRoutes) == 0 {
+		delete(s.routes, route.Uid)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Lookup(ctx context.Context, uids ...uint64) (map[uint64][]*Route, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	result := make(map[uint64][]*Route)
+	for _, uid := range uids {
+		for _, route := range s.routes[uid] {
+			r := *route
+			result[uid] = append(result[uid], &r)
+		}
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,78 @@
+package route
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	etcdv3 "github.com/coreos/etcd/clientv3"
+	"github.com/imkuqin-zw/ZWChat/lib/etcd_lib"
+	"golang.org/x/net/context"
+)
+
+const (
+	StoreMemory = "memory"
+	StoreEtcd   = "etcd"
+)
+
+//用户到接入节点的路由，同一用户可以在多个节点上有多个连接
+type Route struct {
+	Uid       uint64 `json:"uid"`
+	NodeId    string `json:"nodeId"` //接入节点id，即Push服务的地址
+	SessionId uint64 `json:"sessionId"`
+	ConnType  int8   `json:"connType"`  //tcp、http、ws
+	Platform  int32  `json:"platform"`  //external.Platform，握手时上报
+	LoginTime int64  `json:"loginTime"` //绑定时间(ms)
+}
+
+//路由的存储，接入节点在绑定和解绑用户时写入和删除，Logic查询。实现需要可以并发调用
+type Store interface {
+	//写入或覆盖一条路由，以(uid, nodeId, sessionId)区分
+	Put(ctx context.Context, route *Route) error
+	Delete(ctx context.Context, route *Route) error
+	//批量查询，不在线的用户不在结果中
+	Lookup(ctx context.Context, uids ...uint64) (map[uint64][]*Route, error)
+	Close() error
+}
+
+type Cfg struct {
+	Store       string        `yaml:"store"`       //memory或etcd
+	Target      string        `yaml:"target"`      //etcd地址，多个用逗号分隔
+	Prefix      string        `yaml:"prefix"`      //etcd的key前缀
+	DialTimeout time.Duration `yaml:"dialTimeout"` //连接etcd的超时
+	TTL         time.Duration `yaml:"ttl"`         //租约时间，节点崩溃后路由在ttl内过期
+	//etcd与进程中其他使用方共用的租约，设置时不使用Target和TTL，Close时不关闭
+	Lease *etcd_lib.LeaseKeeper `yaml:"-"`
+}
+
+//按配置创建存储，memory只在本进程内有效，用于单节点部署和测试
+func New(cfg Cfg) (Store, error) {
+	switch cfg.Store {
+	case StoreMemory:
+		return NewMemoryStore(), nil
+	case StoreEtcd:
+		if cfg.Lease != nil {
+			return NewSharedEtcdStore(cfg.Lease, cfg.Prefix), nil
+		}
+		client, err := etcdv3.New(etcdv3.Config{
+			Endpoints:   strings.Split(cfg.Target, ","),
+			DialTimeout: cfg.DialTimeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewEtcdStore(client, cfg.Prefix, cfg.TTL), nil
+	}
+	return nil, fmt.Errorf("[route] unknown store %q", cfg.Store)
+}
+
+//按接入节点分组，群消息扇出时每个节点只调用一次Push服务
+func GroupByNode(routes map[uint64][]*Route) map[string][]*Route {
+	nodes := make(map[string][]*Route)
+	for _, userRoutes := range routes {
+		for _, route := range userRoutes {
+			nodes[route.NodeId] = append(nodes[route.NodeId], route)
+		}
+	}
+	return nodes
+}
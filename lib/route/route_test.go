@@ -0,0 +1,93 @@
+package route
+
+import (
+	"testing"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/lib/etcd_lib"
+	"golang.org/x/net/context"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	routes := []*Route{
+		{Uid: 1, NodeId: "a", SessionId: 1, ConnType: 0},
+		{Uid: 1, NodeId: "b", SessionId: 1, ConnType: 2},
+		{Uid: 2, NodeId: "a", SessionId: 2},
+	}
+	for _, route := range routes {
+		if err := s.Put(ctx, route); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//相同(uid, nodeId, sessionId)覆盖
+	if err := s.Put(ctx, &Route{Uid: 2, NodeId: "a", SessionId: 2, Platform: 1}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.Lookup(ctx, 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result[1]) != 2 || len(result[2]) != 1 || result[2][0].Platform != 1 {
+		t.Fatalf("unexpected lookup %v", result)
+	}
+	if _, ok := result[3]; ok {
+		t.Fatal("offline user should not be in result")
+	}
+	//返回的是副本
+	result[2][0].NodeId = "x"
+	if result, _ = s.Lookup(ctx, 2); result[2][0].NodeId != "a" {
+		t.Fatal("lookup should return copies")
+	}
+
+	if err = s.Delete(ctx, routes[0]); err != nil {
+		t.Fatal(err)
+	}
+	if result, _ = s.Lookup(ctx, 1); len(result[1]) != 1 || result[1][0].NodeId != "b" {
+		t.Fatalf("unexpected lookup after delete %v", result)
+	}
+	s.Delete(ctx, routes[1])
+	if _, ok := s.routes[1]; ok {
+		t.Fatal("user without routes should be removed")
+	}
+}
+
+func TestGroupByNode(t *testing.T) {
+	nodes := GroupByNode(map[uint64][]*Route{
+		1: {{Uid: 1, NodeId: "a"}, {Uid: 1, NodeId: "b"}},
+		2: {{Uid: 2, NodeId: "a"}},
+	})
+	if len(nodes) != 2 || len(nodes["a"]) != 2 || len(nodes["b"]) != 1 {
+		t.Fatalf("unexpected groups %v", nodes)
+	}
+}
+
+func TestEtcdKey(t *testing.T) {
+	s := NewEtcdStore(nil, "zw_chat", 0)
+	if key := s.key(&Route{Uid: 1, NodeId: "127.0.0.1:11200", SessionId: 9}); key != "/zw_chat/route/1/127.0.0.1:11200/9" {
+		t.Fatalf("unexpected key %s", key)
+	}
+	if prefix := s.userPrefix(1); prefix != "/zw_chat/route/1/" {
+		t.Fatalf("unexpected prefix %s", prefix)
+	}
+	if ttl := s.keeper.TTL(); ttl != defaultTTL {
+		t.Fatalf("unexpected default ttl %v", ttl)
+	}
+}
+
+//设置共用的租约时使用它和它的client，Close时不关闭
+func TestSharedEtcdStore(t *testing.T) {
+	keeper := etcd_lib.NewLeaseKeeper(nil, time.Minute, "test")
+	defer keeper.Close()
+	store, err := New(Cfg{Store: StoreEtcd, Prefix: "zw_chat", Lease: keeper})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := store.(*EtcdStore); !ok || s.keeper != keeper || !s.shared {
+		t.Fatalf("want store on the shared lease, got %#v", store)
+	}
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,166 @@
+package balancer
+
+import (
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	RoundRobin = "round_robin"
+	Weighted   = "weighted"
+)
+
+func init() {
+	balancer.Register(&builder{name: RoundRobin, newPicker: newRoundRobinPicker})
+	balancer.Register(&builder{name: Weighted, newPicker: newWeightedPicker})
+}
+
+//resolver.Address的Metadata实现Weighter时，加权负载均衡按权重分配请求
+type Weighter interface {
+	GetWeight() int
+}
+
+//没有权重的地址按1计算
+func weight(addr resolver.Address) int {
+	if w, ok := addr.Metadata.(Weighter); ok {
+		if n := w.GetWeight(); n > 0 {
+			return n
+		}
+	}
+	return 1
+}
+
+//用于grpc.WithBalancerBuilder，name为RoundRobin或Weighted
+func Get(name string) balancer.Builder {
+	return balancer.Get(name)
+}
+
+//ready为可用的连接及其地址，不为空
+type pickerBuilder func(ready map[balancer.SubConn]resolver.Address) balancer.Picker
+
+type builder struct {
+	name      string
+	newPicker pickerBuilder
+}
+
+func (b *builder) Name() string {
+	return b.name
+}
+
+func (b *builder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	return &baseBalancer{
+		cc:        cc,
+		newPicker: b.newPicker,
+		subConns:  make(map[string]balancer.SubConn),
+		addrs:     make(map[balancer.SubConn]resolver.Address),
+		states:    make(map[balancer.SubConn]connectivity.State),
+		state:     connectivity.Idle,
+		picker:    errPicker{balancer.ErrNoSubConnAvailable},
+	}
+}
+
+//每个地址一个连接，只在可用的连接中选择，具体策略由picker决定。
+//grpc保证除Pick外的方法在同一个协程中调用
+type baseBalancer struct {
+	cc        balancer.ClientConn
+	newPicker pickerBuilder
+	subConns  map[string]balancer.SubConn
+	addrs     map[balancer.SubConn]resolver.Address
+	states    map[balancer.SubConn]connectivity.State
+	state     connectivity.State
+	picker    balancer.Picker
+}
+
+func (b *baseBalancer) HandleResolvedAddrs(addrs []resolver.Address, err error) {
+	if err != nil {
+		grpclog.Infof("balancer: resolver error %v", err)
+		return
+	}
+	changed := false
+	set := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		set[addr.Addr] = true
+		if sc, ok := b.subConns[addr.Addr]; ok {
+			//地址不变时只更新权重等元数据
+			if weight(b.addrs[sc]) != weight(addr) {
+				changed = true
+			}
+			b.addrs[sc] = addr
+			continue
+		}
+		sc, err := b.cc.NewSubConn([]resolver.Address{addr}, balancer.NewSubConnOptions{})
+		if err != nil {
+			grpclog.Warningf("balancer: new subconn %s: %v", addr.Addr, err)
+			continue
+		}
+		b.subConns[addr.Addr] = sc
+		b.addrs[sc] = addr
+		b.states[sc] = connectivity.Idle
+		sc.Connect()
+		changed = true
+	}
+	//移除的连接在收到Shutdown后清理状态
+	for addr, sc := range b.subConns {
+		if !set[addr] {
+			delete(b.subConns, addr)
+			b.cc.RemoveSubConn(sc)
+			changed = true
+		}
+	}
+	if changed {
+		b.regeneratePicker()
+		b.cc.UpdateBalancerState(b.state, b.picker)
+	}
+}
+
+func (b *baseBalancer) HandleSubConnStateChange(sc balancer.SubConn, s connectivity.State) {
+	old, ok := b.states[sc]
+	if !ok || s == old {
+		return
+	}
+	switch s {
+	case connectivity.Idle:
+		sc.Connect()
+	case connectivity.Shutdown:
+		delete(b.states, sc)
+		delete(b.addrs, sc)
+	}
+	if s != connectivity.Shutdown {
+		b.states[sc] = s
+	}
+	//可用连接不变时保留原picker，只更新整体状态
+	if (old == connectivity.Ready) != (s == connectivity.Ready) || b.state != connectivity.Ready {
+		b.regeneratePicker()
+	}
+	b.cc.UpdateBalancerState(b.state, b.picker)
+}
+
+func (b *baseBalancer) Close() {}
+
+//有可用连接时为Ready，否则有连接中的为Connecting
+func (b *baseBalancer) regeneratePicker() {
+	ready := make(map[balancer.SubConn]resolver.Address)
+	connecting := false
+	for sc, s := range b.states {
+		//已移除但还没有收到Shutdown的连接
+		if b.subConns[b.addrs[sc].Addr] != sc {
+			continue
+		}
+		switch s {
+		case connectivity.Ready:
+			ready[sc] = b.addrs[sc]
+		case connectivity.Idle, connectivity.Connecting:
+			connecting = true
+		}
+	}
+	switch {
+	case len(ready) > 0:
+		b.state, b.picker = connectivity.Ready, b.newPicker(ready)
+	case connecting:
+		b.state, b.picker = connectivity.Connecting, errPicker{balancer.ErrNoSubConnAvailable}
+	default:
+		b.state, b.picker = connectivity.TransientFailure, errPicker{balancer.ErrTransientFailure}
+	}
+}
@@ -0,0 +1,189 @@
+package balancer
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/resolver"
+)
+
+type weightMeta int
+
+func (w weightMeta) GetWeight() int {
+	return int(w)
+}
+
+type fakeSubConn struct {
+	addr string
+}
+
+func (sc *fakeSubConn) UpdateAddresses([]resolver.Address) {}
+
+func (sc *fakeSubConn) Connect() {}
+
+type healthServer struct{}
+
+func (healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func serve(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, healthServer{})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+//测试用的resolver，地址由测试直接推送
+type testResolver struct {
+	mutex sync.Mutex
+	cc    resolver.ClientConn
+	ready chan struct{}
+}
+
+func (r *testResolver) Scheme() string {
+	return "balancer-test"
+}
+
+func (r *testResolver) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOption) (resolver.Resolver, error) {
+	r.mutex.Lock()
+	r.cc = cc
+	r.mutex.Unlock()
+	close(r.ready)
+	return r, nil
+}
+
+func (r *testResolver) ResolveNow(resolver.ResolveNowOption) {}
+
+func (r *testResolver) Close() {}
+
+func (r *testResolver) update(addrs []resolver.Address) {
+	<-r.ready
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cc.NewAddress(addrs)
+}
+
+func dial(t *testing.T, name string) (*grpc.ClientConn, *testResolver) {
+	r := &testResolver{ready: make(chan struct{})}
+	resolver.Register(r)
+	t.Cleanup(func() { resolver.UnregisterForTesting(r.Scheme()) })
+	conn, err := grpc.Dial(r.Scheme()+":///test", grpc.WithInsecure(), grpc.WithBalancerBuilder(Get(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, r
+}
+
+//统计n次请求落在各个地址上的次数
+func count(t *testing.T, conn *grpc.ClientConn, n int) map[string]int {
+	client := grpc_health_v1.NewHealthClient(conn)
+	result := make(map[string]int)
+	for i := 0; i < n; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		p := &peer.Peer{}
+		_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.Peer(p), grpc.FailFast(false))
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		result[p.Addr.String()]++
+	}
+	return result
+}
+
+//等待所有地址都可用，之后的分布才是确定的
+func waitAll(t *testing.T, conn *grpc.ClientConn, addrs ...string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result := count(t, conn, 20)
+		if len(result) == len(addrs) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want %v, got %v", addrs, result)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	a, b, c := serve(t), serve(t), serve(t)
+	conn, r := dial(t, RoundRobin)
+	r.update([]resolver.Address{{Addr: a}, {Addr: b}, {Addr: c}})
+	waitAll(t, conn, a, b, c)
+	if result := count(t, conn, 30); result[a] != 10 || result[b] != 10 || result[c] != 10 {
+		t.Fatalf("unexpected distribution %v", result)
+	}
+
+	//移除的地址不再收到请求
+	r.update([]resolver.Address{{Addr: a}, {Addr: c}})
+	deadline := time.Now().Add(5 * time.Second)
+	for count(t, conn, 20)[b] > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("removed address still picked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if result := count(t, conn, 20); result[a] != 10 || result[c] != 10 {
+		t.Fatalf("unexpected distribution %v", result)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	a, b := serve(t), serve(t)
+	conn, r := dial(t, Weighted)
+	r.update([]resolver.Address{{Addr: a, Metadata: weightMeta(3)}, {Addr: b, Metadata: weightMeta(1)}})
+	waitAll(t, conn, a, b)
+	if result := count(t, conn, 40); result[a] != 30 || result[b] != 10 {
+		t.Fatalf("unexpected distribution %v", result)
+	}
+
+	//只修改权重时连接保留，分布随之改变
+	r.update([]resolver.Address{{Addr: a, Metadata: weightMeta(1)}, {Addr: b, Metadata: weightMeta(1)}})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result := count(t, conn, 40)
+		if result[a] == 20 && result[b] == 20 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("weight not updated %v", result)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+//权重3:1时不会连续选中同一个实例三次
+func TestWeightedPickerSmooth(t *testing.T) {
+	scs := []*fakeSubConn{{"a"}, {"b"}}
+	p := newWeightedPicker(map[balancer.SubConn]resolver.Address{
+		scs[0]: {Addr: "a", Metadata: weightMeta(3)},
+		scs[1]: {Addr: "b"},
+	})
+	var order []string
+	for i := 0; i < 8; i++ {
+		sc, _, _ := p.Pick(context.Background(), balancer.PickOptions{})
+		order = append(order, sc.(*fakeSubConn).addr)
+	}
+	if got := strings.Join(order, ""); got != "aabaaaba" {
+		t.Fatalf("unexpected order %s", got)
+	}
+	if w := weight(resolver.Address{Metadata: weightMeta(0)}); w != 1 {
+		t.Fatalf("zero weight should be 1, got %d", w)
+	}
+}
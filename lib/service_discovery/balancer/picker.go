@@ -0,0 +1,88 @@
+package balancer
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/resolver"
+)
+
+//没有可用连接时返回固定的错误
+type errPicker struct {
+	err error
+}
+
+func (p errPicker) Pick(ctx context.Context, opts balancer.PickOptions) (balancer.SubConn, func(balancer.DoneInfo), error) {
+	return nil, nil, p.err
+}
+
+//按地址排序，结果与map的遍历顺序无关
+func sortedSubConns(ready map[balancer.SubConn]resolver.Address) []balancer.SubConn {
+	subConns := make([]balancer.SubConn, 0, len(ready))
+	for sc := range ready {
+		subConns = append(subConns, sc)
+	}
+	sort.Slice(subConns, func(i, j int) bool { return ready[subConns[i]].Addr < ready[subConns[j]].Addr })
+	return subConns
+}
+
+//从随机位置开始轮询，避免所有客户端同时从第一个实例开始
+type roundRobinPicker struct {
+	mutex    sync.Mutex
+	subConns []balancer.SubConn
+	next     int
+}
+
+func newRoundRobinPicker(ready map[balancer.SubConn]resolver.Address) balancer.Picker {
+	subConns := sortedSubConns(ready)
+	return &roundRobinPicker{subConns: subConns, next: rand.Intn(len(subConns))}
+}
+
+func (p *roundRobinPicker) Pick(ctx context.Context, opts balancer.PickOptions) (balancer.SubConn, func(balancer.DoneInfo), error) {
+	p.mutex.Lock()
+	sc := p.subConns[p.next]
+	p.next = (p.next + 1) % len(p.subConns)
+	p.mutex.Unlock()
+	return sc, nil, nil
+}
+
+type weightedSubConn struct {
+	sc      balancer.SubConn
+	weight  int
+	current int
+}
+
+//平滑加权轮询：每次选择当前值最大的连接，选中后减去总权重，
+//权重为3:1时选择顺序为a a b a，不会连续集中到同一个实例
+type weightedPicker struct {
+	mutex    sync.Mutex
+	subConns []*weightedSubConn
+	total    int
+}
+
+func newWeightedPicker(ready map[balancer.SubConn]resolver.Address) balancer.Picker {
+	p := &weightedPicker{}
+	for _, sc := range sortedSubConns(ready) {
+		w := weight(ready[sc])
+		p.subConns = append(p.subConns, &weightedSubConn{sc: sc, weight: w})
+		p.total += w
+	}
+	return p
+}
+
+func (p *weightedPicker) Pick(ctx context.Context, opts balancer.PickOptions) (balancer.SubConn, func(balancer.DoneInfo), error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var best *weightedSubConn
+	for _, wsc := range p.subConns {
+		wsc.current += wsc.weight
+		if best == nil || wsc.current > best.current {
+			best = wsc
+		}
+	}
+	best.current -= p.total
+	return best.sc, nil, nil
+}
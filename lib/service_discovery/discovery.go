@@ -0,0 +1,131 @@
+package service_discovery
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/lib/etcd_lib"
+	"golang.org/x/net/context"
+)
+
+var ErrNotRegistered = errors.New("service discovery: instance not registered")
+
+const (
+	BackendEtcd   = "etcd"
+	BackendStatic = "static"
+	BackendMemory = "memory"
+)
+
+//一个服务实例，etcd中以json保存，静态文件中以yaml保存
+type Instance struct {
+	Addr      string `json:"addr" yaml:"addr"`
+	Version   string `json:"version" yaml:"version"`
+	Zone      string `json:"zone" yaml:"zone"`
+	Weight    int    `json:"weight" yaml:"weight"`       //加权负载均衡使用，不大于0时按1计算
+	StartTime int64  `json:"startTime" yaml:"startTime"` //unix秒
+	Load      Load   `json:"load" yaml:"load"`
+	//对客户端公开的地址，key为协议(tcp、ws、http)，只有接入节点需要
+	Endpoints map[string]string `json:"endpoints,omitempty" yaml:"endpoints"`
+}
+
+//服务定期上报的负载
+type Load struct {
+	Conns    int64   `json:"conns" yaml:"conns"`
+	CPU      float64 `json:"cpu" yaml:"cpu"`           //整机的cpu使用率，0~1
+	Draining bool    `json:"draining" yaml:"draining"` //排空中的实例不再分配新的客户端
+}
+
+//实现balancer.Weighter
+func (ins *Instance) GetWeight() int {
+	if ins.Weight <= 0 {
+		return 1
+	}
+	return ins.Weight
+}
+
+//服务注册，同一个服务的实例以地址区分
+type Registry interface {
+	//StartTime为0时使用当前时间，重复注册时覆盖原来的值
+	Register(name string, ins Instance) error
+	//更新已注册实例的负载
+	SetLoad(name, addr string, load Load) error
+	Deregister(name, addr string) error
+	Close() error
+}
+
+//服务发现
+type Discovery interface {
+	//先推送当前的全部实例，之后每次变化推送全量列表，按地址排序。
+	//消费慢时只保留最新的列表，ctx结束后通道关闭。列表中的实例是共享的，不能修改
+	Watch(ctx context.Context, name string) (<-chan []*Instance, error)
+	Close() error
+}
+
+//同一个后端同时提供注册和发现
+type Backend interface {
+	Registry
+	Discovery
+}
+
+type Cfg struct {
+	Backend string        `yaml:"backend"` //为空时使用etcd
+	Target  string        `yaml:"target"`  //etcd为地址，逗号分隔；static为文件路径；memory不使用
+	TTL     time.Duration `yaml:"ttl"`     //etcd租约的ttl
+	Reload  time.Duration `yaml:"reload"`  //static检查文件变化的间隔
+	//etcd与进程中其他使用方共用的租约，设置时不使用Target和TTL，Close时不关闭
+	Lease *etcd_lib.LeaseKeeper `yaml:"-"`
+}
+
+type Factory func(cfg Cfg) (Backend, error)
+
+var (
+	factoryMutex sync.RWMutex
+	factories    = map[string]Factory{
+		BackendStatic: func(cfg Cfg) (Backend, error) { return NewStatic(cfg.Target, cfg.Reload) },
+		BackendMemory: func(cfg Cfg) (Backend, error) { return SharedMemory(), nil },
+	}
+)
+
+//etcd在自己的包中注册，使用时需要导入该包
+func RegisterBackend(name string, factory Factory) {
+	factoryMutex.Lock()
+	defer factoryMutex.Unlock()
+	factories[name] = factory
+}
+
+func New(cfg Cfg) (Backend, error) {
+	if cfg.Backend == "" {
+		cfg.Backend = BackendEtcd
+	}
+	factoryMutex.RLock()
+	factory := factories[cfg.Backend]
+	factoryMutex.RUnlock()
+	if factory == nil {
+		return nil, fmt.Errorf("service discovery: unknown backend %q", cfg.Backend)
+	}
+	return factory(cfg)
+}
+
+//用于实现Watch，替换掉还没有被取走的旧列表。每个通道只能有一个发送方
+func Push(ch chan []*Instance, instances []*Instance) {
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- instances
+}
+
+//按地址排序，同一地址只保留一个
+func SortInstances(instances []*Instance) []*Instance {
+	sort.SliceStable(instances, func(i, j int) bool { return instances[i].Addr < instances[j].Addr })
+	result := instances[:0]
+	for _, ins := range instances {
+		if len(result) == 0 || result[len(result)-1].Addr != ins.Addr {
+			result = append(result, ins)
+		}
+	}
+	return result
+}
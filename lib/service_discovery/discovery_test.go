@@ -0,0 +1,180 @@
+package service_discovery
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery/balancer"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/resolver"
+)
+
+func next(t *testing.T, ch <-chan []*Instance) []*Instance {
+	t.Helper()
+	select {
+	case instances, ok := <-ch:
+		if !ok {
+			t.Fatal("watch closed")
+		}
+		return instances
+	case <-time.After(2 * time.Second):
+		t.Fatal("no update")
+	}
+	return nil
+}
+
+func addrs(instances []*Instance) []string {
+	result := make([]string, 0, len(instances))
+	for _, ins := range instances {
+		result = append(result, ins.Addr)
+	}
+	return result
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	if err := m.Register("logic", Instance{Addr: "b:1"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := m.Watch(ctx, "logic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := next(t, ch); len(got) != 1 || got[0].Addr != "b:1" || got[0].StartTime == 0 {
+		t.Fatalf("unexpected instances %+v", got)
+	}
+	m.Register("logic", Instance{Addr: "a:1", Weight: 2})
+	m.Register("other", Instance{Addr: "c:1"})
+	if got := addrs(next(t, ch)); len(got) != 2 || got[0] != "a:1" || got[1] != "b:1" {
+		t.Fatalf("unexpected addrs %v", got)
+	}
+	if err = m.SetLoad("logic", "a:1", Load{Conns: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if got := next(t, ch); got[0].Load.Conns != 10 || got[0].GetWeight() != 2 {
+		t.Fatalf("unexpected instance %+v", got[0])
+	}
+	if err = m.SetLoad("logic", "c:1", Load{Conns: 10}); err != ErrNotRegistered {
+		t.Fatalf("want ErrNotRegistered, got %v", err)
+	}
+	m.Deregister("logic", "b:1")
+	if got := addrs(next(t, ch)); len(got) != 1 || got[0] != "a:1" {
+		t.Fatalf("unexpected addrs %v", got)
+	}
+	cancel()
+	for range ch {
+	}
+}
+
+func TestStatic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.json")
+	write := func(content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"logic": [{"addr": "b:1"}, {"addr": "a:1", "weight": 3}]}`)
+	s, err := New(Cfg{Backend: BackendStatic, Target: path, Reload: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := s.Watch(ctx, "logic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := next(t, ch)
+	if len(got) != 2 || got[0].Addr != "a:1" || got[0].GetWeight() != 3 {
+		t.Fatalf("unexpected instances %+v", got)
+	}
+
+	//其他服务变化或文件格式错误时不推送
+	write(`{"logic": [{"addr": "b:1"}, {"addr": "a:1", "weight": 3}], "access": [{"addr": "c:1"}]}`)
+	time.Sleep(50 * time.Millisecond)
+	write(`{"logic": [`)
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case got = <-ch:
+		t.Fatalf("unexpected update %+v", got)
+	default:
+	}
+	write(`{"logic": [{"addr": "b:1"}]}`)
+	if got := addrs(next(t, ch)); len(got) != 1 || got[0] != "b:1" {
+		t.Fatalf("unexpected addrs %v", got)
+	}
+
+	if _, err = New(Cfg{Backend: BackendStatic, Target: filepath.Join(os.TempDir(), "not-exist.json")}); err == nil {
+		t.Fatal("missing file should fail")
+	}
+	if _, err = New(Cfg{Backend: "zookeeper"}); err == nil {
+		t.Fatal("unknown backend should fail")
+	}
+}
+
+type healthServer struct{}
+
+func (healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+//通过grpc拨号时实例的上下线对调用方透明
+func TestResolver(t *testing.T) {
+	serve := func() (string, *grpc.Server) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := grpc.NewServer()
+		grpc_health_v1.RegisterHealthServer(server, healthServer{})
+		go server.Serve(lis)
+		return lis.Addr().String(), server
+	}
+	a, serverA := serve()
+	defer serverA.Stop()
+	b, serverB := serve()
+	defer serverB.Stop()
+
+	m := NewMemory()
+	m.Register("health", Instance{Addr: a})
+	resolver.Register(NewBuilder("discovery-test", m))
+	defer resolver.UnregisterForTesting("discovery-test")
+	conn, err := grpc.Dial("discovery-test:///health", grpc.WithInsecure(),
+		grpc.WithBalancerBuilder(balancer.Get(balancer.RoundRobin)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := grpc_health_v1.NewHealthClient(conn)
+	call := func() string {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		p := &peer.Peer{}
+		if _, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.Peer(p), grpc.FailFast(false)); err != nil {
+			t.Fatal(err)
+		}
+		return p.Addr.String()
+	}
+	if addr := call(); addr != a {
+		t.Fatalf("want %s, got %s", a, addr)
+	}
+	m.Register("health", Instance{Addr: b})
+	m.Deregister("health", a)
+	deadline := time.Now().Add(5 * time.Second)
+	for call() != b {
+		if time.Now().After(deadline) {
+			t.Fatal("new instance not picked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,21 @@
+package etcd
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+)
+
+//值为json，兼容只写入地址的旧格式
+func parseInstance(value []byte) (*service_discovery.Instance, bool) {
+	ins := &service_discovery.Instance{}
+	if len(value) > 0 && value[0] == '{' {
+		if err := json.Unmarshal(value, ins); err != nil {
+			return nil, false
+		}
+	} else {
+		ins.Addr = strings.TrimSpace(string(value))
+	}
+	return ins, ins.Addr != ""
+}
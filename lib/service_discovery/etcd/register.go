@@ -1,67 +1,162 @@
 package etcd
 
 import (
-	etcdv3 "github.com/coreos/etcd/clientv3"
-	"time"
-	"fmt"
+	"encoding/json"
 	"strings"
-	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
-	"github.com/golang/glog"
+	"sync"
+	"time"
+
+	etcdv3 "github.com/coreos/etcd/clientv3"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/lib/etcd_lib"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+	"go.uber.org/zap"
 	"golang.org/x/net/context"
 )
 
 var Prefix = "ZW"
-var client *etcdv3.Client
-var serviceKey string
-var stopSignal = make(chan bool, 1)
 var DialTimeout = time.Second
 
-func Register(name, addr, target string, interval time.Duration, ttl time.Duration) (err error) {
-	serviceKey = fmt.Sprintf("/%s/%s/%s", Prefix, name, addr)
+const (
+	defaultTTL = 10 * time.Second
+	opTimeout  = 3 * time.Second
+)
+
+//一个进程注册的所有服务共用一个租约，进程崩溃后在ttl内过期。
+//租约失效(如与etcd长时间断开)后重新申请，并把已注册的服务写回。
+//同时实现服务发现，与注册共用一个etcd连接
+type Registry struct {
+	client    *etcdv3.Client
+	keeper    *etcd_lib.LeaseKeeper
+	shared    bool //client和租约由外部创建，Close时不关闭
+	ctx       context.Context
+	cancel    context.CancelFunc
+	mutex     sync.Mutex
+	instances map[string]*service_discovery.Instance //key为服务实例在etcd中的key
+	watchers  sync.WaitGroup
+}
 
-	client, err = etcdv3.New(etcdv3.Config{
-		Endpoints:strings.Split(target, ","),
+//target为etcd地址，逗号分隔
+func NewRegistry(target string, ttl time.Duration) (*Registry, error) {
+	client, err := etcdv3.New(etcdv3.Config{
+		Endpoints:   strings.Split(target, ","),
 		DialTimeout: DialTimeout,
 	})
 	if err != nil {
-		glog.Error(err)
-		return
+		return nil, err
+	}
+	if ttl < time.Second {
+		ttl = defaultTTL
+	}
+	return newRegistry(etcd_lib.NewLeaseKeeper(client, ttl, "register"), false), nil
+}
+
+//与进程中其他使用方共用租约和它的client，注册的服务在租约的所有者Close时删除
+func NewSharedRegistry(keeper *etcd_lib.LeaseKeeper) *Registry {
+	return newRegistry(keeper, true)
+}
+
+func newRegistry(keeper *etcd_lib.LeaseKeeper, shared bool) *Registry {
+	r := &Registry{
+		client:    keeper.Client(),
+		keeper:    keeper,
+		shared:    shared,
+		instances: make(map[string]*service_discovery.Instance),
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	return r
+}
+
+func serviceKey(name, addr string) string {
+	return servicePrefix(name) + addr
+}
+
+//StartTime为0时使用当前时间。重复注册时覆盖原来的值，
+//写入失败时返回错误并在后台重试
+func (r *Registry) Register(name string, ins service_discovery.Instance) error {
+	if ins.StartTime == 0 {
+		ins.StartTime = time.Now().Unix()
 	}
+	key := serviceKey(name, ins.Addr)
+	r.mutex.Lock()
+	r.instances[key] = &ins
+	r.mutex.Unlock()
+	if err := r.put(key, &ins); err != nil {
+		return err
+	}
+	logger.Info("etcd register", zap.String("key", key))
+	return nil
+}
+
+//更新已注册实例的负载
+func (r *Registry) SetLoad(name, addr string, load service_discovery.Load) error {
+	key := serviceKey(name, addr)
+	r.mutex.Lock()
+	ins := r.instances[key]
+	if ins == nil {
+		r.mutex.Unlock()
+		return service_discovery.ErrNotRegistered
+	}
+	if ins.Load == load {
+		r.mutex.Unlock()
+		return nil
+	}
+	updated := *ins
+	updated.Load = load
+	r.instances[key] = &updated
+	r.mutex.Unlock()
+	return r.put(key, &updated)
+}
+
+func (r *Registry) Deregister(name, addr string) error {
+	key := serviceKey(name, addr)
+	r.mutex.Lock()
+	delete(r.instances, key)
+	r.mutex.Unlock()
+	ctx, cancel := context.WithTimeout(r.ctx, opTimeout)
+	defer cancel()
+	return r.keeper.Delete(ctx, key)
+}
+
+//ctx结束或Registry关闭后通道关闭
+func (r *Registry) Watch(ctx context.Context, name string) (<-chan []*service_discovery.Instance, error) {
+	if r.ctx.Err() != nil {
+		return nil, r.ctx.Err()
+	}
+	ch := make(chan []*service_discovery.Instance, 1)
+	w := newWatcher(r.ctx, r.client, name, func(instances []*service_discovery.Instance) {
+		service_discovery.Push(ch, instances)
+	})
+	r.watchers.Add(1)
 	go func() {
-		ticker := time.NewTicker(interval)
-		for {
-			resp, _ := client.Grant(context.TODO(), int64(ttl/time.Second))
-			if _, err := client.Get(context.Background(), serviceKey); err != nil {
-				if err == rpctypes.ErrKeyNotFound {
-					if _, err := client.Put(context.TODO(), serviceKey, addr, etcdv3.WithLease(resp.ID)); err != nil {
-						glog.Error(err)
-					}
-				} else {
-					glog.Error(err)
-				}
-			} else {
-				if _, err := client.Put(context.Background(), serviceKey, addr, etcdv3.WithLease(resp.ID)); err != nil {
-					glog.Error(err)
-				}
-			}
-		}
+		defer r.watchers.Done()
 		select {
-		case <- stopSignal:
-			return
-		case <-ticker.C:
+		case <-ctx.Done():
+		case <-r.ctx.Done():
 		}
+		w.close()
+		close(ch)
 	}()
+	return ch, nil
+}
 
-	return nil
+//撤销租约，已注册的服务立即删除
+func (r *Registry) Close() error {
+	r.cancel()
+	r.watchers.Wait()
+	if r.shared {
+		return nil
+	}
+	r.keeper.Close()
+	return r.client.Close()
 }
 
-func UnRegister() (err error) {
-	stopSignal <- true
-	stopSignal = make(chan bool, 1)
-	if _, err = client.Delete(context.Background(), serviceKey); err != nil {
-		glog.Error(err)
-	} else {
-		glog.Infof("grpclb: deregister '%s' ok.")
+func (r *Registry) put(key string, ins *service_discovery.Instance) error {
+	value, err := json.Marshal(ins)
+	if err != nil {
+		return err
 	}
-	return
-}
\ No newline at end of file
+	ctx, cancel := context.WithTimeout(r.ctx, opTimeout)
+	defer cancel()
+	return r.keeper.Put(ctx, key, string(value))
+}
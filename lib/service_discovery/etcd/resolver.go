@@ -1,36 +1,134 @@
 package etcd
 
 import (
-	"google.golang.org/grpc/naming"
 	"fmt"
-	"github.com/golang/glog"
-	etcdv3 "github.com/coreos/etcd/clientv3"
 	"strings"
+
+	etcdv3 "github.com/coreos/etcd/clientv3"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/lib/etcd_lib"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/resolver"
 )
 
-type resolver struct {
-	serviceName string
+const Scheme = "etcd"
+
+func init() {
+	resolver.Register(NewBuilder())
+	service_discovery.RegisterBackend(service_discovery.BackendEtcd, func(cfg service_discovery.Cfg) (service_discovery.Backend, error) {
+		if cfg.Lease != nil {
+			return NewSharedRegistry(cfg.Lease), nil
+		}
+		return NewRegistry(cfg.Target, cfg.TTL)
+	})
+}
+
+//grpc.Dial的地址，格式为etcd://{etcd地址，逗号分隔}/{服务名}
+func Target(endpoints, serviceName string) string {
+	return fmt.Sprintf("%s://%s/%s", Scheme, endpoints, serviceName)
 }
 
-func NewResolver(serviceName string) *resolver {
-	return &resolver{serviceName: serviceName}
+//服务实例的key为/{Prefix}/{serviceName}/{addr}，以/结尾避免前缀匹配到其他服务
+func servicePrefix(serviceName string) string {
+	return fmt.Sprintf("/%s/%s/", Prefix, serviceName)
 }
 
-func (re *resolver) Resolve(target string) (naming.Watcher, error) {
-	var err error
-	if re.serviceName == "" {
-		err = fmt.Errorf("no service name provided")
-		glog.Error(err)
-		return nil, err
-	}
+type builder struct{}
 
+func NewBuilder() resolver.Builder {
+	return &builder{}
+}
+
+func (b *builder) Scheme() string {
+	return Scheme
+}
+
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOption) (resolver.Resolver, error) {
+	if target.Endpoint == "" {
+		return nil, fmt.Errorf("etcd resolver: no service name provided")
+	}
 	client, err := etcdv3.New(etcdv3.Config{
-		Endpoints: strings.Split(target, ","),
+		Endpoints:   strings.Split(target.Authority, ","),
 		DialTimeout: DialTimeout,
 	})
 	if err != nil {
-		glog.Error(err)
 		return nil, err
 	}
-	return &watcher{re: re, client: client}, nil
-}
\ No newline at end of file
+	r := &etcdResolver{client: client}
+	r.watcher = newWatcher(context.Background(), client, target.Endpoint, func(instances []*service_discovery.Instance) {
+		cc.NewAddress(service_discovery.Addresses(instances))
+	})
+	return r, nil
+}
+
+//每个resolver独占一个client
+type etcdResolver struct {
+	client  *etcdv3.Client
+	watcher *watcher
+}
+
+//变化由watch推送，不需要主动解析
+func (r *etcdResolver) ResolveNow(resolver.ResolveNowOption) {}
+
+//等待watch协程退出后关闭client，Close之后不再调用cc
+func (r *etcdResolver) Close() {
+	r.watcher.close()
+	r.client.Close()
+}
+
+//维护服务的实例列表，每次全量读取或监听到变化后通知全部实例
+type watcher struct {
+	prefix    string
+	onUpdate  func(instances []*service_discovery.Instance)
+	watcher   *etcd_lib.Watcher
+	instances map[string]*service_discovery.Instance //只在watch协程中访问
+}
+
+//parent结束时watch也结束
+func newWatcher(parent context.Context, client *etcdv3.Client, serviceName string,
+	onUpdate func(instances []*service_discovery.Instance)) *watcher {
+	w := &watcher{
+		prefix:    servicePrefix(serviceName),
+		onUpdate:  onUpdate,
+		instances: make(map[string]*service_discovery.Instance),
+	}
+	w.watcher = etcd_lib.Watch(parent, client, w.prefix, w.onEvent)
+	return w
+}
+
+//返回后不再调用onUpdate
+func (w *watcher) close() {
+	w.watcher.Close()
+}
+
+func (w *watcher) onEvent(ev etcd_lib.Event) {
+	switch ev.Type {
+	case etcd_lib.EventReset:
+		w.instances = make(map[string]*service_discovery.Instance)
+	case etcd_lib.EventPut:
+		w.put(ev.Key, ev.Value)
+	case etcd_lib.EventDelete:
+		delete(w.instances, ev.Key)
+	case etcd_lib.EventSynced:
+		w.update()
+	}
+}
+
+func (w *watcher) put(key string, value []byte) {
+	ins, ok := parseInstance(value)
+	if !ok {
+		logger.Error("etcd watch: invalid instance", zap.String("key", key), zap.String("value", string(value)))
+		return
+	}
+	w.instances[key] = ins
+}
+
+func (w *watcher) update() {
+	instances := make([]*service_discovery.Instance, 0, len(w.instances))
+	for _, ins := range w.instances {
+		instances = append(instances, ins)
+	}
+	w.onUpdate(service_discovery.SortInstances(instances))
+}
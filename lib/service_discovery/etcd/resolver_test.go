@@ -0,0 +1,89 @@
+package etcd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/lib/etcd_lib"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+	"google.golang.org/grpc/resolver"
+)
+
+type fakeClientConn struct {
+	addrs []resolver.Address
+}
+
+func (cc *fakeClientConn) NewAddress(addrs []resolver.Address) {
+	cc.addrs = addrs
+}
+
+func (cc *fakeClientConn) NewServiceConfig(string) {}
+
+func TestParseInstance(t *testing.T) {
+	value, _ := json.Marshal(&service_discovery.Instance{Addr: "10.0.0.1:11200", Version: "1.0.0", Zone: "sh", Weight: 3,
+		Load: service_discovery.Load{Conns: 42, CPU: 0.5}, Endpoints: map[string]string{"tcp": "chat.example.com:11000"}})
+	ins, ok := parseInstance(value)
+	if !ok || ins.Addr != "10.0.0.1:11200" || ins.Zone != "sh" || ins.GetWeight() != 3 || ins.Load.Conns != 42 ||
+		ins.Endpoints["tcp"] != "chat.example.com:11000" {
+		t.Fatalf("unexpected instance %+v", ins)
+	}
+	//旧格式只有地址
+	if ins, ok = parseInstance([]byte("10.0.0.2:11200")); !ok || ins.Addr != "10.0.0.2:11200" || ins.GetWeight() != 1 {
+		t.Fatalf("unexpected instance %+v", ins)
+	}
+	for _, value := range []string{"", "{bad", `{"weight":1}`} {
+		if _, ok = parseInstance([]byte(value)); ok {
+			t.Fatalf("%q should be invalid", value)
+		}
+	}
+}
+
+func TestWatcherUpdate(t *testing.T) {
+	cc := &fakeClientConn{}
+	w := &watcher{prefix: servicePrefix("logic"), instances: make(map[string]*service_discovery.Instance),
+		onUpdate: func(instances []*service_discovery.Instance) {
+			cc.NewAddress(service_discovery.Addresses(instances))
+		}}
+	put := func(addr, value string) {
+		w.onEvent(etcd_lib.Event{Type: etcd_lib.EventPut, Key: serviceKey("logic", addr), Value: []byte(value)})
+	}
+	put("stale", "stale:1")
+	w.onEvent(etcd_lib.Event{Type: etcd_lib.EventReset})
+	put("b:1", `{"addr":"b:1","weight":2}`)
+	put("a:1", "a:1")
+	put("bad", "")
+	w.onEvent(etcd_lib.Event{Type: etcd_lib.EventSynced})
+	if len(cc.addrs) != 2 || cc.addrs[0].Addr != "a:1" || cc.addrs[1].Addr != "b:1" {
+		t.Fatalf("unexpected addrs %v", cc.addrs)
+	}
+	if weight := cc.addrs[1].Metadata.(*service_discovery.Instance).GetWeight(); weight != 2 {
+		t.Fatalf("weight should be 2, got %d", weight)
+	}
+	w.onEvent(etcd_lib.Event{Type: etcd_lib.EventDelete, Key: serviceKey("logic", "a:1")})
+	w.onEvent(etcd_lib.Event{Type: etcd_lib.EventSynced})
+	if len(cc.addrs) != 1 || cc.addrs[0].Addr != "b:1" {
+		t.Fatalf("unexpected addrs after delete %v", cc.addrs)
+	}
+	//服务名是前缀的其他服务不会被匹配
+	if strings.HasPrefix(serviceKey("logic2", "a:1"), w.prefix) {
+		t.Fatal("prefix should not match another service")
+	}
+}
+
+//设置共用的租约时使用它和它的client，Close时不关闭
+func TestSharedRegistry(t *testing.T) {
+	keeper := etcd_lib.NewLeaseKeeper(nil, time.Minute, "test")
+	defer keeper.Close()
+	backend, err := service_discovery.New(service_discovery.Cfg{Backend: service_discovery.BackendEtcd, Lease: keeper})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := backend.(*Registry); !ok || r.keeper != keeper || !r.shared {
+		t.Fatalf("want registry on the shared lease, got %#v", backend)
+	}
+	if err = backend.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,118 @@
+package service_discovery
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+var (
+	sharedOnce   sync.Once
+	sharedMemory *Memory
+)
+
+//配置为memory时同一进程内的服务共用这个实例
+func SharedMemory() *Memory {
+	sharedOnce.Do(func() {
+		sharedMemory = NewMemory()
+	})
+	return sharedMemory
+}
+
+//进程内的注册中心，用于测试和单进程部署。
+//Close不清除已注册的实例，便于多个服务共用
+type Memory struct {
+	mutex    sync.Mutex
+	services map[string]map[string]*Instance
+	watchers map[string]map[chan []*Instance]struct{}
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		services: make(map[string]map[string]*Instance),
+		watchers: make(map[string]map[chan []*Instance]struct{}),
+	}
+}
+
+func (m *Memory) Register(name string, ins Instance) error {
+	if ins.StartTime == 0 {
+		ins.StartTime = time.Now().Unix()
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.services[name] == nil {
+		m.services[name] = make(map[string]*Instance)
+	}
+	m.services[name][ins.Addr] = &ins
+	m.notify(name)
+	return nil
+}
+
+func (m *Memory) SetLoad(name, addr string, load Load) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	ins := m.services[name][addr]
+	if ins == nil {
+		return ErrNotRegistered
+	}
+	if ins.Load != load {
+		updated := *ins
+		updated.Load = load
+		m.services[name][addr] = &updated
+		m.notify(name)
+	}
+	return nil
+}
+
+func (m *Memory) Deregister(name, addr string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.services[name][addr]; ok {
+		delete(m.services[name], addr)
+		m.notify(name)
+	}
+	return nil
+}
+
+func (m *Memory) Watch(ctx context.Context, name string) (<-chan []*Instance, error) {
+	ch := make(chan []*Instance, 1)
+	m.mutex.Lock()
+	if m.watchers[name] == nil {
+		m.watchers[name] = make(map[chan []*Instance]struct{})
+	}
+	m.watchers[name][ch] = struct{}{}
+	Push(ch, m.instances(name))
+	m.mutex.Unlock()
+	go func() {
+		<-ctx.Done()
+		m.mutex.Lock()
+		delete(m.watchers[name], ch)
+		close(ch)
+		m.mutex.Unlock()
+	}()
+	return ch, nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+//调用方持有mutex，实例不会被修改，可以共享给所有watcher
+func (m *Memory) notify(name string) {
+	if len(m.watchers[name]) == 0 {
+		return
+	}
+	instances := m.instances(name)
+	for ch := range m.watchers[name] {
+		Push(ch, instances)
+	}
+}
+
+func (m *Memory) instances(name string) []*Instance {
+	instances := make([]*Instance, 0, len(m.services[name]))
+	for _, ins := range m.services[name] {
+		instances = append(instances, ins)
+	}
+	return SortInstances(instances)
+}
@@ -0,0 +1,69 @@
+package service_discovery
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/resolver"
+)
+
+//把Discovery接入grpc，注册后通过{scheme}:///{服务名}拨号，地址的Metadata为*Instance：
+//
+//	resolver.Register(service_discovery.NewBuilder("discovery", backend))
+//	grpc.Dial("discovery:///logic_server", grpc.WithBalancerBuilder(balancer.Get(balancer.Weighted)))
+func NewBuilder(scheme string, discovery Discovery) resolver.Builder {
+	return &builder{scheme: scheme, discovery: discovery}
+}
+
+type builder struct {
+	scheme    string
+	discovery Discovery
+}
+
+func (b *builder) Scheme() string {
+	return b.scheme
+}
+
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOption) (resolver.Resolver, error) {
+	if target.Endpoint == "" {
+		return nil, fmt.Errorf("service discovery: no service name provided")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := b.discovery.Watch(ctx, target.Endpoint)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	r := &discoveryResolver{cancel: cancel, done: make(chan struct{})}
+	go r.watch(ch, cc)
+	return r, nil
+}
+
+type discoveryResolver struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (r *discoveryResolver) watch(ch <-chan []*Instance, cc resolver.ClientConn) {
+	defer close(r.done)
+	for instances := range ch {
+		cc.NewAddress(Addresses(instances))
+	}
+}
+
+func (r *discoveryResolver) ResolveNow(resolver.ResolveNowOption) {}
+
+//等待watch协程退出，Close之后不再调用cc
+func (r *discoveryResolver) Close() {
+	r.cancel()
+	<-r.done
+}
+
+//转换为grpc的地址，Metadata为*Instance
+func Addresses(instances []*Instance) []resolver.Address {
+	addrs := make([]resolver.Address, 0, len(instances))
+	for _, ins := range instances {
+		addrs = append(addrs, resolver.Address{Addr: ins.Addr, Metadata: ins})
+	}
+	return addrs
+}
@@ -0,0 +1,163 @@
+package service_discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultReload = 5 * time.Second
+
+//实例写在yaml文件中，格式为服务名到实例列表：
+//
+//	logic_server:
+//	  - addr: "127.0.0.1:11300"
+//	    weight: 2
+//
+//扩展名为.json时按json解析。定期检查文件，内容变化时推送给watcher。
+//实例只由文件决定，注册相关的方法不做任何事
+type Static struct {
+	path     string
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mutex    sync.Mutex
+	content  []byte
+	services map[string][]*Instance
+	watchers map[string]map[chan []*Instance]struct{}
+}
+
+//文件必须存在且格式正确，之后的重新加载失败时保留原来的实例
+func NewStatic(path string, reload time.Duration) (*Static, error) {
+	if reload <= 0 {
+		reload = defaultReload
+	}
+	s := &Static{path: path, watchers: make(map[string]map[chan []*Instance]struct{})}
+	if _, err := s.load(); err != nil {
+		return nil, err
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.reload(reload)
+	return s, nil
+}
+
+func (s *Static) Register(name string, ins Instance) error {
+	return nil
+}
+
+func (s *Static) SetLoad(name, addr string, load Load) error {
+	return nil
+}
+
+func (s *Static) Deregister(name, addr string) error {
+	return nil
+}
+
+func (s *Static) Watch(ctx context.Context, name string) (<-chan []*Instance, error) {
+	ch := make(chan []*Instance, 1)
+	s.mutex.Lock()
+	if s.watchers[name] == nil {
+		s.watchers[name] = make(map[chan []*Instance]struct{})
+	}
+	s.watchers[name][ch] = struct{}{}
+	Push(ch, s.services[name])
+	s.mutex.Unlock()
+	go func() {
+		<-ctx.Done()
+		s.mutex.Lock()
+		delete(s.watchers[name], ch)
+		close(ch)
+		s.mutex.Unlock()
+	}()
+	return ch, nil
+}
+
+//watcher的通道在各自的ctx结束后关闭
+func (s *Static) Close() error {
+	s.cancel()
+	return nil
+}
+
+func (s *Static) reload(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed, err := s.load()
+		if err != nil {
+			logger.Error("static discovery reload", zap.String("path", s.path), zap.Error(err))
+		} else if changed {
+			logger.Info("static discovery reloaded", zap.String("path", s.path))
+		}
+	}
+}
+
+//内容没有变化时不推送
+func (s *Static) load() (bool, error) {
+	content, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return false, err
+	}
+	s.mutex.Lock()
+	same := s.services != nil && bytes.Equal(content, s.content)
+	s.mutex.Unlock()
+	if same {
+		return false, nil
+	}
+	file := make(map[string][]*Instance)
+	if filepath.Ext(s.path) == ".json" {
+		err = json.Unmarshal(content, &file)
+	} else {
+		err = yaml.Unmarshal(content, &file)
+	}
+	if err != nil {
+		return false, err
+	}
+	services := make(map[string][]*Instance, len(file))
+	for name, instances := range file {
+		valid := make([]*Instance, 0, len(instances))
+		for _, ins := range instances {
+			if ins != nil && ins.Addr != "" {
+				valid = append(valid, ins)
+			}
+		}
+		services[name] = SortInstances(valid)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.content = content
+	old := s.services
+	s.services = services
+	for name, watchers := range s.watchers {
+		if !sameInstances(old[name], services[name]) {
+			for ch := range watchers {
+				Push(ch, services[name])
+			}
+		}
+	}
+	return true, nil
+}
+
+func sameInstances(a, b []*Instance) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
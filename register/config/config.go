@@ -0,0 +1,54 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	commconf "github.com/imkuqin-zw/ZWChat/common/config"
+	"github.com/imkuqin-zw/ZWChat/common/store"
+	"github.com/imkuqin-zw/ZWChat/register/server"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	confPath string
+	Conf     *Config
+)
+
+type Config struct {
+	RpcServer        *commconf.Server                 `yaml:"rpcServer"`
+	Path             *commconf.Path                   `yaml:"path"`
+	ServiceDiscovery *commconf.ServiceDiscoveryServer `yaml:"serviceDiscovery"`
+	Etcd             *commconf.Etcd                   `yaml:"etcd"`
+	Log              *zap.Config                      `yaml:"log"`
+	Register         *server.Cfg                      `yaml:"register"`
+	Store            *store.Cfg                       `yaml:"store"`
+}
+
+func init() {
+	flag.StringVar(&confPath, "conf", "./register.yaml", "config path")
+}
+
+func Init() (err error) {
+	var configBody []byte
+	configBody, err = ioutil.ReadFile(confPath)
+	if err != nil {
+		return
+	}
+	Conf = &Config{}
+	if err = yaml.Unmarshal(configBody, Conf); err != nil {
+		return
+	}
+	if Conf.Register == nil {
+		Conf.Register = &server.Cfg{}
+	}
+	Conf.Path = &commconf.Path{}
+	Conf.Path.Root, err = filepath.Abs(filepath.Dir(os.Args[0]))
+	if err != nil {
+		return
+	}
+	return
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/common/store"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+	//注册etcd后端
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery/etcd"
+	"github.com/imkuqin-zw/ZWChat/register/config"
+	"github.com/imkuqin-zw/ZWChat/register/server"
+	"go.uber.org/zap"
+)
+
+func main() {
+	flag.Parse()
+	if config.Conf.Store == nil || config.Conf.RpcServer == nil {
+		logger.Fatal("store and rpcServer required")
+		return
+	}
+	storage, err := store.New(*config.Conf.Store)
+	if err != nil {
+		logger.Fatal("store", zap.String("store", config.Conf.Store.Store), zap.Error(err))
+		return
+	}
+	defer storage.Close()
+	rpcServer := server.NewRPCServer(server.NewService(*config.Conf.Register, storage))
+	if err = rpcServer.Serve(rpcNetwork(config.Conf.RpcServer.Proto), config.Conf.RpcServer.Addr); err != nil {
+		return
+	}
+	defer rpcServer.Stop()
+	logger.Info("rpc init success", zap.String("addr", config.Conf.RpcServer.Addr))
+	//Logic通过服务发现找到注册服务
+	if sd := config.Conf.ServiceDiscovery; sd != nil {
+		discovery, err := service_discovery.New(service_discovery.Cfg{
+			Backend: sd.Backend, Target: sd.Target, TTL: sd.TTL, Reload: sd.Reload,
+		})
+		if err != nil {
+			logger.Fatal("service discovery", zap.String("backend", sd.Backend), zap.Error(err))
+			return
+		}
+		defer discovery.Close()
+		if err = discovery.Register(sd.ServerName, service_discovery.Instance{
+			Addr: sd.RpcAddr, Version: sd.Version, Zone: sd.Zone, Weight: sd.Weight,
+		}); err != nil {
+			logger.Error("service register", zap.Error(err))
+		}
+		defer discovery.Deregister(sd.ServerName, sd.RpcAddr)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+}
+
+func rpcNetwork(proto string) string {
+	if proto == "" {
+		return "tcp"
+	}
+	return proto
+}
+
+func init() {
+	if err := config.Init(); err != nil {
+		panic(err)
+	}
+	if config.Conf.Etcd != nil {
+		etcd.DialTimeout = config.Conf.Etcd.DialTimeout
+		etcd.Prefix = config.Conf.Etcd.Prefix
+	}
+	logger.InitLogger(config.Conf.Log)
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	kdfName           = "pbkdf2-sha256"
+	defaultIterations = 200000
+	saltLen           = 16
+	keyLen            = 32
+)
+
+//PBKDF2-HMAC-SHA256，结果编码为pbkdf2-sha256${迭代次数}${盐}${摘要}，
+//迭代次数保存在结果中，调高后旧密码仍然可以校验。
+//同时计算的数量限制为CPU数，避免大量登录请求耗尽CPU
+type kdf struct {
+	iterations int
+	slots      chan struct{}
+	dummy      string //账号不存在时用于校验，使耗时与密码错误一致
+}
+
+func newKdf(iterations int) *kdf {
+	if iterations <= 0 {
+		iterations = defaultIterations
+	}
+	k := &kdf{iterations: iterations, slots: make(chan struct{}, runtime.NumCPU())}
+	k.dummy, _ = k.hash("dummy password")
+	return k
+}
+
+func pbkdf2(password, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, password)
+	mac.Write(salt)
+	var block [4]byte
+	binary.BigEndian.PutUint32(block[:], 1)
+	mac.Write(block[:])
+	u := mac.Sum(nil)
+	key := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key[:keyLen]
+}
+
+func (k *kdf) derive(password string, salt []byte, iterations int) []byte {
+	k.slots <- struct{}{}
+	defer func() { <-k.slots }()
+	return pbkdf2([]byte(password), salt, iterations)
+}
+
+func (k *kdf) hash(password string) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := k.derive(password, salt, k.iterations)
+	return fmt.Sprintf("%s$%d$%s$%s", kdfName, k.iterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+//encoded格式不正确时返回false
+func (k *kdf) verify(encoded, password string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != kdfName {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(key, k.derive(password, salt, iterations))
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/store"
+)
+
+const (
+	defaultCountryCode = "86"
+	maxEmailLen        = 254
+	minPasswordLen     = 8
+	maxPasswordLen     = 128
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z][a-z0-9_.]{2,31}$`)
+
+//转为小写，以字母开头，避免与手机号混淆
+func normalizeUsername(username string) (string, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if !usernamePattern.MatchString(username) {
+		return "", ecode.InvalidUsername
+	}
+	return username, nil
+}
+
+//去掉空格、横线和括号，统一为+{国家码}{号码}，00开头视为+，没有+时加上countryCode
+func normalizePhone(phone, countryCode string) (string, error) {
+	b := make([]byte, 0, len(phone))
+	for _, c := range []byte(strings.TrimSpace(phone)) {
+		switch {
+		case c == ' ' || c == '-' || c == '(' || c == ')':
+		case c == '+' && len(b) == 0, c >= '0' && c <= '9':
+			b = append(b, c)
+		default:
+			return "", ecode.InvalidPhone
+		}
+	}
+	phone = string(b)
+	switch {
+	case strings.HasPrefix(phone, "+"):
+	case strings.HasPrefix(phone, "00"):
+		phone = "+" + phone[2:]
+	default:
+		phone = "+" + countryCode + phone
+	}
+	//E.164最多15位数字
+	if digits := len(phone) - 1; digits < 8 || digits > 15 || phone[1] == '0' {
+		return "", ecode.InvalidPhone
+	}
+	return phone, nil
+}
+
+//整体转为小写
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if len(email) > maxEmailLen || at <= 0 || strings.ContainsAny(email, " \t\r\n") {
+		return "", ecode.InvalidEmail
+	}
+	domain := email[at+1:]
+	if strings.Contains(email[:at], "@") || !strings.Contains(domain, ".") ||
+		strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return "", ecode.InvalidEmail
+	}
+	return email, nil
+}
+
+//登录时按格式判断标识的类型：含@为邮箱，+或数字开头为手机号，其他为用户名
+func identifierKey(account, countryCode string) (string, error) {
+	account = strings.TrimSpace(account)
+	switch {
+	case strings.Contains(account, "@"):
+		email, err := normalizeEmail(account)
+		return store.EmailKey(email), err
+	case account != "" && (account[0] == '+' || (account[0] >= '0' && account[0] <= '9')):
+		phone, err := normalizePhone(account, countryCode)
+		return store.PhoneKey(phone), err
+	}
+	username, err := normalizeUsername(account)
+	return store.UsernameKey(username), err
+}
+
+//长度8到128，至少包含字母、数字、其他字符中的两类
+func checkPassword(password string) error {
+	if len(password) < minPasswordLen || len(password) > maxPasswordLen {
+		return ecode.PasswordTooWeak
+	}
+	var letter, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	classes := 0
+	for _, ok := range []bool{letter, digit, other} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < 2 {
+		return ecode.PasswordTooWeak
+	}
+	return nil
+}
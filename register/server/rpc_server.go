@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net"
+
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/register"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+type RPCServer struct {
+	server *grpc.Server
+}
+
+func NewRPCServer(service *Service) *RPCServer {
+	server := grpc.NewServer()
+	register.RegisterRegisterServer(server, service)
+	return &RPCServer{server: server}
+}
+
+func (s *RPCServer) Serve(network, addr string) error {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		logger.Error("rpc listen", zap.String("addr", addr), zap.Error(err))
+		return err
+	}
+	go func() {
+		if err := s.server.Serve(l); err != nil {
+			logger.Debug("rpc serve", zap.String("addr", addr), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (s *RPCServer) Stop() {
+	s.server.GracefulStop()
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/logger"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/register"
+	"github.com/imkuqin-zw/ZWChat/common/store"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+type Cfg struct {
+	Iterations  int    `yaml:"iterations"`  //PBKDF2的迭代次数
+	CountryCode string `yaml:"countryCode"` //手机号没有+时使用的国家码
+}
+
+//实现register.RegisterServer，错误为ecode
+type Service struct {
+	users       store.Users
+	kdf         *kdf
+	countryCode string
+}
+
+func NewService(cfg Cfg, users store.Users) *Service {
+	if cfg.CountryCode == "" {
+		cfg.CountryCode = defaultCountryCode
+	}
+	return &Service{users: users, kdf: newKdf(cfg.Iterations), countryCode: cfg.CountryCode}
+}
+
+func (s *Service) Register(ctx context.Context, req *register.RegisterReq) (*register.RegisterReply, error) {
+	user := &store.User{Nickname: req.Nickname, CreateTime: time.Now().Unix()}
+	var err error
+	if req.Username != "" {
+		if user.Username, err = normalizeUsername(req.Username); err != nil {
+			return nil, err
+		}
+	}
+	if req.Phone != "" {
+		if user.Phone, err = normalizePhone(req.Phone, s.countryCode); err != nil {
+			return nil, err
+		}
+	}
+	if req.Email != "" {
+		if user.Email, err = normalizeEmail(req.Email); err != nil {
+			return nil, err
+		}
+	}
+	if len(user.Identifiers()) == 0 {
+		return nil, ecode.NoIdentifier
+	}
+	if err = checkPassword(req.Password); err != nil {
+		return nil, err
+	}
+	if user.PasswordHash, err = s.kdf.hash(req.Password); err != nil {
+		logger.Error("register hash", zap.Error(err))
+		return nil, ecode.ServerErr
+	}
+	if err = s.users.CreateUser(ctx, user); err != nil {
+		if err == store.ErrExists {
+			return nil, ecode.UserIsAlreadyExist
+		}
+		logger.Error("register create", zap.Error(err))
+		return nil, ecode.ServerErr
+	}
+	logger.Info("register", zap.Uint64("uid", user.Uid), zap.String("username", user.Username))
+	return &register.RegisterReply{Uid: user.Uid}, nil
+}
+
+//账号不存在和密码错误都返回LoginFailed，耗时相同
+func (s *Service) Authenticate(ctx context.Context, req *register.AuthenticateReq) (*register.AuthenticateReply, error) {
+	key, err := identifierKey(req.Account, s.countryCode)
+	if err != nil {
+		return nil, ecode.LoginFailed
+	}
+	user, err := s.users.LookupUser(ctx, key)
+	if err == store.ErrNotFound {
+		s.kdf.verify(s.kdf.dummy, req.Password)
+		return nil, ecode.LoginFailed
+	}
+	if err != nil {
+		logger.Error("register lookup", zap.Error(err))
+		return nil, ecode.ServerErr
+	}
+	if !s.kdf.verify(user.PasswordHash, req.Password) {
+		return nil, ecode.LoginFailed
+	}
+	return &register.AuthenticateReply{Uid: user.Uid}, nil
+}
+
+func (s *Service) GetProfile(ctx context.Context, req *register.ProfileReq) (*register.Profile, error) {
+	user, err := s.users.GetUser(ctx, req.Uid)
+	if err == store.ErrNotFound {
+		return nil, ecode.UserNotFound
+	}
+	if err != nil {
+		logger.Error("register get", zap.Uint64("uid", req.Uid), zap.Error(err))
+		return nil, ecode.ServerErr
+	}
+	return &register.Profile{
+		Uid:        user.Uid,
+		Username:   user.Username,
+		Phone:      user.Phone,
+		Email:      user.Email,
+		Nickname:   user.Nickname,
+		CreateTime: user.CreateTime,
+	}, nil
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+
+	logicrpc "github.com/imkuqin-zw/ZWChat/Logic/rpc"
+	"github.com/imkuqin-zw/ZWChat/common/ecode"
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/register"
+	"github.com/imkuqin-zw/ZWChat/common/store"
+	"github.com/imkuqin-zw/ZWChat/lib/service_discovery"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestPbkdf2(t *testing.T) {
+	for _, c := range []struct {
+		iterations int
+		want       string
+	}{
+		{1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	} {
+		if got := hex.EncodeToString(pbkdf2([]byte("password"), []byte("salt"), c.iterations)); got != c.want {
+			t.Fatalf("iterations %d: want %s, got %s", c.iterations, c.want, got)
+		}
+	}
+	k := newKdf(10)
+	encoded, err := k.hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !k.verify(encoded, "secret") || k.verify(encoded, "Secret") || k.verify("md5$x", "secret") {
+		t.Fatal("unexpected verify result")
+	}
+	//迭代次数调高后旧密码仍然可以校验
+	if !newKdf(20).verify(encoded, "secret") {
+		t.Fatal("old hash should still verify")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for _, c := range []struct{ in, want string }{
+		{" Alice_1 ", "alice_1"},
+		{"a.b", "a.b"},
+		{"1abc", ""},
+		{"ab", ""},
+		{"alice@x", ""},
+	} {
+		got, err := normalizeUsername(c.in)
+		if (err != nil) != (c.want == "") || got != c.want {
+			t.Fatalf("username %q: got %q, %v", c.in, got, err)
+		}
+	}
+	for _, c := range []struct{ in, want string }{
+		{"138 0000-0000", "+8613800000000"},
+		{"+1 (415) 555-2671", "+14155552671"},
+		{"0044 20 7946 0958", "+442079460958"},
+		{"12345", ""},
+		{"+0123456789", ""},
+		{"138a0000000", ""},
+	} {
+		got, err := normalizePhone(c.in, "86")
+		if (err != nil) != (c.want == "") || got != c.want {
+			t.Fatalf("phone %q: got %q, %v", c.in, got, err)
+		}
+	}
+	for _, c := range []struct{ in, want string }{
+		{" Alice@Example.COM ", "alice@example.com"},
+		{"a@b", ""},
+		{"@b.com", ""},
+		{"a@@b.com", ""},
+		{"a b@c.com", ""},
+		{"a@b..com", ""},
+	} {
+		got, err := normalizeEmail(c.in)
+		if (err != nil) != (c.want == "") || got != c.want {
+			t.Fatalf("email %q: got %q, %v", c.in, got, err)
+		}
+	}
+	for _, c := range []struct {
+		password string
+		ok       bool
+	}{
+		{"abcdefgh", false},
+		{"12345678", false},
+		{"abcd1234", true},
+		{"abc!defg", true},
+		{"a1", false},
+	} {
+		if err := checkPassword(c.password); (err == nil) != c.ok {
+			t.Fatalf("password %q: %v", c.password, err)
+		}
+	}
+}
+
+func TestService(t *testing.T) {
+	s := NewService(Cfg{Iterations: 10}, store.NewMemory())
+	ctx := context.Background()
+	reply, err := s.Register(ctx, &register.RegisterReq{Username: "Alice", Phone: "13800000000", Password: "abcd1234", Nickname: "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []struct {
+		req  *register.RegisterReq
+		code error
+	}{
+		{&register.RegisterReq{Username: "ALICE", Password: "abcd1234"}, ecode.UserIsAlreadyExist},
+		{&register.RegisterReq{Phone: "+86 138-0000-0000", Password: "abcd1234"}, ecode.UserIsAlreadyExist},
+		{&register.RegisterReq{Password: "abcd1234"}, ecode.NoIdentifier},
+		{&register.RegisterReq{Email: "bob", Password: "abcd1234"}, ecode.InvalidEmail},
+		{&register.RegisterReq{Email: "bob@example.com", Password: "short"}, ecode.PasswordTooWeak},
+	} {
+		if _, err = s.Register(ctx, req.req); err != req.code {
+			t.Fatalf("%+v: want %v, got %v", req.req, req.code, err)
+		}
+	}
+
+	//用户名和手机号都可以登录
+	for _, account := range []string{"alice", "+8613800000000", "138 0000 0000"} {
+		auth, err := s.Authenticate(ctx, &register.AuthenticateReq{Account: account, Password: "abcd1234"})
+		if err != nil || auth.Uid != reply.Uid {
+			t.Fatalf("%s: unexpected reply %+v, %v", account, auth, err)
+		}
+	}
+	for _, req := range []*register.AuthenticateReq{
+		{Account: "alice", Password: "wrong123"},
+		{Account: "nobody", Password: "abcd1234"},
+		{Account: "", Password: "abcd1234"},
+	} {
+		if _, err = s.Authenticate(ctx, req); err != ecode.LoginFailed {
+			t.Fatalf("%+v: want LoginFailed, got %v", req, err)
+		}
+	}
+
+	profile, err := s.GetProfile(ctx, &register.ProfileReq{Uid: reply.Uid})
+	if err != nil || profile.Username != "alice" || profile.Phone != "+8613800000000" || profile.Nickname != "A" {
+		t.Fatalf("unexpected profile %+v, %v", profile, err)
+	}
+	if _, err = s.GetProfile(ctx, &register.ProfileReq{Uid: 1}); err != ecode.UserNotFound {
+		t.Fatalf("want UserNotFound, got %v", err)
+	}
+}
+
+//Logic通过RegisterRPCCli调用，ecode原样传回
+func TestRegisterRPCCli(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	register.RegisterRegisterServer(server, NewService(Cfg{Iterations: 10}, store.NewMemory()))
+	go server.Serve(lis)
+	defer server.Stop()
+
+	discovery := service_discovery.NewMemory()
+	discovery.Register("register_server", service_discovery.Instance{Addr: lis.Addr().String()})
+	cli, err := logicrpc.NewRegisterRPCCli("register_server", discovery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	ctx := context.Background()
+	uid, err := cli.Register(ctx, &register.RegisterReq{Email: "bob@example.com", Password: "abcd1234"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := cli.Check(ctx, "BOB@example.com", "abcd1234"); err != nil || got != uid {
+		t.Fatalf("unexpected uid %d, %v", got, err)
+	}
+	if _, err = cli.Check(ctx, "bob@example.com", "wrong123"); err != ecode.LoginFailed {
+		t.Fatalf("want LoginFailed, got %v", err)
+	}
+	if _, err = cli.GetProfile(ctx, uid+1); err != ecode.UserNotFound {
+		t.Fatalf("want UserNotFound, got %v", err)
+	}
+}
@@ -1,40 +1,37 @@
 package main
 
 import (
-	"net"
 	"fmt"
 	"os"
-	"github.com/imkuqin-zw/ZWChat/protobuf"
-	"github.com/micro/protobuf/proto"
+	"strconv"
+	"time"
+
+	"github.com/imkuqin-zw/ZWChat/common/protobuf/external"
+	"github.com/imkuqin-zw/ZWChat/lib/net_client"
+	"golang.org/x/net/context"
 )
 
 func main() {
-	addr, err := net.ResolveTCPAddr("tcp4", ":1200")
-	checkError(err)
-	conn, err := net.DialTCP("tcp4", nil, addr)
+	c, err := net_client.Dial(net_client.Options{Network: "tcp", Addr: ":11000"})
 	checkError(err)
-	defer conn.Close()
-	msg := &protobuf.OffsetP2PMsg{
-		SourceUID: 1,
-		TargetUID: 2,
-		MsgID: "12",
-		Msg: "45646",
-	}
-	body, err := proto.Marshal(msg)
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ack, err := c.Handshake(ctx, &external.Handshake{Platform: external.Platform_PC})
 	checkError(err)
-	lens := len(body)
-	fmt.Println(lens)
-	var buf = make([]byte, lens + 5)
-	buf[0] = 'w'
-	buf[1] = byte(uint32(lens))
-	buf[2] = byte(uint32(lens) >> 8)
-	buf[3] = byte(uint32(lens) >> 16)
-	buf[4] = byte(uint32(lens) >> 24)
-	copy(buf[5:], body)
-	fmt.Println(len(buf))
+	fmt.Println("handshake", ack)
 	for i := 0; i < 10; i++ {
-		_, err = conn.Write(buf)
+		resp, err := c.SendP2PMsg(ctx, &external.SendP2PMsg{
+			ClientMsgId: strconv.Itoa(i),
+			To:          2,
+			Content:     []byte("45646"),
+		})
 		checkError(err)
+		fmt.Println("send", resp)
+	}
+	for p := range c.Receive() {
+		msg, err := net_client.Decode(p)
+		fmt.Println("receive", p.MsgId, p.Cmd, msg, err)
 	}
 	os.Exit(0)
 }
@@ -44,4 +41,4 @@ func checkError(err error) {
 		fmt.Fprintf(os.Stderr, "Fatal error: %s\r\n", err.Error())
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}